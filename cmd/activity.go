@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/mathwro/azperm/internal/activity"
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// RunActivity derives the least-privilege permissions of a caller from an Activity Log export
+func (c *CLI) RunActivity(args []string) error {
+	var assigned stringSliceFlag
+
+	fs := flag.NewFlagSet("activity", flag.ContinueOnError)
+	caller := fs.String("caller", "", "Only include events initiated by this caller (UPN, object ID or app ID)")
+	name := fs.String("name", "", "Name of the generated custom role")
+	excludeFailed := fs.Bool("exclude-failed", false, "Ignore failed operations")
+	fs.Var(&assigned, "assigned", "Role definition file currently assigned to the caller, for comparison (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm activity [flags] <activity-log.json|.csv>")
+		fmt.Fprintln(fs.Output(), "Derives a least-privilege custom role from an 'az monitor activity-log list' export")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a single activity log file")
+	}
+
+	events, err := activity.LoadEvents(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	observed, matched := activity.Summarize(events, *caller, !*excludeFailed)
+	if len(observed) == 0 {
+		return fmt.Errorf("no operations found in the activity log for the given caller")
+	}
+
+	// Descriptions and the Actions/DataActions split come from the catalog when it is available
+	c.quiet = true
+	providers, err := c.catalog.Providers()
+	if err != nil {
+		c.colors.Warning.Fprintf(os.Stderr, "⚠️  Operation descriptions unavailable: %v\n", err)
+	}
+
+	var details []models.PermissionDetail
+	scopeSet := make(map[string]bool)
+	var actions, dataActions []string
+	for i, action := range observed {
+		if operation, found := catalog.LookupOperation(providers, action.Action); found {
+			observed[i].Action = operation.Name
+			observed[i].IsDataAction = operation.IsDataAction
+			observed[i].Description = operation.Description
+		}
+
+		details = append(details, models.PermissionDetail{Name: observed[i].Action, IsDataAction: observed[i].IsDataAction})
+		if observed[i].IsDataAction {
+			dataActions = append(dataActions, observed[i].Action)
+		} else {
+			actions = append(actions, observed[i].Action)
+		}
+		for _, scope := range action.Scopes {
+			scopeSet[permissions.ResourceGroupScope(scope)] = true
+		}
+	}
+
+	var scopes []string
+	for scope := range scopeSet {
+		if scope != "/" {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	description := "Least-privilege role derived by azperm from Azure Activity Log"
+	if *caller != "" {
+		description += " for " + *caller
+	}
+
+	report := models.ActivityReport{
+		Source:  fs.Arg(0),
+		Caller:  *caller,
+		Events:  matched,
+		Actions: observed,
+		Role:    permissions.NewCustomRole(*name, description, scopes, actions, dataActions),
+	}
+
+	if len(assigned) > 0 {
+		var roles []models.RoleDefinition
+		for _, path := range assigned {
+			loaded, err := permissions.LoadRoleDefinitions(path)
+			if err != nil {
+				return err
+			}
+			roles = append(roles, loaded...)
+		}
+		comparison := permissions.CompareWithRoles(details, roles)
+		report.Comparison = &comparison
+	}
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, report)
+	}
+
+	c.colors.DisplayActivityReport(report)
+	return nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// exitCodeCatalogAlert is returned by 'catalog diff' when roles reference removed operations,
+// or when the catalog changed and --fail-on-change is set
+const exitCodeCatalogAlert = 2
+
+// RunCatalog manages provider operations catalog snapshots
+func (c *CLI) RunCatalog(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: azperm catalog <save|diff> [flags]")
+	}
+
+	switch args[0] {
+	case "save":
+		return c.runCatalogSave(args[1:])
+	case "diff":
+		return c.runCatalogDiff(args[1:])
+	default:
+		return fmt.Errorf("unknown catalog command: %s (expected 'save' or 'diff')", args[0])
+	}
+}
+
+// runCatalogSave writes the live provider operations catalog to a snapshot file
+func (c *CLI) runCatalogSave(args []string) error {
+	fs := flag.NewFlagSet("catalog save", flag.ContinueOnError)
+	compact := fs.Bool("compact", false, "Write the compact indexed-cache form instead of JSON (smaller and faster to load)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm catalog save [snapshot.json]")
+		fmt.Fprintln(fs.Output(), "Saves the live provider operations catalog (default: the azperm cache file)")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected at most one snapshot file")
+	}
+
+	var file string
+	if len(positional) == 1 {
+		file = positional[0]
+	}
+	path, err := snapshotPath(file)
+	if err != nil {
+		return err
+	}
+
+	c.quiet = true
+	providers, err := c.catalog.Providers()
+	if err != nil {
+		return err
+	}
+
+	save := catalog.SaveFile
+	if *compact {
+		save = catalog.SaveCompactFile
+	}
+	if err := save(path, providers); err != nil {
+		return err
+	}
+
+	c.colors.Success.Fprintf(os.Stderr, "✅ Saved %d provider namespace(s) to %s\n", len(providers), path)
+	return nil
+}
+
+// runCatalogDiff compares two catalog snapshots, or a snapshot with the live catalog
+func (c *CLI) runCatalogDiff(args []string) error {
+	var roleFiles stringSliceFlag
+
+	fs := flag.NewFlagSet("catalog diff", flag.ContinueOnError)
+	cached := fs.Bool("cached", false, "Compare the cached catalog with the live catalog")
+	updateCache := fs.Bool("update-cache", false, "Save the live catalog to the cache, in the compact form, after comparing")
+	failOnChange := fs.Bool("fail-on-change", false, fmt.Sprintf("Exit with status %d when the catalogs differ", exitCodeCatalogAlert))
+	fs.Var(&roleFiles, "roles", "Role definition file to check for references to removed operations (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm catalog diff [flags] <old.json> [new.json]")
+		fmt.Fprintln(fs.Output(), "       azperm catalog diff --cached [flags]")
+		fmt.Fprintln(fs.Output(), "Compares provider operations snapshots; the live catalog is used when new.json is omitted")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var oldPath, newPath string
+	switch {
+	case *cached && fs.NArg() == 0:
+		path, err := catalog.DefaultCachePath()
+		if err != nil {
+			return err
+		}
+		oldPath = path
+	case !*cached && (fs.NArg() == 1 || fs.NArg() == 2):
+		oldPath, newPath = fs.Arg(0), fs.Arg(1)
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected an old snapshot and an optional new snapshot, or --cached")
+	}
+
+	oldProviders, err := catalog.LoadFile(oldPath)
+	if err != nil {
+		return err
+	}
+
+	c.quiet = true
+	var newProviders map[string]models.ProviderOperationsResponse
+	if newPath != "" {
+		newProviders, err = catalog.LoadFile(newPath)
+	} else {
+		newProviders, err = c.catalog.Providers()
+	}
+	if err != nil {
+		return err
+	}
+
+	diff := catalog.Diff(oldProviders, newProviders)
+
+	if len(roleFiles) > 0 {
+		var roles []models.RoleDefinition
+		for _, path := range roleFiles {
+			loaded, err := permissions.LoadRoleDefinitions(path)
+			if err != nil {
+				return err
+			}
+			roles = append(roles, loaded...)
+		}
+		diff.RoleReferences = permissions.RemovedReferences(roles, diff.RemovedOperations, catalog.Operations(newProviders))
+	}
+
+	if c.outputFormat == OutputJSON {
+		if err := display.WriteJSON(os.Stdout, diff); err != nil {
+			return err
+		}
+	} else {
+		c.colors.DisplayCatalogDiff(diff)
+	}
+
+	if *updateCache && newPath == "" {
+		path, err := catalog.DefaultCachePath()
+		if err != nil {
+			return err
+		}
+		if err := catalog.SaveCompactFile(path, newProviders); err != nil {
+			return err
+		}
+	}
+
+	if len(diff.RoleReferences) > 0 || (*failOnChange && catalog.HasChanges(diff)) {
+		return &ExitError{Code: exitCodeCatalogAlert}
+	}
+	return nil
+}
+
+// snapshotPath returns the given snapshot path, or the default cache path when it is empty
+func snapshotPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	return catalog.DefaultCachePath()
+}
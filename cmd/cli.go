@@ -2,37 +2,73 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/mathwro/azperm/internal/azure"
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/config"
 	"github.com/mathwro/azperm/internal/display"
 	"github.com/mathwro/azperm/internal/models"
 	"github.com/mathwro/azperm/internal/parser"
 	"github.com/mathwro/azperm/internal/permissions"
+	"github.com/mathwro/azperm/internal/policy"
+	"github.com/mathwro/azperm/internal/registry"
+	"github.com/mathwro/azperm/internal/scanner"
 	"github.com/mathwro/azperm/internal/shell"
 )
 
 // CLI represents the main CLI application
 type CLI struct {
-	permManager *permissions.Manager
-	azureClient *azure.Client
-	colors      *display.Colors
-	liveMode    bool
-	debugMode   bool
+	permManager  *permissions.Manager
+	azureClient  *azure.Client
+	catalog      *catalog.Catalog
+	resolver     *permissions.Resolver
+	policy       *policy.Policy
+	config       *config.Config
+	overrides    map[string][]string
+	tenant       string
+	subscription string
+	colors       *display.Colors
+	liveMode     bool
+	debugMode    bool
+	quiet        bool
+	outputFormat string
+
+	// offline is set when the catalog comes from a snapshot file
+	offline bool
+	// providersFile replaces the Providers API when matching unknown services
+	providersFile     string
+	providersOnce     sync.Once
+	resourceProviders []models.ResourceProvider
+	providersErr      error
 }
 
 // NewCLI creates a new CLI instance
 func NewCLI() *CLI {
-	return &CLI{
-		permManager: permissions.NewManager(),
-		azureClient: azure.NewClient(),
-		colors:      display.NewColors(),
-		liveMode:    true, // Always use live mode by default
-		debugMode:   false, // Debug mode off by default
-	}
+	c := &CLI{
+		permManager:  permissions.NewManager(),
+		azureClient:  azure.NewClient(),
+		resolver:     permissions.NewResolver(nil),
+		colors:       display.NewColors(),
+		liveMode:     true,  // Always use live mode by default
+		debugMode:    false, // Debug mode off by default
+		outputFormat: OutputText,
+	}
+	c.catalog = catalog.New(c.fetchProviderOperations)
+	c.catalog.SetNamespaceFetch(c.fetchProviderNamespace)
+	if dir, err := catalog.DefaultNamespaceCacheDir(); err == nil {
+		c.catalog.SetNamespaceCache(dir)
+	}
+	c.resolver.SetDiscovery(c.discoverService)
+	c.azureClient.SetUserAgent("azperm/" + c.Version())
+	return c
 }
 
 // SetLiveMode enables or disables live API querying mode
@@ -43,6 +79,72 @@ func (c *CLI) SetLiveMode(enabled bool) {
 // SetDebugMode enables or disables debug mode with verbose output
 func (c *CLI) SetDebugMode(enabled bool) {
 	c.debugMode = enabled
+	if enabled {
+		c.resolver = permissions.NewResolver(func(format string, args ...interface{}) {
+			c.colors.Info.Printf(format, args...)
+		})
+	} else {
+		c.resolver = permissions.NewResolver(nil)
+	}
+	c.resolver.SetOverrides(c.overrides)
+	c.resolver.SetDiscovery(c.discoverService)
+}
+
+// SetCatalogFile loads the provider operations catalog from a snapshot file instead of the live API
+func (c *CLI) SetCatalogFile(path string) {
+	c.catalog = catalog.New(func() (map[string]models.ProviderOperationsResponse, error) {
+		return catalog.LoadFile(path)
+	})
+}
+
+// SetCloud selects a built-in or user-defined cloud profile instead of the cloud configured in
+// Azure CLI. User-defined profiles are loaded from the clouds.yaml file in the config directory.
+func (c *CLI) SetCloud(name string) error {
+	path, err := azure.DefaultCloudProfilesPath()
+	if err == nil {
+		if _, statErr := os.Stat(path); statErr == nil {
+			profiles, err := azure.LoadCloudProfiles(path)
+			if err != nil {
+				return err
+			}
+			if err := c.azureClient.AddCloudProfiles(profiles...); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	return c.azureClient.SetCloud(name)
+}
+
+// loadServiceExtensions adds the user-defined services of the services.yaml file in the
+// config directory to the service registry
+func (c *CLI) loadServiceExtensions() error {
+	path, err := registry.DefaultExtensionsPath()
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	services, err := registry.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	registry.Extend(services)
+	return nil
+}
+
+// SetOutputFormat sets the output format for analysis results
+func (c *CLI) SetOutputFormat(format string) error {
+	switch format {
+	case OutputText, OutputJSON, OutputMarkdown, OutputCSV, OutputHTML, OutputSARIF:
+		c.outputFormat = format
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s (supported: %s, %s, %s, %s, %s, %s)",
+			format, OutputText, OutputJSON, OutputMarkdown, OutputCSV, OutputHTML, OutputSARIF)
+	}
 }
 
 // Run executes the main CLI logic
@@ -84,21 +186,28 @@ func (c *CLI) RunWithLastCommand() error {
 	}
 
 	// Get permissions using live Azure API querying
-	permissions, _ := c.getPermissions(cmd)
+	permissions, confidence := c.getPermissions(cmd)
 
 	if len(permissions) == 0 {
 		c.colors.ShowNoPermissionsWarning(cmd.FullCmd, true)
 		return fmt.Errorf("failed to retrieve permissions from Azure API")
 	}
 
-	// Always display results with live query indication since we always use live mode
-	c.colors.DisplayPermissionsWithLiveQuery(cmd, permissions)
-
-	return nil
+	return c.displayAnalysis(cmd, permissions, confidence)
 }
 
 // RunWithArgs executes the main CLI logic with optional command line arguments
 func (c *CLI) RunWithArgs(args []string) error {
+	// Dispatch subcommands such as "serve" or "scan"
+	if len(args) > 0 {
+		if handler := c.subcommand(args[0]); handler != nil {
+			if isReportFormat(c.outputFormat) && !supportsReports(args[0]) {
+				return fmt.Errorf("output format %s is not supported by '%s'", c.outputFormat, args[0])
+			}
+			return handler(args[1:])
+		}
+	}
+
 	// Load permissions database
 	c.permManager.LoadPermissions()
 
@@ -142,32 +251,88 @@ func (c *CLI) RunWithArgs(args []string) error {
 	}
 
 	// Get permissions using live Azure API querying
-	permissions, _ := c.getPermissions(cmd)
+	permissions, confidence := c.getPermissions(cmd)
 
 	if len(permissions) == 0 {
 		c.colors.ShowNoPermissionsWarning(cmd.FullCmd, true)
 		return fmt.Errorf("failed to retrieve permissions from Azure API")
 	}
 
-	// Always display results with live query indication since we always use live mode
-	c.colors.DisplayPermissionsWithLiveQuery(cmd, permissions)
+	return c.displayAnalysis(cmd, permissions, confidence)
+}
 
-	return nil
+// displayAnalysis prints the resolved permissions in the configured output format
+func (c *CLI) displayAnalysis(cmd *models.AzureCommand, details []models.PermissionDetail, confidence models.ConfidenceLevel) error {
+	result := models.AnalysisResult{
+		Command:     "az " + cmd.FullCmd,
+		Service:     cmd.Service,
+		Operation:   cmd.Operation,
+		Parameters:  cmd.Parameters,
+		Scope:       permissions.ComputeScope(cmd, permissions.SubscriptionPlaceholder, details),
+		Permissions: details,
+		Confidence:  confidence,
+	}
+	if scopes := permissions.ComputeScopes(cmd, permissions.SubscriptionPlaceholder, details); len(scopes) > 1 {
+		result.Scopes = scopes
+	}
+	permissions.AssessRisk(&result)
+	violations := c.evaluatePolicy("", []models.AnalysisResult{result})
+
+	switch {
+	case c.outputFormat == OutputJSON:
+		if err := display.WriteJSON(os.Stdout, result); err != nil {
+			return err
+		}
+	case isReportFormat(c.outputFormat):
+		if err := c.writeReport(scanReport("", []models.AnalysisResult{result}, violations)); err != nil {
+			return err
+		}
+	default:
+		// Always display results with live query indication since we always use live mode
+		c.colors.DisplayPermissionsWithLiveQuery(cmd, details, confidence)
+	}
+
+	return c.enforcePolicy(violations)
+}
+
+// scanReport aggregates analysis results into a report. Commands from several files carry
+// their file; source names the single file the commands come from, if any.
+func scanReport(source string, commands []models.AnalysisResult, violations []models.PolicyViolation) models.ScanResult {
+	report := models.ScanResult{Source: source, Commands: commands, Violations: violations}
+	report.Actions, report.DataActions = permissions.SplitActions(report.Commands)
+	permissions.AssessScanRisk(&report)
+	return report
+}
+
+// writeReport renders analysis results in the selected document report format
+func (c *CLI) writeReport(result models.ScanResult) error {
+	switch c.outputFormat {
+	case OutputMarkdown:
+		return display.WriteMarkdownReport(os.Stdout, result)
+	case OutputCSV:
+		return display.WriteCSVReport(os.Stdout, result)
+	case OutputHTML:
+		return display.WriteHTMLReport(os.Stdout, result)
+	case OutputSARIF:
+		return display.WriteSARIFReport(os.Stdout, result, c.Version())
+	default:
+		return fmt.Errorf("output format %s is not a report format", c.outputFormat)
+	}
 }
 
 // readPipedInput reads input from stdin (piped commands)
 func (c *CLI) readPipedInput() (string, error) {
-	scanner := bufio.NewScanner(os.Stdin)
+	reader := bufio.NewScanner(os.Stdin)
 	var lines []string
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	for reader.Scan() {
+		line := strings.TrimSpace(reader.Text())
 		if line != "" {
 			lines = append(lines, line)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := reader.Err(); err != nil {
 		return "", err
 	}
 
@@ -175,46 +340,12 @@ func (c *CLI) readPipedInput() (string, error) {
 		return "", fmt.Errorf("no input provided")
 	}
 
-	// Join all lines or take the first Azure CLI command
-	input := strings.Join(lines, " ")
-	
-	// Extract Azure CLI command if it's mixed with other content
-	if azCmd := c.extractAzureCommand(input); azCmd != "" {
-		return azCmd, nil
+	// Take the first Azure CLI command if it's mixed with other content
+	if invocations := scanner.ScanScript(strings.Join(lines, "\n")); len(invocations) > 0 {
+		return invocations[0].Command, nil
 	}
 
-	return input, nil
-}
-
-// extractAzureCommand extracts Azure CLI command from mixed input
-func (c *CLI) extractAzureCommand(input string) string {
-	// Look for patterns like "az ..." in the input
-	words := strings.Fields(input)
-	
-	var azCommand []string
-	foundAz := false
-	
-	for _, word := range words {
-		if word == "az" {
-			foundAz = true
-			azCommand = []string{word}
-		} else if foundAz {
-			// Continue collecting command parts
-			azCommand = append(azCommand, word)
-			
-			// Stop at common terminators or if we hit another command
-			if strings.HasPrefix(word, "--") && len(azCommand) > 3 {
-				// We have enough of the command
-				break
-			}
-		}
-	}
-	
-	if foundAz && len(azCommand) >= 3 {
-		return strings.Join(azCommand, " ")
-	}
-	
-	return ""
+	return strings.Join(lines, " "), nil
 }
 
 // getLastAzureCommand attempts to get the last Azure CLI command from shell history
@@ -228,29 +359,45 @@ func (c *CLI) getLastAzureCommand() (string, error) {
 }
 
 // getPermissions retrieves permissions using live Azure API querying
-func (c *CLI) getPermissions(cmd *models.AzureCommand) ([]string, models.ConfidenceLevel) {
+func (c *CLI) getPermissions(cmd *models.AzureCommand) ([]models.PermissionDetail, models.ConfidenceLevel) {
 	// Always try to get permissions from live Azure API first
 	if permissions, err := c.getLivePermissions(cmd); err == nil && len(permissions) > 0 {
-		return permissions, models.ConfidenceHigh
+		return permissions, c.resolver.Confidence(cmd)
 	}
 
 	// If live API fails, show error and exit gracefully
-	c.colors.Error.Println("❌ Failed to query Azure API for permissions")
-	c.colors.Warning.Println("💡 Make sure you're logged in with 'az login' and have internet connectivity")
-	
+	if c.outputFormat == OutputText {
+		c.colors.Error.Println("❌ Failed to query Azure API for permissions")
+		c.colors.Warning.Println("💡 Make sure you're logged in with 'az login' and have internet connectivity")
+	}
+
 	// Return empty permissions to indicate failure
-	return []string{}, models.ConfidenceLow
+	return []models.PermissionDetail{}, models.ConfidenceLow
+}
+
+// getLivePermissions attempts to get permissions using live Azure API, fetching only the
+// provider namespaces the command needs
+func (c *CLI) getLivePermissions(cmd *models.AzureCommand) ([]models.PermissionDetail, error) {
+	operations, err := c.catalog.Namespaces(c.resolver.Namespaces(cmd)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find relevant operations for the command
+	return c.resolver.Resolve(cmd, catalog.NewStore(operations))
 }
 
-// getLivePermissions attempts to get permissions using live Azure API
-func (c *CLI) getLivePermissions(cmd *models.AzureCommand) ([]string, error) {
+// fetchProviderOperations downloads the provider operations catalog from the live Azure API
+func (c *CLI) fetchProviderOperations() (map[string]models.ProviderOperationsResponse, error) {
 	// Try to get Azure CLI access token
 	accessToken, err := c.getAzureAccessToken()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Azure access token: %w", err)
 	}
 
-	c.colors.Info.Println("🔍 Querying Azure API for permissions...")
+	if c.outputFormat == OutputText && !c.quiet {
+		c.colors.Info.Println("🔍 Querying Azure API for permissions...")
+	}
 
 	// Show debug info about the endpoint being used if debug mode is enabled
 	if c.debugMode {
@@ -262,7 +409,7 @@ func (c *CLI) getLivePermissions(cmd *models.AzureCommand) ([]string, error) {
 	}
 
 	// Use the real Azure API with the access token
-	operations, err := c.azureClient.FetchRealProviderOperations(accessToken)
+	operations, err := c.azureClient.FetchRealProviderOperations(context.Background(), accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch real provider operations: %w", err)
 	}
@@ -271,466 +418,118 @@ func (c *CLI) getLivePermissions(cmd *models.AzureCommand) ([]string, error) {
 		c.colors.Info.Printf("📊 Retrieved %d resource providers from Azure API\n", len(operations))
 	}
 
-	// Find relevant operations for the command
-	return c.findOperationsForCommand(cmd, operations)
+	return operations, nil
 }
 
-// getAzureAccessToken attempts to get an access token from Azure CLI
-func (c *CLI) getAzureAccessToken() (string, error) {
-	// Try to get access token using Azure CLI
-	cmd := exec.Command("az", "account", "get-access-token", "--query", "accessToken", "--output", "tsv")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get access token from Azure CLI (make sure you're logged in with 'az login'): %w", err)
-	}
-
-	token := strings.TrimSpace(string(output))
-	if token == "" {
-		return "", fmt.Errorf("empty access token returned from Azure CLI")
-	}
-
-	return token, nil
-}
-
-// findOperationsForCommand finds relevant operations from the live API data
-func (c *CLI) findOperationsForCommand(cmd *models.AzureCommand, operations map[string]models.ProviderOperationsResponse) ([]string, error) {
-	// Map service to resource provider
-	provider := c.mapServiceToProvider(cmd.Service)
-	if provider == "" {
-		return nil, fmt.Errorf("unknown service: %s", cmd.Service)
-	}
-
-	if c.debugMode {
-		c.colors.Info.Printf("🔗 Mapped service '%s' to provider '%s'\n", cmd.Service, provider)
-	}
-
-	providerOps, exists := operations[provider]
-	if !exists {
-		return nil, fmt.Errorf("provider not found: %s", provider)
-	}
-
-	if c.debugMode {
-		c.colors.Info.Printf("📋 Found provider '%s' with %d resource types\n", provider, len(providerOps.ResourceTypes))
-	}
-
-	permissionsSet := make(map[string]bool) // Use map to avoid duplicates
-	
-	// First check provider-level operations
-	for _, operation := range providerOps.Operations {
-		if c.matchesOperation(cmd.Operation, operation.Name) {
-			permissionsSet[operation.Name] = true
-			if c.debugMode {
-				c.colors.Info.Printf("✅ Matched provider operation: %s\n", operation.Name)
-			}
-		}
-	}
-	
-	// Then check resource type operations
-	for _, resourceType := range providerOps.ResourceTypes {
-		if c.matchesResourceType(cmd, resourceType.Name) {
-			if c.debugMode {
-				c.colors.Info.Printf("✅ Matched resource type: %s\n", resourceType.Name)
-			}
-			// Find operations that match the command operation
-			for _, operation := range resourceType.Operations {
-				if c.matchesOperation(cmd.Operation, operation.Name) {
-					permissionsSet[operation.Name] = true
-					if c.debugMode {
-						c.colors.Info.Printf("✅ Matched operation: %s\n", operation.Name)
-					}
-				}
-			}
-		} else if c.debugMode && c.isDataPlaneOperation(cmd) {
-			// Show what we're rejecting for debugging
-			c.colors.Info.Printf("❌ Rejected resource type: %s\n", resourceType.Name)
-		}
-	}
-
-	// Convert map to slice
-	var permissions []string
-	for permission := range permissionsSet {
-		permissions = append(permissions, permission)
-	}
-
-	// If no exact matches, provide intelligent suggestions
-	if len(permissions) == 0 {
+// discoverService matches a service that is not in the service registry to a resource
+// provider, using the providers file or the Providers API. The provider list is loaded once.
+func (c *CLI) discoverService(service string) (registry.Service, bool) {
+	c.providersOnce.Do(func() {
+		c.resourceProviders, c.providersErr = c.loadResourceProviders()
+	})
+	if c.providersErr != nil {
 		if c.debugMode {
-			c.colors.Warning.Println("⚠️  No exact matches found, using intelligent suggestions...")
+			c.colors.Warning.Fprintf(os.Stderr, "⚠️  Cannot discover service '%s': %v\n", service, c.providersErr)
 		}
-		permissions = c.suggestOperationsFromLiveData(cmd, providerOps)
-	}
-
-	if c.debugMode {
-		c.colors.Info.Printf("🎯 Found %d permissions\n", len(permissions))
+		return registry.Service{}, false
 	}
-	return permissions, nil
+	return registry.Discover(service, c.resourceProviders)
 }
 
-// Helper methods for mapping and matching (similar to azure client)
-func (c *CLI) mapServiceToProvider(service string) string {
-	serviceMap := map[string]string{
-		"group":      "Microsoft.Resources",
-		"vm":         "Microsoft.Compute",
-		"storage":    "Microsoft.Storage",
-		"webapp":     "Microsoft.Web",
-		"keyvault":   "Microsoft.KeyVault",
-		"network":    "Microsoft.Network",
-		"sql":        "Microsoft.Sql",
-		"aks":        "Microsoft.ContainerService",
-		"role":       "Microsoft.Authorization",
-		"container":  "Microsoft.ContainerInstance",
-	}
-
-	// Handle compound services first (more specific matches)
-	if strings.Contains(service, "storage") && strings.Contains(service, "account") {
-		return "Microsoft.Storage"
+// loadResourceProviders reads the resource providers from the providers file, or from the
+// Providers API unless the catalog is offline
+func (c *CLI) loadResourceProviders() ([]models.ResourceProvider, error) {
+	if c.providersFile != "" {
+		return azure.LoadResourceProviders(c.providersFile)
 	}
-	
-	// Handle storage data plane operations (storage + anything else that's not "account")
-	if strings.Contains(service, "storage") && !strings.Contains(service, "account") {
-		return "Microsoft.Storage"
-	}
-	
-	for key, provider := range serviceMap {
-		if strings.Contains(service, key) {
-			return provider
-		}
+	if c.offline {
+		return nil, fmt.Errorf("the catalog is offline and no providers file is set")
 	}
-	return ""
-}
 
-func (c *CLI) matchesResourceType(cmd *models.AzureCommand, resourceType string) bool {
-	service := strings.ToLower(cmd.Service)
-	resType := strings.ToLower(resourceType)
-	operation := strings.ToLower(cmd.Operation)
-	
-	// Dynamic data plane detection based on service patterns
-	if c.isDataPlaneOperation(cmd) {
-		return c.matchesDataPlaneResourceType(service, operation, resType)
-	}
-	
-	// Control plane operations - use precise mappings
-	// For "storage account" commands, we need to match exactly "storageAccounts", not sub-resources
-	serviceOperationToResourceTypes := map[string]map[string][]string{
-		"group": {
-			"create": {"subscriptions/resourcegroups"},
-			"delete": {"subscriptions/resourcegroups"},
-			"list":   {"subscriptions/resourcegroups"},
-			"show":   {"subscriptions/resourcegroups"},
-		},
-		"vm": {
-			"create": {"virtualmachines"},
-			"delete": {"virtualmachines"},
-			"start":  {"virtualmachines"},
-			"stop":   {"virtualmachines"},
-			"restart": {"virtualmachines"},
-			"list":   {"virtualmachines"},
-			"show":   {"virtualmachines", "virtualmachines/instanceview"},
-		},
-		"storage account": {
-			"create": {"storageaccounts"},
-			"delete": {"storageaccounts"},
-			"list":   {"storageaccounts"},
-			"show":   {"storageaccounts"},
-			"update": {"storageaccounts"},
-		},
-		"storage": {
-			"create": {"storageaccounts"},
-			"delete": {"storageaccounts"},
-			"list":   {"storageaccounts"},
-			"show":   {"storageaccounts"},
-		},
-		"webapp": {
-			"create": {"sites"},
-			"delete": {"sites"},
-			"list":   {"sites"},
-			"show":   {"sites"},
-			"start":  {"sites"},
-			"stop":   {"sites"},
-			"restart": {"sites"},
-		},
-		"keyvault": {
-			"create": {"vaults"},
-			"delete": {"vaults"},
-			"list":   {"vaults"},
-			"show":   {"vaults"},
-		},
-		"aks": {
-			"create": {"managedclusters"},
-			"delete": {"managedclusters"},
-			"list":   {"managedclusters"},
-			"show":   {"managedclusters"},
-			"start":  {"managedclusters"},
-			"stop":   {"managedclusters"},
-		},
-		"container": {
-			"create": {"containergroups"},
-			"delete": {"containergroups"},
-			"list":   {"containergroups"},
-			"show":   {"containergroups"},
-			"start":  {"containergroups"},
-			"stop":   {"containergroups"},
-			"restart": {"containergroups"},
-		},
+	accessToken, err := c.getAzureAccessToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure access token: %w", err)
 	}
-
-	// Try exact service match first (for compound services like "storage account")
-	if serviceOps, exists := serviceOperationToResourceTypes[service]; exists {
-		if resourceTypes, opExists := serviceOps[operation]; opExists {
-			normalizedResType := strings.ReplaceAll(resType, "/", "")
-			for _, resourceTypePattern := range resourceTypes {
-				normalizedPattern := strings.ReplaceAll(resourceTypePattern, "/", "")
-				// Exact match for the main resource type
-				if normalizedResType == normalizedPattern {
-					return true
-				}
-			}
-		}
-		// If we have a specific mapping for this service, don't fall through to generic matching
-		return false
+	// Discovery runs in the middle of an analysis, so progress must not mix with its output
+	if c.outputFormat == OutputText && !c.quiet {
+		c.colors.Info.Fprintln(os.Stderr, "🔍 Querying Azure API for resource providers...")
 	}
-	
-	return false
+	return c.azureClient.FetchResourceProviders(context.Background(), accessToken)
 }
 
-// isDataPlaneOperation dynamically determines if this is a data plane operation
-// by analyzing the Azure API response rather than using hardcoded mappings
-func (c *CLI) isDataPlaneOperation(cmd *models.AzureCommand) bool {
-	service := strings.ToLower(cmd.Service)
-	
-	// Check for multi-part service names that typically indicate data plane operations
-	serviceParts := strings.Fields(service)
-	if len(serviceParts) >= 2 {
-		// Special cases for control plane operations that have multi-part names
-		controlPlaneExceptions := []string{
-			"storage account",    // az storage account create
-			"network vnet",       // az network vnet create  
-			"network nsg",        // az network nsg create
-			"app service",        // az webapp (app service)
-			"key vault",          // sometimes referenced as "key vault"
-		}
-		
-		for _, exception := range controlPlaneExceptions {
-			if service == exception {
-				return false // This is a control plane operation
-			}
-		}
-		
-		// Multi-part service names (like "keyvault secret" or "storage blob") 
-		// are strong indicators of data plane operations
-		return true
+// fetchProviderNamespace downloads the operations of a single provider namespace from the live Azure API
+func (c *CLI) fetchProviderNamespace(namespace string) (models.ProviderOperationsResponse, error) {
+	accessToken, err := c.getAzureAccessToken()
+	if err != nil {
+		return models.ProviderOperationsResponse{}, fmt.Errorf("failed to get Azure access token: %w", err)
 	}
-	
-	return false
-}
 
-// matchesDataPlaneResourceType dynamically matches data plane resource types
-// by analyzing the actual Azure API resource type patterns
-func (c *CLI) matchesDataPlaneResourceType(service, operation, resourceType string) bool {
-	serviceParts := strings.Fields(service)
-	if len(serviceParts) < 2 {
-		return false
+	// Namespaces are fetched in the middle of an analysis, like discovery
+	if c.outputFormat == OutputText && !c.quiet {
+		c.colors.Info.Fprintf(os.Stderr, "🔍 Querying Azure API for %s permissions...\n", namespace)
 	}
-	
-	baseService := serviceParts[0]
-	subResource := serviceParts[1]
-	
-	// Dynamic matching based on resource type structure from Azure API
-	resourceTypeLower := strings.ToLower(resourceType)
-	
-	// Special handling for known service name variations
-	serviceAliases := map[string][]string{
-		"keyvault": {"vault", "vaults"},
-		"storage":  {"storageaccount", "storageaccounts"},
-		"cosmosdb": {"documentdb", "cosmos"},
-	}
-	
-	// Check if the resource type contains the base service name or its aliases
-	serviceMatched := false
-	if aliases, exists := serviceAliases[baseService]; exists {
-		for _, alias := range aliases {
-			if strings.Contains(resourceTypeLower, alias) {
-				serviceMatched = true
-				break
-			}
-		}
-	} else {
-		// Direct match for services without aliases
-		serviceMatched = strings.Contains(resourceTypeLower, baseService)
-	}
-	
-	if !serviceMatched {
-		return false
-	}
-	
-	// Check if the resource type contains the sub-resource name
-	if !strings.Contains(resourceTypeLower, subResource) {
-		return false
-	}
-	
-	// Count hierarchy levels in the original resource type
-	hierarchyLevels := strings.Count(resourceType, "/")
-	
-	// Data plane operations typically have deeper hierarchy (1+ levels)
-	if hierarchyLevels < 1 {
-		return false
-	}
-	
-	// For truly dynamic matching, prioritize the most specific resource types
-	// by preferring deeper hierarchy levels that directly contain the sub-resource
-	resourceTypeParts := strings.Split(resourceTypeLower, "/")
-	
-	// Check if the sub-resource name appears in the resource type path
-	// Search from the end to find the most specific match
-	subResourceFound := false
-	subResourcePosition := -1
-	for i := len(resourceTypeParts) - 1; i >= 0; i-- {
-		part := resourceTypeParts[i]
-		if strings.Contains(part, subResource) {
-			subResourceFound = true
-			subResourcePosition = i
-			// Continue searching backwards for an even more specific match
-			// but if we find a direct match (part == subResource+"s" or part == subResource), prefer it
-			if part == subResource || part == subResource+"s" {
-				break
-			}
-		}
-	}
-	
-	if !subResourceFound {
-		return false
-	}
-	
-	// Prefer more specific resource types: 
-	// The sub-resource should appear towards the end of the path for specificity
-	// For example: "storageAccounts/blobServices/containers/blobs" is more specific than "storageAccounts/blobServices"
-	totalParts := len(resourceTypeParts)
-	
-	// Only match if the sub-resource appears in the last 2 parts of the path
-	// This ensures we get the most specific permissions
-	if subResourcePosition < totalParts-2 {
-		return false
+
+	provider, err := c.azureClient.FetchProviderNamespace(context.Background(), accessToken, namespace)
+	var httpErr *azure.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		return models.ProviderOperationsResponse{}, catalog.ErrNamespaceNotFound
 	}
-	
-	// Additional heuristic: avoid monitoring/insights resources unless they're specifically requested
-	if strings.Contains(resourceTypeLower, "insights") || strings.Contains(resourceTypeLower, "monitoring") {
-		// Only include if the operation is specifically about insights/monitoring
-		if !strings.Contains(strings.ToLower(operation), "monitor") && 
-		   !strings.Contains(strings.ToLower(operation), "metric") && 
-		   !strings.Contains(strings.ToLower(operation), "diagnostic") {
-			return false
-		}
+	if err != nil {
+		return models.ProviderOperationsResponse{}, err
 	}
-	
-	return true
-}
 
-func (c *CLI) matchesOperation(cmdOp, apiOp string) bool {
-	cmdOp = strings.ToLower(cmdOp)
-	apiOp = strings.ToLower(apiOp)
-
-	// Direct match first
-	if strings.Contains(apiOp, cmdOp) {
-		return true
+	if c.debugMode {
+		c.colors.Info.Fprintf(os.Stderr, "📊 Retrieved %d operations and %d resource types for %s\n", len(provider.Operations), len(provider.ResourceTypes), namespace)
 	}
+	return provider, nil
+}
 
-	// Enhanced operation mapping that includes data plane patterns
-	operationMap := map[string][]string{
-		"create":  {"write", "create"},
-		"update":  {"write", "update"},
-		"set":     {"write", "set", "setsecret", "setkey", "setcertificate"},
-		"delete":  {"delete", "remove", "deletesecret", "deletekey", "deletecertificate"},
-		"remove":  {"delete", "remove"},
-		"list":    {"read", "list", "getsecret", "getkey", "getcertificate"},
-		"show":    {"read", "get", "getsecret", "getkey", "getcertificate"},
-		"get":     {"read", "get", "getsecret", "getkey", "getcertificate"},
-		"start":   {"start"},
-		"stop":    {"poweroff", "stop"},
-		"restart": {"restart"},
-		"upload":  {"write", "put"},
-		"download": {"read", "get"},
-	}
-
-	if matches, exists := operationMap[cmdOp]; exists {
-		for _, match := range matches {
-			if strings.Contains(apiOp, match) {
-				return true
-			}
-		}
+// getAzureAccessToken attempts to get an access token from Azure CLI
+func (c *CLI) getAzureAccessToken() (string, error) {
+	// Try to get access token using Azure CLI, for the Resource Manager audience of the cloud in use
+	args := []string{"account", "get-access-token", "--query", "accessToken", "--output", "tsv"}
+	if audience := c.azureClient.Cloud().Audience; audience != "" {
+		args = append(args, "--resource", audience)
+	}
+	// Azure CLI accepts either a tenant or a subscription to pick the account
+	if c.tenant != "" {
+		args = append(args, "--tenant", c.tenant)
+	} else if c.subscription != "" {
+		args = append(args, "--subscription", c.subscription)
+	}
+	cmd := exec.Command("az", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token from Azure CLI (make sure you're logged in with 'az login'): %w", err)
 	}
 
-	// Additional data plane operation patterns
-	// Many data plane operations end with "/action"
-	if strings.HasSuffix(apiOp, "/action") {
-		actionName := strings.TrimSuffix(apiOp, "/action")
-		if strings.Contains(actionName, cmdOp) {
-			return true
-		}
-		
-		// Check if the action name contains operation patterns
-		for _, match := range operationMap[cmdOp] {
-			if strings.Contains(actionName, match) {
-				return true
-			}
-		}
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return "", fmt.Errorf("empty access token returned from Azure CLI")
 	}
 
-	return false
+	return token, nil
 }
 
-func (c *CLI) suggestOperationsFromLiveData(cmd *models.AzureCommand, providerOps models.ProviderOperationsResponse) []string {
-	var suggestions []string
-	
-	// Find the most likely resource type
-	var bestResourceType *models.ProviderResourceType
-	for i, rt := range providerOps.ResourceTypes {
-		if c.matchesResourceType(cmd, rt.Name) {
-			bestResourceType = &providerOps.ResourceTypes[i]
-			break
-		}
+// getCurrentSubscription returns the configured subscription, or the ID of the subscription
+// currently selected in Azure CLI
+func (c *CLI) getCurrentSubscription() (string, error) {
+	if c.subscription != "" {
+		return c.subscription, nil
 	}
 
-	if bestResourceType == nil && len(providerOps.ResourceTypes) > 0 {
-		bestResourceType = &providerOps.ResourceTypes[0]
+	cmd := exec.Command("az", "account", "show", "--query", "id", "--output", "tsv")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current subscription from Azure CLI (make sure you're logged in with 'az login'): %w", err)
 	}
 
-	if bestResourceType != nil {
-		operationMap := map[string][]string{
-			"create":  {"write"},
-			"update":  {"write"},
-			"set":     {"write"},
-			"delete":  {"delete"},
-			"remove":  {"delete"},
-			"list":    {"read"},
-			"show":    {"read"},
-			"get":     {"read"},
-			"start":   {"start"},
-			"stop":    {"poweroff", "stop"},
-			"restart": {"restart"},
-		}
-
-		if patterns, exists := operationMap[strings.ToLower(cmd.Operation)]; exists {
-			for _, op := range bestResourceType.Operations {
-				for _, pattern := range patterns {
-					if strings.Contains(strings.ToLower(op.Name), pattern) {
-						suggestions = append(suggestions, op.Name)
-					}
-				}
-			}
-		}
-
-		// If no suggestions yet, add read permission as fallback
-		if len(suggestions) == 0 {
-			for _, op := range bestResourceType.Operations {
-				if strings.Contains(strings.ToLower(op.Name), "read") {
-					suggestions = append(suggestions, op.Name)
-					break
-				}
-			}
-		}
+	subscriptionID := strings.TrimSpace(string(output))
+	if subscriptionID == "" {
+		return "", fmt.Errorf("no subscription selected in Azure CLI")
 	}
 
-	return suggestions
+	return subscriptionID, nil
 }
 
 // getIntelligentSuggestions provides intelligent permission suggestions
@@ -770,34 +569,10 @@ func (c *CLI) getIntelligentSuggestions(cmd *models.AzureCommand) []string {
 func (c *CLI) refineGenericPermissions(cmd *models.AzureCommand, generic []string) []string {
 	var refined []string
 
-	// Map services to resource providers
-	serviceToProvider := map[string]string{
-		"group":      "Microsoft.Resources",
-		"vm":         "Microsoft.Compute", 
-		"storage":    "Microsoft.Storage",
-		"webapp":     "Microsoft.Web",
-		"keyvault":   "Microsoft.KeyVault",
-		"network":    "Microsoft.Network",
-		"sql":        "Microsoft.Sql",
-		"aks":        "Microsoft.ContainerService",
-		"role":       "Microsoft.Authorization",
-	}
-
-	// Map services to resource types
-	serviceToResource := map[string]string{
-		"group":       "subscriptions/resourceGroups",
-		"vm":          "virtualMachines",
-		"storage":     "storageAccounts",
-		"webapp":      "sites",
-		"keyvault":    "vaults",
-		"network":     "virtualNetworks",
-		"sql":         "servers",
-		"aks":         "managedClusters",
-		"role":        "roleAssignments",
-	}
-
-	provider := serviceToProvider[cmd.Service]
-	resource := serviceToResource[cmd.Service]
+	var provider, resource string
+	if service, ok := registry.Default().Exact(cmd.Service); ok {
+		provider, resource = service.Provider, service.ResourceType
+	}
 
 	if provider != "" && resource != "" {
 		for _, perm := range generic {
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Supported output formats
+const (
+	OutputText     = "text"
+	OutputJSON     = "json"
+	OutputMarkdown = "markdown"
+	OutputCSV      = "csv"
+	OutputHTML     = "html"
+	OutputSARIF    = "sarif"
+)
+
+// isReportFormat reports whether a format is a document report rendered by writeReport
+func isReportFormat(format string) bool {
+	return format == OutputMarkdown || format == OutputCSV || format == OutputHTML || format == OutputSARIF
+}
+
+// supportsReports reports whether a subcommand can render report formats
+func supportsReports(name string) bool {
+	return name == "scan" || name == "workflow" || name == "pipeline"
+}
+
+// subcommand returns the handler for a named subcommand, or nil if the name is not a subcommand
+func (c *CLI) subcommand(name string) func(args []string) error {
+	switch name {
+	case "serve":
+		return c.RunServe
+	case "scan":
+		return c.RunScan
+	case "role":
+		return c.RunRole
+	case "repl":
+		return c.RunRepl
+	case "exec":
+		return c.RunExec
+	case "explain-error":
+		return c.RunExplainError
+	case "activity":
+		return c.RunActivity
+	case "catalog":
+		return c.RunCatalog
+	case "expand":
+		return c.RunExpand
+	case "which-commands":
+		return c.RunWhichCommands
+	case "ops":
+		return c.RunOps
+	case "workflow":
+		return c.RunWorkflow
+	case "pipeline":
+		return c.RunPipeline
+	case "config":
+		return c.RunConfig
+	case "doctor":
+		return c.RunDoctor
+	case "registry":
+		return c.RunRegistry
+	default:
+		return nil
+	}
+}
+
+// parseInterspersed parses flags that may appear before, between or after positional
+// arguments and returns the positional arguments. Arguments after "--" are never parsed as flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		remaining := fs.Args()
+		consumed := len(args) - len(remaining)
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, remaining...), nil
+		}
+		if len(remaining) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+// stringSliceFlag is a repeatable string flag
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// ExitError is returned when azperm must exit with a specific status code.
+// When Err is nil the exit is silent because the reason was already reported.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
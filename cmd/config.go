@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/azure"
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/config"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/permissions"
+	"github.com/mathwro/azperm/internal/policy"
+)
+
+// ApplyConfig applies the effective settings of the layered configuration
+func (c *CLI) ApplyConfig(cfg *config.Config) error {
+	c.config = cfg
+
+	if err := c.loadServiceExtensions(); err != nil {
+		return err
+	}
+
+	if path := cfg.String(config.KeyMappings); path != "" {
+		mapping, err := permissions.LoadMappingFile(path)
+		if err != nil {
+			return err
+		}
+		c.overrides = mapping.Commands
+		c.permManager.SetOverrides(mapping)
+		c.resolver.SetOverrides(mapping.Commands)
+	}
+
+	if version := cfg.String(config.KeyAPIVersion); version != "" {
+		c.azureClient.SetAPIVersion(version)
+	}
+	if endpoint := cfg.String(config.KeyManagementEndpoint); endpoint != "" {
+		c.azureClient.SetManagementEndpoint(endpoint)
+	}
+	if timeout := cfg.Duration(config.KeyTimeout); timeout > 0 {
+		c.azureClient.SetTimeout(timeout)
+	}
+	if retries, ok := cfg.Int(config.KeyRetries); ok {
+		retryPolicy := azure.DefaultRetryPolicy
+		retryPolicy.MaxRetries = retries
+		c.azureClient.SetRetryPolicy(retryPolicy)
+	}
+	err := c.azureClient.ConfigureNetwork(azure.NetworkOptions{
+		Proxy:      cfg.String(config.KeyProxy),
+		CAFile:     cfg.String(config.KeyCAFile),
+		ClientCert: cfg.String(config.KeyClientCert),
+		ClientKey:  cfg.String(config.KeyClientKey),
+	})
+	if err != nil {
+		return err
+	}
+	if cloud := cfg.String(config.KeyCloud); cloud != "" {
+		if err := c.SetCloud(cloud); err != nil {
+			return err
+		}
+	}
+	c.tenant = cfg.String(config.KeyTenant)
+	c.subscription = cfg.String(config.KeySubscription)
+
+	// Use an offline catalog snapshot if requested
+	catalogFile := cfg.String(config.KeyCatalog)
+	if cfg.Bool(config.KeyOffline) && catalogFile == "" {
+		cachePath, err := catalog.DefaultCachePath()
+		if err != nil {
+			return err
+		}
+		catalogFile = cachePath
+	}
+	if catalogFile != "" {
+		c.SetCatalogFile(catalogFile)
+		c.offline = true
+	}
+	c.providersFile = cfg.String(config.KeyProviders)
+
+	// Load the permission policy, picking up the default file from the working directory
+	policyFile := cfg.String(config.KeyPolicy)
+	if policyFile == "" {
+		if _, err := os.Stat(policy.DefaultFile); err == nil {
+			policyFile = policy.DefaultFile
+		}
+	}
+	if policyFile != "" {
+		if err := c.SetPolicyFile(policyFile); err != nil {
+			return err
+		}
+	}
+
+	return c.SetOutputFormat(cfg.String(config.KeyOutput))
+}
+
+// RunConfig shows and edits the layered configuration
+func (c *CLI) RunConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: azperm config <get|set|list|profiles|explain> [flags]")
+	}
+	if c.config == nil {
+		cfg, err := config.Load(nil)
+		if err != nil {
+			return err
+		}
+		c.config = cfg
+	}
+
+	switch args[0] {
+	case "get":
+		return c.runConfigGet(args[1:])
+	case "set":
+		return c.runConfigSet(args[1:])
+	case "list":
+		return c.runConfigList(args[1:])
+	case "profiles":
+		return c.runConfigProfiles(args[1:])
+	case "explain":
+		return c.runConfigExplain(args[1:])
+	default:
+		return fmt.Errorf("unknown config command: %s (expected 'get', 'set', 'list', 'profiles' or 'explain')", args[0])
+	}
+}
+
+// runConfigGet prints the effective value of a setting
+func (c *CLI) runConfigGet(args []string) error {
+	fs := flag.NewFlagSet("config get", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm config get <key>")
+		fmt.Fprintln(fs.Output(), "Prints the effective value of a setting")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a single key")
+	}
+	if _, err := configKey(fs.Arg(0)); err != nil {
+		return err
+	}
+
+	value := c.config.Get(fs.Arg(0))
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, value)
+	}
+	fmt.Println(value.Value)
+	return nil
+}
+
+// runConfigSet writes a setting to the user, repository or system config file
+func (c *CLI) runConfigSet(args []string) error {
+	fs := flag.NewFlagSet("config set", flag.ContinueOnError)
+	profile := fs.String("profile", "", "Set the value in a named profile")
+	repo := fs.Bool("repo", false, "Write to the repository config file ("+config.RepoFile+")")
+	system := fs.Bool("system", false, "Write to the system-wide config file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm config set [flags] <key> <value>")
+		fmt.Fprintln(fs.Output(), "Writes a setting to the user config file unless --repo or --system is given")
+		fmt.Fprintln(fs.Output(), "Example: azperm config set --profile gov cloud AzureUSGovernment")
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected a key and a value")
+	}
+	if *repo && *system {
+		return fmt.Errorf("--repo and --system cannot be used together")
+	}
+	name, value := positional[0], positional[1]
+	key, err := configKey(name)
+	if err != nil {
+		return err
+	}
+
+	var path string
+	switch {
+	case *repo:
+		path = config.RepoPath()
+	case *system:
+		path = config.SystemPath()
+	default:
+		if path, err = config.UserPath(); err != nil {
+			return err
+		}
+	}
+
+	// Paths are stored relative to the repository file so it can be shared, otherwise absolute
+	if key.Path {
+		absolute, err := filepath.Abs(value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", value, err)
+		}
+		value = absolute
+		if *repo {
+			if relative, err := filepath.Rel(filepath.Dir(path), absolute); err == nil {
+				value = filepath.ToSlash(relative)
+			}
+		}
+	}
+
+	if err := config.Set(path, *profile, name, value); err != nil {
+		return err
+	}
+
+	c.colors.Success.Fprintf(os.Stderr, "✅ Set %s = %s in %s\n", name, value, path)
+	return nil
+}
+
+// runConfigList prints the effective value of every setting and where it comes from
+func (c *CLI) runConfigList(args []string) error {
+	fs := flag.NewFlagSet("config list", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm config list")
+		fmt.Fprintln(fs.Output(), "Lists the effective settings and the layer each one comes from")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	values := c.config.List()
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, values)
+	}
+
+	c.colors.Header.Println("⚙️  Effective configuration")
+	if profile, source := c.config.ActiveProfile(); profile != "" {
+		c.colors.Info.Printf("   Profile: %s (selected by %s)\n", profile, source)
+	}
+	fmt.Println()
+	for _, value := range values {
+		fmt.Printf("  %-20s %-30s ", value.Key, value.Value)
+		c.colors.Info.Println(valueSource(value))
+	}
+	fmt.Println()
+	return nil
+}
+
+// runConfigProfiles lists the named profiles defined in the config files
+func (c *CLI) runConfigProfiles(args []string) error {
+	fs := flag.NewFlagSet("config profiles", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm config profiles")
+		fmt.Fprintln(fs.Output(), "Lists the named profiles and their settings")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	profiles := c.config.Profiles()
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, profiles)
+	}
+
+	if len(profiles) == 0 {
+		c.colors.Warning.Println("⚠️  No profiles defined")
+		c.colors.Warning.Println("   Create one with: azperm config set --profile <name> <key> <value>")
+		return nil
+	}
+
+	for _, profile := range profiles {
+		marker := " "
+		if profile.Active {
+			marker = "*"
+		}
+		c.colors.Header.Printf("%s %s\n", marker, profile.Name)
+		c.colors.Info.Printf("    %s\n", strings.Join(profile.Files, ", "))
+
+		keys := make([]string, 0, len(profile.Values))
+		for key := range profile.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("    %-20s %s\n", key, profile.Values[key])
+		}
+	}
+	return nil
+}
+
+// runConfigExplain shows the precedence of the layers and which one sets each setting
+func (c *CLI) runConfigExplain(args []string) error {
+	fs := flag.NewFlagSet("config explain", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm config explain [key]")
+		fmt.Fprintln(fs.Output(), "Shows every layer that sets a setting, from highest to lowest precedence")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var names []string
+	if fs.NArg() > 0 {
+		for _, name := range fs.Args() {
+			if _, err := configKey(name); err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+	} else {
+		for _, key := range config.Keys {
+			names = append(names, key.Name)
+		}
+	}
+
+	explanations := make([]config.Explanation, 0, len(names))
+	for _, name := range names {
+		explanations = append(explanations, c.config.Explain(name))
+	}
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, explanations)
+	}
+
+	c.colors.Header.Println("⚙️  Configuration precedence (lowest to highest)")
+	c.colors.Info.Printf("   %s\n", strings.Join(c.config.Precedence(), " < "))
+	fmt.Println()
+	for _, explanation := range explanations {
+		c.colors.Header.Printf("%s", explanation.Key)
+		c.colors.Info.Printf(" (%s)\n", explanation.Env)
+		if len(explanation.Candidates) == 0 {
+			fmt.Println("    (not set)")
+			continue
+		}
+		for i, candidate := range explanation.Candidates {
+			if i == 0 {
+				c.colors.Success.Printf("  → %-30s %s\n", candidate.Value, valueSource(candidate))
+			} else {
+				fmt.Printf("    %-30s %s (overridden)\n", candidate.Value, valueSource(candidate))
+			}
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// configKey looks up a setting, listing the supported ones when it is unknown
+func configKey(name string) (config.Key, error) {
+	key, ok := config.LookupKey(name)
+	if !ok {
+		var names []string
+		for _, key := range config.Keys {
+			names = append(names, key.Name)
+		}
+		return config.Key{}, fmt.Errorf("unknown config key: %s (supported: %s)", name, strings.Join(names, ", "))
+	}
+	return key, nil
+}
+
+// valueSource describes the layer a value comes from
+func valueSource(value config.Value) string {
+	if value.Path != "" {
+		return fmt.Sprintf("[%s: %s]", value.Source, value.Path)
+	}
+	return fmt.Sprintf("[%s]", value.Source)
+}
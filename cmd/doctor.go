@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/mathwro/azperm/internal/azure"
+	"github.com/mathwro/azperm/internal/display"
+)
+
+// Statuses of doctor checks
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// certificateExpiryWarning is how long before expiry a client certificate is reported
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// doctorCheck is the outcome of a single environment check
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// doctorReport describes the effective environment of azperm
+type doctorReport struct {
+	Version     string            `json:"version"`
+	Profile     string            `json:"profile,omitempty"`
+	Cloud       string            `json:"cloud"`
+	CloudSource string            `json:"cloudSource"`
+	Network     azure.NetworkInfo `json:"network"`
+	Checks      []doctorCheck     `json:"checks"`
+}
+
+// RunDoctor reports the effective cloud, proxy and TLS configuration and checks connectivity
+func (c *CLI) RunDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	noConnect := fs.Bool("no-connect", false, "Skip the connectivity check against the management endpoint")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm doctor [flags]")
+		fmt.Fprintln(fs.Output(), "Reports the effective cloud, proxy and TLS configuration and checks that Azure is reachable")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report := doctorReport{Version: c.Version(), Network: c.azureClient.NetworkInfo()}
+	if c.config != nil {
+		report.Profile, _ = c.config.ActiveProfile()
+	}
+	if cloudName, _, source, err := c.azureClient.GetEffectiveCloudInfo(); err == nil {
+		report.Cloud, report.CloudSource = cloudName, source
+	}
+
+	if path, err := exec.LookPath("az"); err == nil {
+		report.Checks = append(report.Checks, doctorCheck{Name: "Azure CLI", Status: checkOK, Detail: path})
+	} else {
+		report.Checks = append(report.Checks, doctorCheck{Name: "Azure CLI", Status: checkFail, Detail: "az not found in PATH; access tokens cannot be requested"})
+	}
+
+	if !report.Network.ClientCertExpiry.IsZero() {
+		report.Checks = append(report.Checks, clientCertificateCheck(report.Network.ClientCertExpiry))
+	}
+
+	if !*noConnect {
+		if elapsed, err := c.azureClient.CheckConnectivity(); err != nil {
+			report.Checks = append(report.Checks, doctorCheck{Name: "Connectivity", Status: checkFail, Detail: err.Error()})
+		} else {
+			report.Checks = append(report.Checks, doctorCheck{Name: "Connectivity", Status: checkOK, Detail: fmt.Sprintf("%s reachable in %s", report.Network.Endpoint, elapsed.Round(time.Millisecond))})
+		}
+	}
+
+	if c.outputFormat == OutputJSON {
+		if err := display.WriteJSON(os.Stdout, report); err != nil {
+			return err
+		}
+	} else {
+		c.displayDoctorReport(report)
+	}
+
+	for _, check := range report.Checks {
+		if check.Status == checkFail {
+			return &ExitError{Code: 1}
+		}
+	}
+	return nil
+}
+
+// clientCertificateCheck warns about expired or soon expiring client certificates
+func clientCertificateCheck(expiry time.Time) doctorCheck {
+	check := doctorCheck{Name: "Client certificate", Status: checkOK, Detail: "valid until " + expiry.Format(time.RFC3339)}
+	switch {
+	case time.Now().After(expiry):
+		check.Status, check.Detail = checkFail, "expired on "+expiry.Format(time.RFC3339)
+	case time.Until(expiry) < certificateExpiryWarning:
+		check.Status, check.Detail = checkWarn, "expires on "+expiry.Format(time.RFC3339)
+	}
+	return check
+}
+
+// displayDoctorReport prints the doctor report as text
+func (c *CLI) displayDoctorReport(report doctorReport) {
+	c.colors.Header.Printf("🩺 azperm v%s\n", report.Version)
+	fmt.Println()
+
+	if report.Profile != "" {
+		fmt.Printf("  %-20s %s\n", "Profile", report.Profile)
+	}
+	fmt.Printf("  %-20s %s (source: %s)\n", "Cloud", report.Cloud, report.CloudSource)
+	fmt.Printf("  %-20s %s\n", "Endpoint", report.Network.Endpoint)
+	if report.Network.Proxy != "" {
+		fmt.Printf("  %-20s %s (source: %s)\n", "Proxy", report.Network.Proxy, report.Network.ProxySource)
+	} else {
+		fmt.Printf("  %-20s none\n", "Proxy")
+	}
+	if report.Network.NoProxy != "" {
+		fmt.Printf("  %-20s %s\n", "No proxy", report.Network.NoProxy)
+	}
+	if report.Network.CAFile != "" {
+		fmt.Printf("  %-20s %s (%d certificate(s) added to the system roots)\n", "CA bundle", report.Network.CAFile, report.Network.CACertificates)
+	} else {
+		fmt.Printf("  %-20s system roots\n", "CA bundle")
+	}
+	if report.Network.ClientCert != "" {
+		fmt.Printf("  %-20s %s (%s)\n", "Client certificate", report.Network.ClientCert, report.Network.ClientCertSubject)
+	}
+	fmt.Println()
+
+	for _, check := range report.Checks {
+		switch check.Status {
+		case checkOK:
+			c.colors.Success.Printf("  ✅ %s: %s\n", check.Name, check.Detail)
+		case checkWarn:
+			c.colors.Warning.Printf("  ⚠️  %s: %s\n", check.Name, check.Detail)
+		default:
+			c.colors.Error.Printf("  ❌ %s: %s\n", check.Name, check.Detail)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/azure"
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// exitCodeMissingPermissions is returned when the preflight check finds missing permissions (EX_NOPERM)
+const exitCodeMissingPermissions = 77
+
+// RunExec checks that the current principal holds the permissions an Azure CLI command
+// needs and only then runs it, passing through stdin, stdout, stderr and the exit code
+func (c *CLI) RunExec(args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	scopeFlag := fs.String("scope", "", "Scope to check the permissions at (default: computed from the command)")
+	checkOnly := fs.Bool("check-only", false, "Only check the permissions, don't run the command")
+	allowUnresolved := fs.Bool("allow-unresolved", false, "Run the command even if its permissions cannot be resolved")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm exec [flags] -- az <command> [args...]")
+		fmt.Fprintln(fs.Output(), "Runs an Azure CLI command only if the current principal has the required permissions")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	azArgs := fs.Args()
+	if len(azArgs) < 2 || azArgs[0] != "az" {
+		fs.Usage()
+		return fmt.Errorf("expected an Azure CLI command after '--'")
+	}
+
+	// Status output goes to stderr so the command's own stdout is passed through untouched
+	c.quiet = true
+
+	cmd, err := parser.ParseAzureCommand(strings.Join(azArgs, " "))
+	if err != nil {
+		return fmt.Errorf("failed to parse Azure command: %w", err)
+	}
+
+	providers, err := c.catalog.Namespaces(c.resolver.Namespaces(cmd)...)
+	if err != nil {
+		return err
+	}
+
+	required, err := c.resolver.Resolve(cmd, catalog.NewStore(providers))
+	if err == nil && len(required) == 0 {
+		err = fmt.Errorf("no permissions found for command: %s", cmd.FullCmd)
+	}
+	if err != nil {
+		if !*allowUnresolved {
+			return &ExitError{Code: 1, Err: fmt.Errorf("cannot verify permissions: %w (use --allow-unresolved to run anyway)", err)}
+		}
+		c.colors.Warning.Fprintf(os.Stderr, "⚠️  Cannot verify permissions: %v\n", err)
+		return c.runAz(azArgs[1:], *checkOnly)
+	}
+
+	accessToken, err := c.getAzureAccessToken()
+	if err != nil {
+		return err
+	}
+
+	// Commands on several resources (--ids) are checked at each of them
+	scopes := []string{*scopeFlag}
+	if *scopeFlag == "" {
+		subscriptionID := cmd.Parameters["subscription"]
+		if subscriptionID == "" {
+			if subscriptionID, err = c.getCurrentSubscription(); err != nil {
+				return err
+			}
+		}
+		scopes = permissions.ComputeScopes(cmd, subscriptionID, required)
+		if len(scopes) == 0 {
+			return fmt.Errorf("cannot determine the scope of the command; use --scope")
+		}
+	}
+
+	var checkedScopes []string
+	for _, scope := range scopes {
+		grants, checkedScope, err := c.fetchEffectivePermissions(accessToken, scope)
+		if err != nil {
+			return err
+		}
+
+		missing := permissions.MissingPermissions(required, grants)
+		if len(missing) > 0 {
+			c.reportMissingPermissions(accessToken, checkedScope, required, missing)
+			return &ExitError{Code: exitCodeMissingPermissions}
+		}
+		checkedScopes = append(checkedScopes, checkedScope)
+	}
+
+	c.colors.Success.Fprintf(os.Stderr, "✅ All %d required permission(s) present at %s\n", len(required), strings.Join(checkedScopes, ", "))
+	return c.runAz(azArgs[1:], *checkOnly)
+}
+
+// fetchEffectivePermissions returns the caller's permissions at the scope, walking up to the
+// parent scope when the target does not exist yet
+func (c *CLI) fetchEffectivePermissions(accessToken, scope string) ([]models.Permission, string, error) {
+	for {
+		grants, err := c.azureClient.FetchPermissions(context.Background(), accessToken, scope)
+
+		var httpErr *azure.HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == 404 && permissions.ParentScope(scope) != "/" {
+			scope = permissions.ParentScope(scope)
+			continue
+		}
+
+		return grants, scope, err
+	}
+}
+
+// reportMissingPermissions prints the missing permissions and the built-in roles that would grant them
+func (c *CLI) reportMissingPermissions(accessToken, scope string, required, missing []models.PermissionDetail) {
+	c.colors.Error.Fprintf(os.Stderr, "❌ Missing %d of %d required permission(s) at %s:\n", len(missing), len(required), scope)
+	for _, permission := range missing {
+		fmt.Fprintf(os.Stderr, "  • %s\n", permission.Name)
+		if permission.Description != "" {
+			fmt.Fprintf(os.Stderr, "      %s\n", permission.Description)
+		}
+	}
+
+	roles, err := c.azureClient.FetchRoleDefinitions(context.Background(), accessToken, permissions.SubscriptionScope(scope), true)
+	if err != nil {
+		c.colors.Warning.Fprintf(os.Stderr, "⚠️  Could not look up built-in roles: %v\n", err)
+		return
+	}
+
+	suggestions := permissions.SuggestRoles(required, roles, 3)
+	if len(suggestions) == 0 {
+		c.colors.Warning.Fprintln(os.Stderr, "💡 No single built-in role grants all required permissions; consider 'azperm role create'")
+		return
+	}
+
+	fmt.Fprintln(os.Stderr)
+	c.colors.Info.Fprintln(os.Stderr, "💡 Suggested built-in role(s):")
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(os.Stderr, "  • %s\n", suggestion.Name)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintf(os.Stderr, "  az role assignment create --assignee <principal-id> --role \"%s\" --scope \"%s\"\n", suggestions[0].Name, scope)
+}
+
+// runAz runs the Azure CLI with the given arguments, passing through standard streams and the exit code
+func (c *CLI) runAz(args []string, checkOnly bool) error {
+	if checkOnly {
+		return nil
+	}
+
+	cmd := exec.Command("az", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ExitError{Code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("failed to run az: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// RunExpand lists the concrete provider operations granted by wildcard permission patterns
+func (c *CLI) RunExpand(args []string) error {
+	var notPatterns, roleFiles stringSliceFlag
+
+	fs := flag.NewFlagSet("expand", flag.ContinueOnError)
+	dataActions := fs.Bool("data-actions", false, "Treat the patterns as dataActions instead of actions")
+	fs.Var(&notPatterns, "not", "Pattern to subtract, as in NotActions/NotDataActions (repeatable)")
+	fs.Var(&roleFiles, "role", "Role definition file whose permissions to expand (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm expand [flags] <pattern>...")
+		fmt.Fprintln(fs.Output(), "Expands wildcard permission patterns against the provider operations catalog")
+		fmt.Fprintln(fs.Output(), "Example: azperm expand 'Microsoft.Compute/*/read' --not 'Microsoft.Compute/disks/*'")
+		fs.PrintDefaults()
+	}
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var expansions []models.PermissionExpansion
+	if len(patterns) > 0 {
+		permission := models.Permission{Actions: []string{}, NotActions: []string{}, DataActions: []string{}, NotDataActions: []string{}}
+		if *dataActions {
+			permission.DataActions, permission.NotDataActions = patterns, notPatterns
+		} else {
+			permission.Actions, permission.NotActions = patterns, notPatterns
+		}
+		expansions = append(expansions, models.PermissionExpansion{Permission: permission})
+	}
+	for _, path := range roleFiles {
+		roles, err := permissions.LoadRoleDefinitions(path)
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			for _, permission := range permissions.RolePermissions(role) {
+				expansions = append(expansions, models.PermissionExpansion{Role: role.Name, Permission: permission})
+			}
+		}
+	}
+	if len(expansions) == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected at least one pattern or --role")
+	}
+
+	c.quiet = true
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	operations := store.Operations()
+	for i := range expansions {
+		expansions[i].Operations = permissions.ExpandPermission(expansions[i].Permission, operations)
+	}
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, expansions)
+	}
+
+	for _, expansion := range expansions {
+		c.colors.DisplayPermissionExpansion(expansion)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// RunExplainError turns Azure authorization errors into actionable permission reports
+func (c *CLI) RunExplainError(args []string) error {
+	fs := flag.NewFlagSet("explain-error", flag.ContinueOnError)
+	lastError := fs.Bool("last-error", false, "Read the error from the most recent Azure CLI command log")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm explain-error [flags] [error-file]")
+		fmt.Fprintln(fs.Output(), "Explains AuthorizationFailed errors from az output (reads stdin when no file is given)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var text string
+	if *lastError {
+		logFile, err := latestAzureCLILog()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(logFile)
+		if err != nil {
+			return fmt.Errorf("failed to read Azure CLI log: %w", err)
+		}
+		text = string(content)
+	} else {
+		_, content, err := readScriptInput(fs.Args())
+		if err != nil {
+			return err
+		}
+		text = content
+	}
+
+	failures := parser.ParseAuthorizationErrors(text)
+	if len(failures) == 0 {
+		return fmt.Errorf("no authorization errors found in the input")
+	}
+
+	c.quiet = true
+	explanations := c.explainFailures(failures)
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, explanations)
+	}
+
+	c.colors.DisplayErrorExplanations(explanations)
+	return nil
+}
+
+// explainFailures enriches authorization failures with operation details and role suggestions.
+// Lookups that fail (e.g. when not logged in) are reported as warnings and skipped.
+func (c *CLI) explainFailures(failures []models.AuthorizationFailure) []models.ErrorExplanation {
+	providers, err := c.catalog.Providers()
+	if err != nil {
+		c.colors.Warning.Fprintf(os.Stderr, "⚠️  Operation descriptions unavailable: %v\n", err)
+	}
+
+	accessToken, tokenErr := c.getAzureAccessToken()
+	if tokenErr != nil {
+		c.colors.Warning.Fprintf(os.Stderr, "⚠️  Built-in role suggestions unavailable: %v\n", tokenErr)
+	}
+
+	// Role definitions are fetched once per subscription
+	rolesBySubscription := make(map[string][]models.RoleDefinition)
+
+	var explanations []models.ErrorExplanation
+	for _, failure := range failures {
+		explanation := models.ErrorExplanation{
+			AuthorizationFailure: failure,
+			Operations:           []models.OperationInfo{},
+			SuggestedRoles:       []models.RoleSuggestion{},
+			Commands:             []string{},
+		}
+
+		var required []models.PermissionDetail
+		for _, action := range failure.Actions {
+			operation, found := catalog.LookupOperation(providers, action)
+			if !found {
+				operation = models.OperationInfo{ProviderOperation: models.ProviderOperation{
+					Name:         action,
+					IsDataAction: failure.Code == parser.CodeForbiddenByRbac,
+				}}
+			}
+			explanation.Operations = append(explanation.Operations, operation)
+			required = append(required, models.PermissionDetail{
+				Name:         action,
+				IsDataAction: operation.IsDataAction,
+				Provider:     operation.Provider,
+				ResourceType: operation.ResourceType,
+				Description:  operation.Description,
+			})
+		}
+
+		if tokenErr == nil && len(failure.Scopes) > 0 {
+			subscription := permissions.SubscriptionScope(failure.Scopes[0])
+			roles, fetched := rolesBySubscription[subscription]
+			if !fetched {
+				if roles, err = c.azureClient.FetchRoleDefinitions(context.Background(), accessToken, subscription, true); err != nil {
+					c.colors.Warning.Fprintf(os.Stderr, "⚠️  Could not look up built-in roles: %v\n", err)
+				}
+				rolesBySubscription[subscription] = roles
+			}
+			explanation.SuggestedRoles = permissions.SuggestRoles(required, roles, 3)
+		}
+
+		role := "<role-name>"
+		if len(explanation.SuggestedRoles) > 0 {
+			role = explanation.SuggestedRoles[0].Name
+		}
+		assignee := failure.ObjectID
+		if assignee == "" {
+			assignee = "<principal-object-id>"
+		}
+		for _, scope := range failure.Scopes {
+			explanation.Commands = append(explanation.Commands, fmt.Sprintf(
+				"az role assignment create --assignee-object-id %s --role \"%s\" --scope \"%s\"", assignee, role, scope))
+		}
+
+		explanations = append(explanations, explanation)
+	}
+
+	return explanations
+}
+
+// latestAzureCLILog returns the most recently written Azure CLI command log file
+func latestAzureCLILog() (string, error) {
+	configDir := os.Getenv("AZURE_CONFIG_DIR")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not get user home directory: %w", err)
+		}
+		configDir = filepath.Join(homeDir, ".azure")
+	}
+
+	logFiles, err := filepath.Glob(filepath.Join(configDir, "commands", "*.log"))
+	if err != nil || len(logFiles) == 0 {
+		return "", fmt.Errorf("no Azure CLI command logs found in %s", filepath.Join(configDir, "commands"))
+	}
+
+	latest := ""
+	var latestInfo os.FileInfo
+	for _, logFile := range logFiles {
+		info, err := os.Stat(logFile)
+		if err != nil {
+			continue
+		}
+		if latestInfo == nil || info.ModTime().After(latestInfo.ModTime()) ||
+			(info.ModTime().Equal(latestInfo.ModTime()) && logFile > latest) {
+			latest, latestInfo = logFile, info
+		}
+	}
+
+	if latest == "" {
+		return "", fmt.Errorf("no readable Azure CLI command logs found")
+	}
+	return latest, nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+)
+
+// RunOps explores the provider operations catalog
+func (c *CLI) RunOps(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: azperm ops <search|show> [flags] ...")
+	}
+
+	switch args[0] {
+	case "search":
+		return c.runOpsSearch(args[1:])
+	case "show":
+		return c.runOpsShow(args[1:])
+	default:
+		return fmt.Errorf("unknown ops command: %s (expected 'search' or 'show')", args[0])
+	}
+}
+
+// runOpsSearch searches operation names, display names and descriptions
+func (c *CLI) runOpsSearch(args []string) error {
+	var options catalog.SearchOptions
+
+	fs := flag.NewFlagSet("ops search", flag.ContinueOnError)
+	fs.StringVar(&options.Provider, "provider", "", "Only include operations of this provider namespace (e.g. Microsoft.Compute)")
+	fs.StringVar(&options.ResourceType, "resource-type", "", "Only include operations of resource types containing this value")
+	fs.BoolVar(&options.DataActionsOnly, "data-actions", false, "Only include data actions")
+	fs.StringVar(&options.Origin, "origin", "", "Only include operations with this origin (user, system)")
+	fs.IntVar(&options.Limit, "limit", 50, "Maximum number of results (0 for all)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm ops search [flags] <terms>...")
+		fmt.Fprintln(fs.Output(), "Searches the provider operations catalog, ranking name matches first")
+		fs.PrintDefaults()
+	}
+	terms, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	query := strings.Join(terms, " ")
+	if query == "" && options.Provider == "" && options.ResourceType == "" && !options.DataActionsOnly && options.Origin == "" {
+		fs.Usage()
+		return fmt.Errorf("expected search terms or a filter")
+	}
+
+	c.quiet = true
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	matches := store.Search(query, options)
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, matches)
+	}
+
+	c.colors.DisplayOperationMatches(query, matches)
+	return nil
+}
+
+// runOpsShow shows the full details of a single operation
+func (c *CLI) runOpsShow(args []string) error {
+	fs := flag.NewFlagSet("ops show", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm ops show <operation>")
+		fmt.Fprintln(fs.Output(), "Shows the details of a provider operation, e.g. Microsoft.Compute/virtualMachines/start/action")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a single operation name")
+	}
+	name := fs.Arg(0)
+
+	c.quiet = true
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	operation, found := store.Lookup(name)
+	if !found {
+		// Suggest other operations on the same resource type
+		var names []string
+		if index := strings.LastIndex(name, "/"); index > 0 {
+			for _, match := range store.Search(name[:index+1], catalog.SearchOptions{Limit: 5}) {
+				names = append(names, match.Name)
+			}
+		}
+		if len(names) > 0 {
+			return fmt.Errorf("operation not found: %s (did you mean: %s?)", name, strings.Join(names, ", "))
+		}
+		return fmt.Errorf("operation not found: %s", name)
+	}
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, operation)
+	}
+
+	c.colors.DisplayOperation(operation)
+	return nil
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+	"github.com/mathwro/azperm/internal/scanner"
+)
+
+// defaultPipelineFiles are the conventional names of an Azure Pipelines definition
+var defaultPipelineFiles = []string{"azure-pipelines.yml", "azure-pipelines.yaml"}
+
+// RunPipeline analyzes the Azure CLI commands in an Azure Pipelines definition per service connection
+func (c *CLI) RunPipeline(args []string) error {
+	fs := flag.NewFlagSet("pipeline", flag.ContinueOnError)
+	roles := fs.Bool("roles", false, "Print a least-privilege custom role per service connection instead of the analysis")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm pipeline [flags] [azure-pipelines.yml]")
+		fmt.Fprintln(fs.Output(), "Analyzes the az commands in an Azure Pipelines definition and its local templates")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a single pipeline file")
+	}
+	if path == "" {
+		for _, name := range defaultPipelineFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return fmt.Errorf("no azure-pipelines.yml found in the current directory")
+		}
+	}
+
+	pipeline, err := scanner.ScanAzurePipeline(path)
+	if err != nil {
+		return err
+	}
+	for _, warning := range pipeline.Warnings {
+		c.colors.Warning.Fprintf(os.Stderr, "⚠️  %s\n", warning)
+	}
+
+	c.quiet = true
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	result := analyzePipeline(c.resolver, store, path, pipeline)
+
+	var violations []models.PolicyViolation
+	commands := []models.AnalysisResult{}
+	for _, connection := range result.Connections {
+		violations = append(violations, c.evaluatePolicy(path, connection.Commands)...)
+		commands = append(commands, connection.Commands...)
+	}
+
+	switch {
+	case *roles:
+		err = display.WriteJSON(os.Stdout, connectionRoles(result))
+	case c.outputFormat == OutputJSON:
+		err = display.WriteJSON(os.Stdout, result)
+	case isReportFormat(c.outputFormat):
+		// Commands from templates carry their own file
+		err = c.writeReport(scanReport(path, commands, violations))
+	default:
+		c.colors.DisplayPipelineResult(result)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.enforcePolicy(violations)
+}
+
+// analyzePipeline resolves the permissions of every Azure CLI command per service connection
+func analyzePipeline(resolver *permissions.Resolver, store *catalog.Store, source string, pipeline scanner.Pipeline) models.PipelineResult {
+	result := models.PipelineResult{
+		Source:      source,
+		Name:        pipeline.Name,
+		Files:       pipeline.Files,
+		Connections: []models.ConnectionResult{},
+		Warnings:    pipeline.Warnings,
+	}
+
+	for _, connection := range pipeline.Connections {
+		connectionResult := models.ConnectionResult{
+			Name:     connection.Name,
+			Commands: []models.AnalysisResult{},
+		}
+		for _, invocation := range connection.Invocations {
+			analysis := analyzeCommandLine(resolver, store, invocation.Command)
+			analysis.File = invocation.File
+			analysis.Line = invocation.Line
+			analysis.Column = invocation.Column
+			connectionResult.Commands = append(connectionResult.Commands, analysis)
+		}
+		connectionResult.Actions, connectionResult.DataActions = permissions.SplitActions(connectionResult.Commands)
+		result.Connections = append(result.Connections, connectionResult)
+	}
+
+	return result
+}
+
+// connectionRoles builds a least-privilege custom role for every service connection
+func connectionRoles(result models.PipelineResult) []models.RoleDefinition {
+	roles := []models.RoleDefinition{}
+	for _, connection := range result.Connections {
+		if connection.Name == "" || (len(connection.Actions) == 0 && len(connection.DataActions) == 0) {
+			continue
+		}
+		description := fmt.Sprintf("Generated by azperm for service connection '%s' used by %s", connection.Name, result.Source)
+		roles = append(roles, permissions.NewCustomRole(connection.Name, description, nil, connection.Actions, connection.DataActions))
+	}
+	return roles
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/policy"
+)
+
+// SetPolicyFile loads the permission policy that analysis results are checked against
+func (c *CLI) SetPolicyFile(path string) error {
+	loaded, err := policy.Load(path)
+	if err != nil {
+		return err
+	}
+	c.policy = loaded
+	return nil
+}
+
+// evaluatePolicy checks analysis results against the loaded policy. Results without a file
+// are attributed to source.
+func (c *CLI) evaluatePolicy(source string, results []models.AnalysisResult) []models.PolicyViolation {
+	if c.policy == nil {
+		return nil
+	}
+
+	located := make([]models.AnalysisResult, len(results))
+	for i, result := range results {
+		if result.File == "" {
+			result.File = source
+		}
+		located[i] = result
+	}
+	return c.policy.Evaluate(located)
+}
+
+// enforcePolicy reports policy violations and returns the exit code configured for the most
+// severe one
+func (c *CLI) enforcePolicy(violations []models.PolicyViolation) error {
+	if c.policy == nil || len(violations) == 0 {
+		return nil
+	}
+
+	c.colors.DisplayPolicyViolations(violations)
+	if code := c.policy.ExitCode(violations); code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
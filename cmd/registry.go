@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/registry"
+)
+
+// RunRegistry implements the "registry" subcommand, which lists and validates the service
+// registry that maps az command groups to resource providers
+func (c *CLI) RunRegistry(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: azperm registry <list|validate> [flags]")
+	}
+
+	switch args[0] {
+	case "list":
+		return c.runRegistryList(args[1:])
+	case "validate":
+		return c.runRegistryValidate(args[1:])
+	default:
+		return fmt.Errorf("unknown registry command: %s (expected 'list' or 'validate')", args[0])
+	}
+}
+
+// runRegistryList prints the built-in and user-defined services
+func (c *CLI) runRegistryList(args []string) error {
+	fs := flag.NewFlagSet("registry list", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm registry list")
+		fmt.Fprintln(fs.Output(), "Lists the az command groups azperm maps to resource providers")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	services := registry.Default().Services()
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, services)
+	}
+
+	c.colors.Header.Printf("🗂️  %d service(s)\n", len(services))
+	fmt.Println()
+	for _, service := range services {
+		target := service.Provider
+		if service.ResourceType != "" {
+			target += "/" + service.ResourceType
+		}
+		fmt.Printf("  %-32s %s", service.Group, target)
+		if len(service.Aliases) > 0 {
+			c.colors.Info.Printf(" (aliases: %s)", strings.Join(service.Aliases, ", "))
+		}
+		if service.Source != registry.SourceBuiltIn {
+			c.colors.Info.Printf(" [%s]", service.Source)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	return nil
+}
+
+// runRegistryValidate checks every registry entry against the current catalog
+func (c *CLI) runRegistryValidate(args []string) error {
+	fs := flag.NewFlagSet("registry validate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm registry validate")
+		fmt.Fprintln(fs.Output(), "Checks that the provider namespace and resource types of every service exist in the current catalog")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c.quiet = true
+	providers, err := c.catalog.Providers()
+	if err != nil {
+		return err
+	}
+
+	services := registry.Default().Services()
+	issues := registry.Default().Validate(providers)
+
+	if c.outputFormat == OutputJSON {
+		result := struct {
+			Services int              `json:"services"`
+			Issues   []registry.Issue `json:"issues"`
+		}{len(services), issues}
+		if result.Issues == nil {
+			result.Issues = []registry.Issue{}
+		}
+		if err := display.WriteJSON(os.Stdout, result); err != nil {
+			return err
+		}
+	} else if len(issues) == 0 {
+		c.colors.Success.Printf("✅ All %d service(s) exist in the catalog\n", len(services))
+	} else {
+		c.colors.Error.Printf("❌ %d issue(s) in %d service(s)\n", len(issues), len(services))
+		fmt.Println()
+		for _, issue := range issues {
+			fmt.Printf("  %-32s %s", issue.Group, issue.Message)
+			if issue.Source != registry.SourceBuiltIn {
+				c.colors.Info.Printf(" [%s]", issue.Source)
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+
+	if len(issues) > 0 {
+		return &ExitError{Code: 1}
+	}
+	return nil
+}
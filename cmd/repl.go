@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// replPrompt is the prompt shown by the interactive session
+const replPrompt = "azperm> "
+
+// replMetaCommands lists the meta-commands understood by the interactive session
+var replMetaCommands = []string{":explain", ":help", ":history", ":quit", ":role", ":scope", ":exit"}
+
+// RunRepl starts an interactive session that loads the catalog once and analyzes commands as they are typed
+func (c *CLI) RunRepl(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	name := fs.String("name", "", "Name of the role accumulated during the session")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm repl [flags]")
+		fmt.Fprintln(fs.Output(), "Interactive session with az command completion; type :help for meta-commands")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c.permManager.LoadPermissions()
+
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	session := &replSession{
+		cli:      c,
+		store:    store,
+		roleName: *name,
+	}
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		session.fd = fd
+		session.terminal = term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, replPrompt)
+		session.terminal.AutoCompleteCallback = session.complete
+		c.colors.Success.Printf("Loaded %d resource providers. Type :help for help, Tab to complete.\n", len(store.Providers()))
+	} else {
+		session.scanner = bufio.NewScanner(os.Stdin)
+	}
+
+	return session.run()
+}
+
+// replSession holds the state of an interactive session
+type replSession struct {
+	cli   *CLI
+	store *catalog.Store
+
+	fd       int
+	terminal *term.Terminal
+	scanner  *bufio.Scanner
+
+	history  []string
+	lastLine string
+	results  []models.AnalysisResult
+	roleName string
+	scopes   []string
+}
+
+// run reads and evaluates lines until the input ends or the user quits
+func (s *replSession) run() error {
+	for {
+		line, err := s.readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			quit, err := s.meta(line)
+			if err != nil {
+				s.cli.colors.Error.Printf("❌ %v\n", err)
+			}
+			if quit {
+				return nil
+			}
+			continue
+		}
+
+		s.analyze(line)
+	}
+}
+
+// readLine reads the next input line, using the raw-mode line editor when attached to a terminal
+func (s *replSession) readLine() (string, error) {
+	if s.terminal == nil {
+		if s.scanner.Scan() {
+			return s.scanner.Text(), nil
+		}
+		if err := s.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	// Raw mode is only enabled while editing so regular output renders normally
+	state, err := term.MakeRaw(s.fd)
+	if err != nil {
+		return "", fmt.Errorf("failed to enable raw terminal mode: %w", err)
+	}
+	defer term.Restore(s.fd, state)
+
+	return s.terminal.ReadLine()
+}
+
+// analyze resolves and prints the permissions for a command line and adds it to the session role
+func (s *replSession) analyze(line string) {
+	if !strings.HasPrefix(line, "az ") {
+		line = "az " + line
+	}
+
+	cmd, err := parser.ParseAzureCommand(line)
+	if err != nil {
+		s.cli.colors.Error.Printf("❌ failed to parse Azure command: %v\n", err)
+		return
+	}
+
+	result := s.cli.resolver.Analyze(cmd, s.store)
+	s.lastLine = line
+
+	if s.cli.outputFormat == OutputJSON {
+		display.WriteJSON(os.Stdout, result)
+	} else if result.Error != "" {
+		s.cli.colors.ShowNoPermissionsWarning(cmd.FullCmd, true)
+		s.cli.colors.Warning.Printf("   %s\n", result.Error)
+	} else {
+		s.cli.colors.DisplayPermissionsWithLiveQuery(cmd, result.Permissions, result.Confidence)
+	}
+
+	if result.Error == "" {
+		s.history = append(s.history, line)
+		s.results = append(s.results, result)
+	}
+}
+
+// meta evaluates a meta-command and reports whether the session should end
+func (s *replSession) meta(line string) (bool, error) {
+	fields := strings.Fields(line)
+	args := fields[1:]
+
+	switch fields[0] {
+	case ":quit", ":exit":
+		return true, nil
+	case ":help":
+		s.help()
+	case ":explain":
+		return false, s.explain(args)
+	case ":scope":
+		if len(args) > 0 {
+			s.scopes = args
+		}
+		if len(s.scopes) == 0 {
+			fmt.Printf("Assignable scopes: %s (default)\n", permissions.DefaultAssignableScope)
+		} else {
+			fmt.Printf("Assignable scopes: %s\n", strings.Join(s.scopes, ", "))
+		}
+	case ":role":
+		return false, s.role(args)
+	case ":history":
+		if len(s.history) == 0 {
+			fmt.Println("No commands added to the role yet")
+		}
+		for i, command := range s.history {
+			fmt.Printf("%3d  %s\n", i+1, command)
+		}
+	default:
+		return false, fmt.Errorf("unknown meta-command: %s (type :help)", fields[0])
+	}
+
+	return false, nil
+}
+
+// explain re-resolves a command with verbose matching output
+func (s *replSession) explain(args []string) error {
+	line := strings.Join(args, " ")
+	if line == "" {
+		line = s.lastLine
+	}
+	if line == "" {
+		return fmt.Errorf("nothing to explain yet, type an az command first or use :explain az ...")
+	}
+	if !strings.HasPrefix(line, "az ") {
+		line = "az " + line
+	}
+
+	cmd, err := parser.ParseAzureCommand(line)
+	if err != nil {
+		return fmt.Errorf("failed to parse Azure command: %w", err)
+	}
+
+	colors := s.cli.colors
+	colors.Header.Printf("🔎 Explaining: %s\n", line)
+	resolver := permissions.NewResolver(func(format string, args ...interface{}) {
+		colors.Info.Printf("   "+format, args...)
+	})
+	resolver.SetOverrides(s.cli.overrides)
+	resolver.SetDiscovery(s.cli.discoverService)
+
+	result := resolver.Analyze(cmd, s.store)
+	if result.Error != "" {
+		return fmt.Errorf("%s", result.Error)
+	}
+
+	fmt.Println()
+	for _, permission := range result.Permissions {
+		kind := "Action"
+		if permission.IsDataAction {
+			kind = "DataAction"
+		}
+		fmt.Printf("  • %s [%s]\n", permission.Name, kind)
+		if permission.Description != "" {
+			fmt.Printf("      %s\n", permission.Description)
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// role shows, names, saves or resets the role accumulated during the session
+func (s *replSession) role(args []string) error {
+	if len(args) == 0 {
+		return display.WriteJSON(os.Stdout, s.buildRole())
+	}
+
+	switch args[0] {
+	case "name":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: :role name <name>")
+		}
+		s.roleName = strings.Trim(strings.Join(args[1:], " "), "\"'")
+	case "save":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: :role save <file>")
+		}
+		file, err := os.Create(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to create role file: %w", err)
+		}
+		defer file.Close()
+		if err := display.WriteJSON(file, s.buildRole()); err != nil {
+			return fmt.Errorf("failed to write role file: %w", err)
+		}
+		s.cli.colors.Success.Printf("✅ Role saved to %s\n", args[1])
+	case "reset":
+		s.history = nil
+		s.results = nil
+		s.cli.colors.Success.Println("✅ Role reset")
+	default:
+		return fmt.Errorf("unknown :role subcommand: %s (use name, save or reset)", args[0])
+	}
+
+	return nil
+}
+
+// buildRole creates the custom role covering every command analyzed in this session
+func (s *replSession) buildRole() models.RoleDefinition {
+	return permissions.BuildCustomRole(s.roleName, "", s.scopes, s.results)
+}
+
+// help prints the meta-commands
+func (s *replSession) help() {
+	s.cli.colors.Info.Println("Type an Azure CLI command (e.g. az vm start --name myVM) to see its permissions.")
+	s.cli.colors.Info.Println("Each resolved command is added to the session role.")
+	fmt.Println()
+	fmt.Println("  :explain [az ...]        Show how the last (or given) command was resolved")
+	fmt.Println("  :scope [scope ...]       Show or set the assignable scopes of the session role")
+	fmt.Println("  :role                    Show the accumulated custom role definition")
+	fmt.Println("  :role name <name>        Set the role name")
+	fmt.Println("  :role save <file>        Write the role definition to a file")
+	fmt.Println("  :role reset              Remove all commands from the role")
+	fmt.Println("  :history                 List the commands included in the role")
+	fmt.Println("  :quit, :exit             Leave the session (Ctrl-D also works)")
+}
+
+// complete implements tab completion of meta-commands, command groups and parameters
+func (s *replSession) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	head, tail := line[:pos], line[pos:]
+	fields := strings.Fields(head)
+
+	prefix := ""
+	if len(fields) > 0 && !strings.HasSuffix(head, " ") {
+		prefix = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+
+	var candidates []string
+	switch {
+	case len(fields) == 0 && strings.HasPrefix(prefix, ":"):
+		candidates = filterPrefix(replMetaCommands, prefix)
+	case len(fields) == 0:
+		candidates = filterPrefix([]string{"az"}, prefix)
+	case fields[0] != "az":
+		return "", 0, false
+	default:
+		words := fields[1:]
+		commandWords := words
+		for i, word := range words {
+			if strings.HasPrefix(word, "-") {
+				commandWords = words[:i]
+				break
+			}
+		}
+
+		if strings.HasPrefix(prefix, "-") {
+			candidates = parser.CompleteParameter(strings.Join(commandWords, " "), prefix)
+		} else if len(commandWords) == len(words) {
+			candidates = parser.CompleteCommand(words, prefix)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+
+	completion := candidates[0]
+	if len(candidates) > 1 {
+		completion = commonPrefix(candidates)
+		if completion == prefix {
+			// Nothing more to complete, list the alternatives above the prompt
+			fmt.Fprintf(s.terminal, "%s\n", strings.Join(candidates, "  "))
+			return line, pos, true
+		}
+	} else {
+		completion += " "
+	}
+
+	newHead := head[:len(head)-len(prefix)] + completion
+	return newHead + tail, len(newHead), true
+}
+
+// filterPrefix returns the sorted values that start with prefix
+func filterPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// commonPrefix returns the longest common prefix of the values
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// RunRole handles the "role" subcommands
+func (c *CLI) RunRole(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: azperm role <create|inspect> [flags] ...")
+	}
+
+	switch args[0] {
+	case "create":
+		return c.runRoleCreate(args[1:])
+	case "inspect":
+		return c.runRoleInspect(args[1:])
+	default:
+		return fmt.Errorf("unknown role subcommand: %s", args[0])
+	}
+}
+
+// runRoleCreate generates a custom role definition from one command or a script
+func (c *CLI) runRoleCreate(args []string) error {
+	var scopes stringSliceFlag
+
+	fs := flag.NewFlagSet("role create", flag.ContinueOnError)
+	name := fs.String("name", "", "Name of the custom role")
+	description := fs.String("description", "", "Description of the custom role")
+	fs.Var(&scopes, "scope", "Assignable scope for the role (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm role create [flags] [script-file | az ...]")
+		fmt.Fprintln(fs.Output(), "Generates a least-privilege custom role for an Azure CLI command or script")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	var results []models.AnalysisResult
+	if fs.NArg() > 0 && fs.Arg(0) == "az" {
+		results = []models.AnalysisResult{analyzeCommandLine(c.resolver, store, strings.Join(fs.Args(), " "))}
+	} else {
+		source, text, err := readScriptInput(fs.Args())
+		if err != nil {
+			return err
+		}
+		results = analyzeScript(c.resolver, store, source, text).Commands
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no Azure CLI commands found")
+	}
+
+	role := permissions.BuildCustomRole(*name, *description, scopes, results)
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, models.RoleResult{Role: role, Commands: results})
+	}
+
+	// Unresolved commands go to stderr so the role definition can be redirected to a file
+	for _, result := range results {
+		if result.Error != "" {
+			c.colors.Warning.Fprintf(os.Stderr, "⚠️  %s: %s\n", result.Command, result.Error)
+		}
+	}
+
+	return display.WriteJSON(os.Stdout, role)
+}
+
+// runRoleInspect reports which known Azure CLI commands a role definition enables
+func (c *CLI) runRoleInspect(args []string) error {
+	fs := flag.NewFlagSet("role inspect", flag.ContinueOnError)
+	showBlocked := fs.Bool("show-blocked", false, "Also list the commands the role blocks completely")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm role inspect [flags] <role.json | built-in role name>")
+		fmt.Fprintln(fs.Output(), "Lists the known Azure CLI commands a role fully allows, partially allows or blocks")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected a role definition file or a built-in role name")
+	}
+
+	c.quiet = true
+	roles, err := c.loadInspectedRoles(strings.Join(fs.Args(), " "))
+	if err != nil {
+		return err
+	}
+
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	var inspections []models.RoleInspection
+	for _, role := range roles {
+		inspections = append(inspections, c.resolver.InspectRole(role, parser.KnownCommands, store))
+	}
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, inspections)
+	}
+
+	for _, inspection := range inspections {
+		c.colors.DisplayRoleInspection(inspection, *showBlocked)
+	}
+	return nil
+}
+
+// loadInspectedRoles loads role definitions from a file, or looks up a built-in role by name
+func (c *CLI) loadInspectedRoles(nameOrPath string) ([]models.RoleDefinition, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return permissions.LoadRoleDefinitions(nameOrPath)
+	}
+
+	accessToken, err := c.getAzureAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	subscriptionID, err := c.getCurrentSubscription()
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := c.azureClient.FetchRoleDefinitions(context.Background(), accessToken, "/subscriptions/"+subscriptionID, true)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if strings.EqualFold(role.Name, nameOrPath) {
+			return []models.RoleDefinition{role}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s is neither a role definition file nor a built-in role name", nameOrPath)
+}
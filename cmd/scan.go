@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+	"github.com/mathwro/azperm/internal/permissions"
+	"github.com/mathwro/azperm/internal/scanner"
+)
+
+// RunScan analyzes every Azure CLI command in a script file or piped script
+func (c *CLI) RunScan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm scan [script-file]")
+		fmt.Fprintln(fs.Output(), "Analyzes all Azure CLI commands in a bash or PowerShell script (reads stdin when no file is given)")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	source, text, err := readScriptInput(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	result := analyzeScript(c.resolver, store, source, text)
+	result.Violations = c.evaluatePolicy(source, result.Commands)
+
+	switch {
+	case c.outputFormat == OutputJSON:
+		if err := display.WriteJSON(os.Stdout, result); err != nil {
+			return err
+		}
+	case isReportFormat(c.outputFormat):
+		if err := c.writeReport(result); err != nil {
+			return err
+		}
+	default:
+		c.colors.DisplayScanResult(result)
+	}
+
+	return c.enforcePolicy(result.Violations)
+}
+
+// readScriptInput reads a script from the file named in args, or from stdin
+func readScriptInput(args []string) (string, string, error) {
+	if len(args) > 1 {
+		return "", "", fmt.Errorf("expected a single script file, got %d arguments", len(args))
+	}
+
+	if len(args) == 1 && args[0] != "-" {
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read script: %w", err)
+		}
+		return args[0], string(content), nil
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read script from stdin: %w", err)
+	}
+	return "", string(content), nil
+}
+
+// analyzeScript resolves the permissions for every Azure CLI command found in the script text
+func analyzeScript(resolver *permissions.Resolver, store *catalog.Store, source, text string) models.ScanResult {
+	result := models.ScanResult{
+		Source:   source,
+		Commands: []models.AnalysisResult{},
+	}
+
+	for _, invocation := range scanner.ScanScript(text) {
+		analysis := analyzeCommandLine(resolver, store, invocation.Command)
+		analysis.Line = invocation.Line
+		analysis.Column = invocation.Column
+		result.Commands = append(result.Commands, analysis)
+	}
+
+	result.Actions, result.DataActions = permissions.SplitActions(result.Commands)
+	permissions.AssessScanRisk(&result)
+	return result
+}
+
+// analyzeCommandLine parses and resolves a single Azure CLI command line
+func analyzeCommandLine(resolver *permissions.Resolver, store *catalog.Store, line string) models.AnalysisResult {
+	cmd, err := parser.ParseAzureCommand(line)
+	if err != nil {
+		return models.AnalysisResult{
+			Command:     line,
+			Permissions: []models.PermissionDetail{},
+			Confidence:  models.ConfidenceLow,
+			RiskLevel:   models.RiskNone,
+			Error:       fmt.Sprintf("failed to parse Azure command: %v", err),
+		}
+	}
+
+	return resolver.Analyze(cmd, store)
+}
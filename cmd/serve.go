@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// maxRequestBodySize limits the size of request bodies accepted by the API server
+const maxRequestBodySize = 1 << 20
+
+// RunServe starts the HTTP API server
+func (c *CLI) RunServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := fs.String("listen", ":8080", "Address to listen on")
+	refresh := fs.Duration("refresh", 6*time.Hour, "Interval for refreshing the provider operations catalog")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm serve [flags]")
+		fmt.Fprintln(fs.Output(), "Exposes the permission resolver as an HTTP API")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *refresh <= 0 {
+		return fmt.Errorf("refresh interval must be positive")
+	}
+
+	// The server reports through its log, so catalog and discovery progress stays off stdout
+	c.quiet = true
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Warm up the catalog in the background so the first request doesn't pay for it
+	go func() {
+		if _, err := c.catalog.Store(); err != nil {
+			log.Printf("initial catalog load failed: %v", err)
+		}
+	}()
+	c.catalog.StartBackgroundRefresh(ctx, *refresh, func(err error) {
+		log.Printf("catalog refresh failed: %v", err)
+	})
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           newAPIServer(c.catalog, c.overrides, c.discoverService).routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	c.colors.Success.Fprintf(os.Stderr, "🌐 azperm API listening on %s\n", *listen)
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// apiServer serves the azperm HTTP API. It only holds shared, concurrency-safe
+// components so a single instance can serve any number of requests in parallel.
+type apiServer struct {
+	catalog  *catalog.Catalog
+	resolver *permissions.Resolver
+}
+
+// newAPIServer creates a new API server backed by the given catalog, mapping overrides and
+// service discovery
+func newAPIServer(cat *catalog.Catalog, overrides map[string][]string, discover permissions.DiscoverFunc) *apiServer {
+	resolver := permissions.NewResolver(nil)
+	resolver.SetOverrides(overrides)
+	resolver.SetDiscovery(discover)
+	return &apiServer{
+		catalog:  cat,
+		resolver: resolver,
+	}
+}
+
+// routes registers the API endpoints
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/analyze", s.handleAnalyze)
+	mux.HandleFunc("POST /v1/scan", s.handleScan)
+	mux.HandleFunc("POST /v1/role", s.handleRole)
+	mux.HandleFunc("GET /v1/operations", s.handleOperations)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	return mux
+}
+
+// analyzeRequest is the body of POST /v1/analyze
+type analyzeRequest struct {
+	Command string `json:"command"`
+}
+
+// scanRequest is the body of POST /v1/scan
+type scanRequest struct {
+	Source string `json:"source"`
+	Script string `json:"script"`
+}
+
+// roleRequest is the body of POST /v1/role
+type roleRequest struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	AssignableScopes []string `json:"assignableScopes"`
+	Commands         []string `json:"commands"`
+	Script           string   `json:"script"`
+}
+
+// handleAnalyze resolves the permissions for a single Azure CLI command
+func (s *apiServer) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	var req analyzeRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	command := strings.TrimSpace(req.Command)
+	if !strings.HasPrefix(command, "az ") {
+		writeError(w, http.StatusBadRequest, "command must start with 'az'")
+		return
+	}
+
+	store, ok := s.store(w)
+	if !ok {
+		return
+	}
+
+	writeResponse(w, http.StatusOK, analyzeCommandLine(s.resolver, store, command))
+}
+
+// handleScan resolves the permissions for every Azure CLI command in a script
+func (s *apiServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	var req scanRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if strings.TrimSpace(req.Script) == "" {
+		writeError(w, http.StatusBadRequest, "script must not be empty")
+		return
+	}
+
+	store, ok := s.store(w)
+	if !ok {
+		return
+	}
+
+	writeResponse(w, http.StatusOK, analyzeScript(s.resolver, store, req.Source, req.Script))
+}
+
+// handleRole generates a custom role for a list of commands and/or a script
+func (s *apiServer) handleRole(w http.ResponseWriter, r *http.Request) {
+	var req roleRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if len(req.Commands) == 0 && strings.TrimSpace(req.Script) == "" {
+		writeError(w, http.StatusBadRequest, "either commands or script must be provided")
+		return
+	}
+
+	store, ok := s.store(w)
+	if !ok {
+		return
+	}
+
+	results := []models.AnalysisResult{}
+	for _, command := range req.Commands {
+		results = append(results, analyzeCommandLine(s.resolver, store, command))
+	}
+	if req.Script != "" {
+		results = append(results, analyzeScript(s.resolver, store, "", req.Script).Commands...)
+	}
+
+	role := permissions.BuildCustomRole(req.Name, req.Description, req.AssignableScopes, results)
+	writeResponse(w, http.StatusOK, models.RoleResult{Role: role, Commands: results})
+}
+
+// handleOperations searches the provider operations catalog
+func (s *apiServer) handleOperations(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a non-negative integer")
+			return
+		}
+		limit = parsed
+	}
+
+	store, err := s.catalog.Store()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	writeResponse(w, http.StatusOK, store.Find(r.URL.Query().Get("q"), limit))
+}
+
+// handleHealth reports whether the catalog has been loaded
+func (s *apiServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	updatedAt, lastErr := s.catalog.Status()
+
+	status := map[string]interface{}{
+		"status": "ok",
+	}
+	if !updatedAt.IsZero() {
+		status["catalogUpdatedAt"] = updatedAt.UTC().Format(time.RFC3339)
+	}
+	if lastErr != nil {
+		status["lastError"] = lastErr.Error()
+	}
+
+	code := http.StatusOK
+	if updatedAt.IsZero() {
+		status["status"] = "loading"
+		code = http.StatusServiceUnavailable
+	}
+
+	writeResponse(w, code, status)
+}
+
+// store returns the indexed shared catalog or writes an error response if it is unavailable
+func (s *apiServer) store(w http.ResponseWriter) (*catalog.Store, bool) {
+	store, err := s.catalog.Store()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return nil, false
+	}
+	return store, true
+}
+
+// decodeRequest decodes a JSON request body, writing an error response on failure
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// writeResponse writes a JSON response
+func writeResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := display.WriteJSON(w, v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeResponse(w, status, map[string]string{"error": message})
+}
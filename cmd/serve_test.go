@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// newTestAPI serves the API over a catalog loaded with the given fetch function
+func newTestAPI(t *testing.T, fetch catalog.FetchFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(newAPIServer(catalog.New(fetch), nil, nil).routes())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// realisticCatalog fetches the realistic test catalog
+func realisticCatalog() (map[string]models.ProviderOperationsResponse, error) {
+	return catalogtest.Realistic(), nil
+}
+
+// request sends a request to the test API and decodes the JSON response into out
+func request(t *testing.T, server *httptest.Server, method, path, body string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if got := resp.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("%s %s Content-Type = %q", method, path, got)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServeValidation(t *testing.T) {
+	server := newTestAPI(t, realisticCatalog)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		wantError string
+	}{
+		{"command without az", http.MethodPost, "/v1/analyze", `{"command": "vm start --name vm1"}`, "command must start with 'az'"},
+		{"empty command", http.MethodPost, "/v1/analyze", `{"command": "  "}`, "command must start with 'az'"},
+		{"unknown field", http.MethodPost, "/v1/analyze", `{"command": "az vm start", "cmd": "x"}`, `unknown field "cmd"`},
+		{"malformed body", http.MethodPost, "/v1/analyze", `{"command": `, "invalid request body"},
+		{"body too large", http.MethodPost, "/v1/scan", `{"script": "` + strings.Repeat("a", maxRequestBodySize) + `"}`, "request body too large"},
+		{"empty script", http.MethodPost, "/v1/scan", `{"source": "deploy.sh", "script": " \n "}`, "script must not be empty"},
+		{"role without commands or script", http.MethodPost, "/v1/role", `{"name": "Deployer", "commands": []}`, "either commands or script must be provided"},
+		{"invalid limit", http.MethodGet, "/v1/operations?q=read&limit=ten", "", "limit must be a non-negative integer"},
+		{"negative limit", http.MethodGet, "/v1/operations?q=read&limit=-1", "", "limit must be a non-negative integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response map[string]string
+			if status := request(t, server, tt.method, tt.path, tt.body, &response); status != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+			}
+			if !strings.Contains(response["error"], tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", response["error"], tt.wantError)
+			}
+		})
+	}
+
+	if status := request(t, server, http.MethodGet, "/v1/analyze", "", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET /v1/analyze status = %d, want %d", status, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServeEndpoints(t *testing.T) {
+	server := newTestAPI(t, realisticCatalog)
+
+	var analysis models.AnalysisResult
+	if status := request(t, server, http.MethodPost, "/v1/analyze", `{"command": "az vm create --name vm1 -g rg"}`, &analysis); status != http.StatusOK {
+		t.Fatalf("POST /v1/analyze status = %d", status)
+	}
+	if names := permissions.PermissionNames(analysis.Permissions); len(names) != 1 || names[0] != "Microsoft.Compute/virtualMachines/write" {
+		t.Errorf("POST /v1/analyze permissions = %v", names)
+	}
+
+	var scan models.ScanResult
+	script := `{"source": "deploy.sh", "script": "az group create -n rg -l westeurope\naz keyvault create -n kv -g rg\n"}`
+	if status := request(t, server, http.MethodPost, "/v1/scan", script, &scan); status != http.StatusOK {
+		t.Fatalf("POST /v1/scan status = %d", status)
+	}
+	if scan.Source != "deploy.sh" || len(scan.Commands) != 2 || scan.Commands[1].Line != 2 {
+		t.Errorf("POST /v1/scan = %+v", scan)
+	}
+
+	var role models.RoleResult
+	body := `{"name": "Deployer", "assignableScopes": ["/subscriptions/0000"], "commands": ["az vm create --name vm1 -g rg"], "script": "az keyvault create -n kv -g rg"}`
+	if status := request(t, server, http.MethodPost, "/v1/role", body, &role); status != http.StatusOK {
+		t.Fatalf("POST /v1/role status = %d", status)
+	}
+	if role.Role.Name != "Deployer" || len(role.Commands) != 2 || len(role.Role.Actions) != 2 {
+		t.Errorf("POST /v1/role = %+v", role)
+	}
+
+	var operations []models.OperationInfo
+	if status := request(t, server, http.MethodGet, "/v1/operations?q=virtualMachines/start&limit=1", "", &operations); status != http.StatusOK {
+		t.Fatalf("GET /v1/operations status = %d", status)
+	}
+	if len(operations) != 1 {
+		t.Errorf("GET /v1/operations returned %d operations, want 1", len(operations))
+	}
+}
+
+func TestServeHealth(t *testing.T) {
+	release := make(chan struct{})
+	server := newTestAPI(t, func() (map[string]models.ProviderOperationsResponse, error) {
+		<-release
+		return catalogtest.Realistic(), nil
+	})
+
+	// Requests wait for the catalog, while the health check reports that it is loading
+	done := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(server.URL+"/v1/analyze", "application/json", strings.NewReader(`{"command": "az vm start --name vm1 -g rg"}`))
+		if err != nil {
+			done <- 0
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+
+	var health map[string]string
+	if status := request(t, server, http.MethodGet, "/healthz", "", &health); status != http.StatusServiceUnavailable || health["status"] != "loading" {
+		t.Errorf("GET /healthz while loading = %d %v, want %d loading", status, health, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	if status := <-done; status != http.StatusOK {
+		t.Errorf("POST /v1/analyze status = %d, want %d", status, http.StatusOK)
+	}
+
+	health = nil
+	if status := request(t, server, http.MethodGet, "/healthz", "", &health); status != http.StatusOK || health["status"] != "ok" || health["catalogUpdatedAt"] == "" {
+		t.Errorf("GET /healthz = %d %v, want %d ok", status, health, http.StatusOK)
+	}
+}
+
+func TestServeCatalogUnavailable(t *testing.T) {
+	server := newTestAPI(t, func() (map[string]models.ProviderOperationsResponse, error) {
+		return nil, errors.New("no credentials")
+	})
+
+	var response map[string]string
+	if status := request(t, server, http.MethodPost, "/v1/analyze", `{"command": "az vm start"}`, &response); status != http.StatusServiceUnavailable {
+		t.Errorf("POST /v1/analyze status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(response["error"], "no credentials") {
+		t.Errorf("error = %q", response["error"])
+	}
+
+	var health map[string]string
+	if status := request(t, server, http.MethodGet, "/healthz", "", &health); status != http.StatusServiceUnavailable || !strings.Contains(health["lastError"], "no credentials") {
+		t.Errorf("GET /healthz = %d %v", status, health)
+	}
+}
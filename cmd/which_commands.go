@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/parser"
+)
+
+// RunWhichCommands lists the Azure CLI commands that require a permission
+func (c *CLI) RunWhichCommands(args []string) error {
+	fs := flag.NewFlagSet("which-commands", flag.ContinueOnError)
+	resolve := fs.Bool("resolve", false, "Also resolve the known az commands against the live provider catalog")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm which-commands [flags] <permission>")
+		fmt.Fprintln(fs.Output(), "Lists the az commands that require a permission, including the parameters that trigger it")
+		fmt.Fprintln(fs.Output(), "Example: azperm which-commands Microsoft.Network/virtualNetworks/subnets/join/action")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a single permission")
+	}
+	permission := fs.Arg(0)
+
+	c.permManager.LoadPermissions()
+	references := c.permManager.CommandsForPermission(permission)
+
+	if *resolve {
+		c.quiet = true
+		store, err := c.catalog.Store()
+		if err != nil {
+			return err
+		}
+
+		references = append(references, c.resolver.CommandsForPermission(permission, parser.KnownCommands, store)...)
+	}
+
+	if c.outputFormat == OutputJSON {
+		return display.WriteJSON(os.Stdout, references)
+	}
+
+	c.colors.DisplayCommandReferences(permission, references)
+	return nil
+}
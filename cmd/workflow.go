@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/display"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+	"github.com/mathwro/azperm/internal/scanner"
+)
+
+// defaultWorkflowDir is where GitHub Actions workflows live in a repository
+const defaultWorkflowDir = ".github/workflows"
+
+// RunWorkflow analyzes the Azure CLI commands in GitHub Actions workflow files
+func (c *CLI) RunWorkflow(args []string) error {
+	fs := flag.NewFlagSet("workflow", flag.ContinueOnError)
+	roles := fs.Bool("roles", false, "Print a least-privilege custom role per job instead of the analysis")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: azperm workflow [flags] [workflow-file | directory]...")
+		fmt.Fprintf(fs.Output(), "Analyzes the az commands in GitHub Actions workflows (default: %s)\n", defaultWorkflowDir)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{defaultWorkflowDir}
+	}
+	files, err := expandYAMLPaths(paths)
+	if err != nil {
+		return err
+	}
+
+	c.quiet = true
+	store, err := c.catalog.Store()
+	if err != nil {
+		return err
+	}
+
+	var results []models.WorkflowResult
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read workflow: %w", err)
+		}
+		workflow, err := scanner.ScanGitHubWorkflow(content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		results = append(results, analyzeWorkflow(c.resolver, store, file, workflow))
+	}
+
+	var violations []models.PolicyViolation
+	for _, result := range results {
+		for _, job := range result.Jobs {
+			violations = append(violations, c.evaluatePolicy(result.Source, job.Commands)...)
+		}
+	}
+
+	switch {
+	case *roles:
+		err = display.WriteJSON(os.Stdout, workflowRoles(results))
+	case c.outputFormat == OutputJSON:
+		err = display.WriteJSON(os.Stdout, results)
+	case isReportFormat(c.outputFormat):
+		err = c.writeReport(workflowReport(results, violations))
+	default:
+		for _, result := range results {
+			c.colors.DisplayWorkflowResult(result)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.enforcePolicy(violations)
+}
+
+// workflowReport combines the commands of every job of the workflows into one report, each
+// command attributed to its workflow file
+func workflowReport(results []models.WorkflowResult, violations []models.PolicyViolation) models.ScanResult {
+	var source string
+	if len(results) == 1 {
+		source = results[0].Source
+	}
+
+	commands := []models.AnalysisResult{}
+	for _, result := range results {
+		for _, job := range result.Jobs {
+			for _, command := range job.Commands {
+				command.File = result.Source
+				commands = append(commands, command)
+			}
+		}
+	}
+	return scanReport(source, commands, violations)
+}
+
+// expandYAMLPaths returns the given files and the .yml/.yaml files in the given directories
+func expandYAMLPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		for _, pattern := range []string{"*.yml", "*.yaml"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no YAML files found in %v", paths)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// analyzeWorkflow resolves the permissions of every Azure CLI command in the jobs of a workflow
+func analyzeWorkflow(resolver *permissions.Resolver, store *catalog.Store, source string, workflow scanner.Workflow) models.WorkflowResult {
+	result := models.WorkflowResult{
+		Source: source,
+		Name:   workflow.Name,
+		Jobs:   []models.JobResult{},
+	}
+
+	var all []models.AnalysisResult
+	for _, job := range workflow.Jobs {
+		jobResult := models.JobResult{
+			ID:       job.ID,
+			Name:     job.Name,
+			Identity: job.Identity,
+			Commands: []models.AnalysisResult{},
+		}
+		for _, invocation := range job.Invocations {
+			analysis := analyzeCommandLine(resolver, store, invocation.Command)
+			analysis.Line = invocation.Line
+			analysis.Column = invocation.Column
+			jobResult.Commands = append(jobResult.Commands, analysis)
+		}
+		jobResult.Actions, jobResult.DataActions = permissions.SplitActions(jobResult.Commands)
+
+		all = append(all, jobResult.Commands...)
+		result.Jobs = append(result.Jobs, jobResult)
+	}
+
+	result.Actions, result.DataActions = permissions.SplitActions(all)
+	return result
+}
+
+// workflowRoles builds a least-privilege custom role for every job that runs Azure CLI commands
+func workflowRoles(results []models.WorkflowResult) []models.RoleDefinition {
+	roles := []models.RoleDefinition{}
+	for _, result := range results {
+		workflowName := result.Name
+		if workflowName == "" {
+			workflowName = filepath.Base(result.Source)
+		}
+
+		for _, job := range result.Jobs {
+			if len(job.Actions) == 0 && len(job.DataActions) == 0 {
+				continue
+			}
+
+			description := fmt.Sprintf("Generated by azperm for job '%s' of %s", job.ID, result.Source)
+			if job.Identity != "" {
+				description += " (identity " + job.Identity + ")"
+			}
+			roles = append(roles, permissions.NewCustomRole(workflowName+" / "+job.ID, description, nil, job.Actions, job.DataActions))
+		}
+	}
+	return roles
+}
@@ -0,0 +1,210 @@
+package activity
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// Event is the subset of an Activity Log event needed to derive permissions
+type Event struct {
+	Caller string
+	Action string
+	Scope  string
+	Status string
+}
+
+// jsonEvent is an event as exported by 'az monitor activity-log list --output json'
+type jsonEvent struct {
+	Caller        string `json:"caller"`
+	ResourceID    string `json:"resourceId"`
+	Authorization *struct {
+		Action string `json:"action"`
+		Scope  string `json:"scope"`
+	} `json:"authorization"`
+	OperationName struct {
+		Value string `json:"value"`
+	} `json:"operationName"`
+	Status struct {
+		Value string `json:"value"`
+	} `json:"status"`
+}
+
+// csvColumns maps the logical event fields to the column headers accepted in CSV exports,
+// covering flattened az output as well as Azure portal exports
+var csvColumns = map[string][]string{
+	"caller": {"caller", "event initiated by"},
+	"action": {"authorization.action", "action", "operationname.value", "operation name value", "operation name"},
+	"scope":  {"authorization.scope", "scope", "resourceid", "resource id", "resource"},
+	"status": {"status.value", "status"},
+}
+
+// LoadEvents reads an Activity Log export in JSON or CSV format
+func LoadEvents(path string) ([]Event, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity log: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if strings.EqualFold(filepath.Ext(path), ".csv") || (len(trimmed) > 0 && trimmed[0] != '[' && trimmed[0] != '{') {
+		return ParseCSV(bytes.NewReader(content))
+	}
+	return ParseJSON(bytes.NewReader(content))
+}
+
+// ParseJSON parses an Activity Log export produced by 'az monitor activity-log list'
+func ParseJSON(r io.Reader) ([]Event, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity log: %w", err)
+	}
+
+	var raw []jsonEvent
+	if err := json.Unmarshal(content, &raw); err != nil {
+		// The REST API wraps events in {"value": [...]}
+		var list struct {
+			Value []jsonEvent `json:"value"`
+		}
+		if listErr := json.Unmarshal(content, &list); listErr != nil {
+			return nil, fmt.Errorf("failed to parse activity log JSON: %w", err)
+		}
+		raw = list.Value
+	}
+
+	events := make([]Event, 0, len(raw))
+	for _, item := range raw {
+		event := Event{
+			Caller: item.Caller,
+			Action: item.OperationName.Value,
+			Scope:  item.ResourceID,
+			Status: item.Status.Value,
+		}
+		if item.Authorization != nil {
+			if item.Authorization.Action != "" {
+				event.Action = item.Authorization.Action
+			}
+			if item.Authorization.Scope != "" {
+				event.Scope = item.Authorization.Scope
+			}
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// ParseCSV parses an Activity Log export in CSV format with a header row
+func ParseCSV(r io.Reader) ([]Event, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity log CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for field, names := range csvColumns {
+		for _, name := range names {
+			for i, column := range header {
+				if _, found := columns[field]; !found && strings.EqualFold(strings.TrimSpace(column), name) {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	if _, found := columns["action"]; !found {
+		return nil, fmt.Errorf("activity log CSV has no action column (expected one of: %s)", strings.Join(csvColumns["action"], ", "))
+	}
+
+	var events []Event
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read activity log CSV: %w", err)
+		}
+
+		value := func(field string) string {
+			if i, found := columns[field]; found && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		events = append(events, Event{
+			Caller: value("caller"),
+			Action: value("action"),
+			Scope:  value("scope"),
+			Status: value("status"),
+		})
+	}
+
+	return events, nil
+}
+
+// Summarize collects the distinct actions performed by the caller, with the scopes they were
+// performed at and how often they succeeded or failed. An empty caller includes all events.
+// It returns the observed actions and the number of events that matched the caller.
+func Summarize(events []Event, caller string, includeFailed bool) ([]models.ObservedAction, int) {
+	byAction := make(map[string]*models.ObservedAction)
+	scopes := make(map[string]map[string]bool)
+	matched := 0
+
+	for _, event := range events {
+		if caller != "" && !strings.EqualFold(event.Caller, caller) {
+			continue
+		}
+		if event.Action == "" {
+			continue
+		}
+
+		failed := strings.EqualFold(event.Status, "Failed")
+		succeeded := strings.EqualFold(event.Status, "Succeeded")
+		if failed && !includeFailed {
+			continue
+		}
+		matched++
+
+		key := strings.ToLower(event.Action)
+		observed, exists := byAction[key]
+		if !exists {
+			observed = &models.ObservedAction{Action: event.Action}
+			byAction[key] = observed
+			scopes[key] = make(map[string]bool)
+		}
+
+		if succeeded {
+			observed.Succeeded++
+		}
+		if failed {
+			observed.Failed++
+		}
+		if event.Scope != "" && !scopes[key][strings.ToLower(event.Scope)] {
+			scopes[key][strings.ToLower(event.Scope)] = true
+			observed.Scopes = append(observed.Scopes, event.Scope)
+		}
+	}
+
+	actions := make([]models.ObservedAction, 0, len(byAction))
+	for _, observed := range byAction {
+		sort.Strings(observed.Scopes)
+		actions = append(actions, *observed)
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return strings.ToLower(actions[i].Action) < strings.ToLower(actions[j].Action)
+	})
+
+	return actions, matched
+}
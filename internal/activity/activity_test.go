@@ -0,0 +1,232 @@
+package activity
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+const (
+	vm1 = "/subscriptions/0000/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1"
+	vm2 = "/subscriptions/0000/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm2"
+)
+
+// jsonExport is an abridged 'az monitor activity-log list --output json' export. Each
+// operation is logged when it starts and when it ends.
+const jsonExport = `[
+  {
+    "caller": "dev@contoso.com",
+    "authorization": {"action": "Microsoft.Compute/virtualMachines/start/action", "scope": "` + vm1 + `"},
+    "operationName": {"value": "Microsoft.Compute/virtualMachines/start/action", "localizedValue": "Start Virtual Machine"},
+    "resourceId": "` + vm1 + `",
+    "status": {"value": "Started", "localizedValue": "Started"}
+  },
+  {
+    "caller": "dev@contoso.com",
+    "authorization": {"action": "Microsoft.Compute/virtualMachines/start/action", "scope": "` + vm1 + `"},
+    "operationName": {"value": "Microsoft.Compute/virtualMachines/start/action"},
+    "resourceId": "` + vm1 + `",
+    "status": {"value": "Succeeded"}
+  },
+  {
+    "caller": "DEV@contoso.com",
+    "authorization": {"action": "microsoft.compute/virtualmachines/start/action", "scope": "/subscriptions/0000/resourcegroups/rg/providers/microsoft.compute/virtualmachines/vm1"},
+    "operationName": {"value": "Microsoft.Compute/virtualMachines/start/action"},
+    "resourceId": "` + vm1 + `",
+    "status": {"value": "Succeeded"}
+  },
+  {
+    "caller": "dev@contoso.com",
+    "authorization": null,
+    "operationName": {"value": "Microsoft.Compute/virtualMachines/write"},
+    "resourceId": "` + vm2 + `",
+    "status": {"value": "Succeeded"}
+  },
+  {
+    "caller": "dev@contoso.com",
+    "authorization": {"action": "Microsoft.Compute/virtualMachines/delete", "scope": "` + vm2 + `"},
+    "operationName": {"value": "Microsoft.Compute/virtualMachines/delete"},
+    "resourceId": "` + vm2 + `",
+    "status": {"value": "Failed"}
+  },
+  {
+    "caller": "pipeline-sp",
+    "authorization": {"action": "Microsoft.Compute/virtualMachines/restart/action", "scope": "` + vm1 + `"},
+    "operationName": {"value": "Microsoft.Compute/virtualMachines/restart/action"},
+    "resourceId": "` + vm1 + `",
+    "status": {"value": "Succeeded"}
+  }
+]`
+
+// csvExport is an Azure portal export with the columns in a different order
+const csvExport = `Operation name,Status,Time,Event initiated by,Resource
+Microsoft.Compute/virtualMachines/start/action,Succeeded,2024-05-01T10:00:00Z,dev@contoso.com,` + vm1 + `
+Microsoft.Compute/virtualMachines/start/action,Succeeded,2024-05-01T11:00:00Z,dev@contoso.com,` + vm2 + `
+Microsoft.Compute/virtualMachines/delete,Failed,2024-05-01T12:00:00Z,dev@contoso.com,` + vm2 + `
+Microsoft.Compute/virtualMachines/restart/action,Succeeded,2024-05-01T13:00:00Z,pipeline-sp,` + vm1 + `
+`
+
+func TestParseJSON(t *testing.T) {
+	events, err := ParseJSON(strings.NewReader(jsonExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("ParseJSON() returned %d events, want 6", len(events))
+	}
+	// Events without an authorization block fall back to the operation name and resource
+	want := Event{Caller: "dev@contoso.com", Action: "Microsoft.Compute/virtualMachines/write", Scope: vm2, Status: "Succeeded"}
+	if events[3] != want {
+		t.Errorf("event 3 = %+v, want %+v", events[3], want)
+	}
+
+	wrapped, err := ParseJSON(strings.NewReader(`{"value": ` + jsonExport + `}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(wrapped, events) {
+		t.Error("ParseJSON() parsed a REST API export differently")
+	}
+
+	if _, err := ParseJSON(strings.NewReader(`[{"caller": `)); err == nil {
+		t.Error("ParseJSON() accepted malformed JSON")
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	events, err := ParseCSV(strings.NewReader(csvExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Event{Caller: "dev@contoso.com", Action: "Microsoft.Compute/virtualMachines/delete", Scope: vm2, Status: "Failed"}
+	if len(events) != 4 || events[2] != want {
+		t.Errorf("ParseCSV() = %+v, want 4 events with %+v third", events, want)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "no action column", content: "Caller,Status,Resource\ndev,Succeeded,/subscriptions/0000\n", wantErr: "no action column"},
+		{name: "empty", content: "", wantErr: "header"},
+		{name: "unterminated quote", content: "action,caller\n\"Microsoft.Compute/virtualMachines/read,dev\n", wantErr: "failed to read activity log CSV"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCSV(strings.NewReader(tt.content)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCSV() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadEvents(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"activity.json", jsonExport},
+		{"activity.csv", csvExport},
+		{"activity.txt", csvExport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			events, err := LoadEvents(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) == 0 {
+				t.Error("LoadEvents() returned no events")
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	events, err := ParseJSON(strings.NewReader(jsonExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvEvents, err := ParseCSV(strings.NewReader(csvExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := models.ObservedAction{Action: "Microsoft.Compute/virtualMachines/start/action", Scopes: []string{vm1}, Succeeded: 2}
+	write := models.ObservedAction{Action: "Microsoft.Compute/virtualMachines/write", Scopes: []string{vm2}, Succeeded: 1}
+	restart := models.ObservedAction{Action: "Microsoft.Compute/virtualMachines/restart/action", Scopes: []string{vm1}, Succeeded: 1}
+
+	tests := []struct {
+		name          string
+		events        []Event
+		caller        string
+		includeFailed bool
+		want          []models.ObservedAction
+		wantMatched   int
+	}{
+		{
+			name:        "caller, deduplicated ignoring case",
+			events:      events,
+			caller:      "dev@contoso.com",
+			want:        []models.ObservedAction{start, write},
+			wantMatched: 4,
+		},
+		{
+			name:          "including failed events",
+			events:        events,
+			caller:        "dev@contoso.com",
+			includeFailed: true,
+			want: []models.ObservedAction{
+				{Action: "Microsoft.Compute/virtualMachines/delete", Scopes: []string{vm2}, Failed: 1},
+				start,
+				write,
+			},
+			wantMatched: 5,
+		},
+		{
+			name:        "all callers",
+			events:      events,
+			want:        []models.ObservedAction{restart, start, write},
+			wantMatched: 5,
+		},
+		{
+			name:   "scopes of an action",
+			events: csvEvents,
+			caller: "dev@contoso.com",
+			want: []models.ObservedAction{
+				{Action: "Microsoft.Compute/virtualMachines/start/action", Scopes: []string{vm1, vm2}, Succeeded: 2},
+			},
+			wantMatched: 2,
+		},
+		{
+			name:        "unknown caller",
+			events:      events,
+			caller:      "someone-else",
+			want:        []models.ObservedAction{},
+			wantMatched: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched := Summarize(tt.events, tt.caller, tt.includeFailed)
+			if matched != tt.wantMatched {
+				t.Errorf("Summarize() matched %d events, want %d", matched, tt.wantMatched)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
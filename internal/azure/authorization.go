@@ -0,0 +1,80 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// authorizationAPIVersion is the Microsoft.Authorization API version used for permission and role queries
+const authorizationAPIVersion = "2022-04-01"
+
+// FetchPermissions retrieves the effective permissions of the signed-in principal at a scope
+func (c *Client) FetchPermissions(ctx context.Context, accessToken, scope string) ([]models.Permission, error) {
+	var grants []models.Permission
+
+	path := strings.TrimSuffix(scope, "/") + "/providers/Microsoft.Authorization/permissions"
+	if err := c.getManagementList(ctx, accessToken, path, url.Values{}, &grants); err != nil {
+		return nil, fmt.Errorf("failed to fetch permissions for scope %s: %w", scope, err)
+	}
+
+	return grants, nil
+}
+
+// FetchRoleDefinitions retrieves the role definitions available at a scope.
+// When builtInOnly is set, custom roles are excluded.
+func (c *Client) FetchRoleDefinitions(ctx context.Context, accessToken, scope string, builtInOnly bool) ([]models.RoleDefinition, error) {
+	var definitions []struct {
+		ID         string `json:"id"`
+		Properties struct {
+			RoleName         string              `json:"roleName"`
+			Description      string              `json:"description"`
+			Type             string              `json:"type"`
+			Permissions      []models.Permission `json:"permissions"`
+			AssignableScopes []string            `json:"assignableScopes"`
+		} `json:"properties"`
+	}
+
+	query := url.Values{}
+	if builtInOnly {
+		query.Set("$filter", "type eq 'BuiltInRole'")
+	}
+
+	path := strings.TrimSuffix(scope, "/") + "/providers/Microsoft.Authorization/roleDefinitions"
+	if err := c.getManagementList(ctx, accessToken, path, query, &definitions); err != nil {
+		return nil, fmt.Errorf("failed to fetch role definitions: %w", err)
+	}
+
+	roles := make([]models.RoleDefinition, 0, len(definitions))
+	for _, value := range definitions {
+		role := models.RoleDefinition{
+			ID:               value.ID,
+			Name:             value.Properties.RoleName,
+			IsCustom:         value.Properties.Type == "CustomRole",
+			Description:      value.Properties.Description,
+			AssignableScopes: value.Properties.AssignableScopes,
+		}
+		role.SetPermissions(value.Properties.Permissions)
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+// getManagementList performs an authenticated GET request against the management endpoint and decodes
+// the items of every page of the list response into out
+func (c *Client) getManagementList(ctx context.Context, accessToken, path string, query url.Values, out interface{}) error {
+	endpoint, err := c.GetEffectiveEndpoint()
+	if err != nil {
+		return err
+	}
+
+	if query.Get("api-version") == "" {
+		query.Set("api-version", authorizationAPIVersion)
+	}
+
+	return c.getList(ctx, accessToken, endpoint+path+"?"+query.Encode(), out)
+}
@@ -1,13 +1,15 @@
 package azure
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/mathwro/azperm/internal/models"
 )
@@ -22,8 +24,19 @@ type AzureCloudConfig struct {
 
 // Client represents an Azure API client
 type Client struct {
-	httpClient *http.Client
-	apiVersion string
+	httpClient         *http.Client
+	retry              RetryPolicy
+	proxy              string
+	network            NetworkInfo
+	userAgent          string
+	apiVersion         string
+	managementEndpoint string
+	cloudName          string
+	cloudProfiles      map[string]CloudProfile
+	cloudOnce          sync.Once
+	cloud              ResolvedCloud
+	// detectCloud reads the cloud Azure CLI is configured for
+	detectCloud func() (CloudProfile, error)
 }
 
 // NewClient creates a new Azure API client
@@ -37,8 +50,12 @@ func NewClient() *Client {
 	}
 	
 	return &Client{
-		httpClient: &http.Client{},
-		apiVersion: defaultAPIVersion,
+		httpClient:    &http.Client{Timeout: DefaultTimeout},
+		retry:         DefaultRetryPolicy,
+		userAgent:     "azperm",
+		apiVersion:    defaultAPIVersion,
+		cloudProfiles: make(map[string]CloudProfile),
+		detectCloud:   azureCLICloud,
 	}
 }
 
@@ -49,6 +66,11 @@ func (c *Client) SetAPIVersion(version string) {
 	}
 }
 
+// SetManagementEndpoint overrides the Resource Manager endpoint of the cloud in use
+func (c *Client) SetManagementEndpoint(endpoint string) {
+	c.managementEndpoint = strings.TrimSuffix(endpoint, "/")
+}
+
 // GetAPIVersion returns the current API version being used
 func (c *Client) GetAPIVersion() string {
 	return c.apiVersion
@@ -56,24 +78,26 @@ func (c *Client) GetAPIVersion() string {
 
 // GetCloudConfig returns the current Azure cloud configuration
 func (c *Client) GetCloudConfig() (*AzureCloudConfig, error) {
-	return c.getAzureCloudConfig()
+	cloud := c.Cloud()
+	return &AzureCloudConfig{
+		Name:                    cloud.Name,
+		ManagementEndpointURL:   cloud.ResourceManagerEndpoint,
+		ResourceManagerEndpoint: cloud.ResourceManagerEndpoint,
+		ActiveDirectoryEndpoint: cloud.ActiveDirectoryAuthority,
+	}, nil
 }
 
 // GetEffectiveEndpoint returns the actual management endpoint being used (including environment overrides)
 func (c *Client) GetEffectiveEndpoint() (string, error) {
-	// Check for environment variable override first
+	// Check for an explicit or environment variable override first
+	if c.managementEndpoint != "" {
+		return c.managementEndpoint, nil
+	}
 	if envEndpoint := os.Getenv("AZPERM_MANAGEMENT_ENDPOINT"); envEndpoint != "" {
 		return strings.TrimSuffix(envEndpoint, "/"), nil
 	}
-	
-	// Otherwise get from Azure CLI configuration
-	cloudConfig, err := c.getAzureCloudConfig()
-	if err != nil {
-		// Fallback to public cloud
-		return "https://management.azure.com", nil
-	}
-	
-	return cloudConfig.ManagementEndpointURL, nil
+
+	return c.Cloud().ResourceManagerEndpoint, nil
 }
 
 // GetEffectiveCloudInfo returns comprehensive information about the effective configuration
@@ -82,145 +106,83 @@ func (c *Client) GetEffectiveCloudInfo() (string, string, string, error) {
 	if err != nil {
 		return "", "", "", err
 	}
-	
-	cloudName := "Unknown"
-	isOverridden := false
-	
+
 	// Check if endpoint is overridden
-	if os.Getenv("AZPERM_MANAGEMENT_ENDPOINT") != "" {
-		cloudName = "Custom (Environment Override)"
-		isOverridden = true
-	} else {
-		// Get cloud name from Azure CLI
-		if cloudConfig, err := c.getAzureCloudConfig(); err == nil {
-			cloudName = cloudConfig.Name
-		}
+	if c.managementEndpoint != "" || os.Getenv("AZPERM_MANAGEMENT_ENDPOINT") != "" {
+		return "Custom (Environment Override)", effectiveEndpoint, CloudSourceEnvironment, nil
 	}
-	
-	source := "Azure CLI"
-	if isOverridden {
-		source = "Environment Variable"
-	}
-	
-	return cloudName, effectiveEndpoint, source, nil
+
+	cloud := c.Cloud()
+	return cloud.Name, effectiveEndpoint, cloud.Source, nil
 }
 
 // FetchProviderOperations retrieves provider operations data from Azure API
 func (c *Client) FetchProviderOperations(useLive bool) (map[string]models.ProviderOperationsResponse, error) {
 	// Always use live API as requested by user
-	return c.FetchRealProviderOperations("")
-}
-
-// getAzureCloudConfig gets the current Azure cloud configuration from Azure CLI
-func (c *Client) getAzureCloudConfig() (*AzureCloudConfig, error) {
-	// Get current cloud configuration from Azure CLI
-	cmd := exec.Command("az", "cloud", "show", "--output", "json")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get Azure cloud configuration from Azure CLI: %w", err)
-	}
-
-	var cloudConfig struct {
-		Name      string `json:"name"`
-		Endpoints struct {
-			Management        string `json:"management"`
-			ResourceManager   string `json:"resourceManager"`
-			ActiveDirectory   string `json:"activeDirectory"`
-		} `json:"endpoints"`
-	}
-
-	if err := json.Unmarshal(output, &cloudConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse Azure cloud configuration: %w", err)
-	}
-
-	// Use Resource Manager endpoint for ARM APIs (Provider Operations API)
-	// The management endpoint is for classic/legacy operations
-	managementURL := cloudConfig.Endpoints.ResourceManager
-	if managementURL == "" {
-		// Fallback to management endpoint if Resource Manager is not available
-		managementURL = cloudConfig.Endpoints.Management
-	}
-
-	// Remove trailing slash if present
-	managementURL = strings.TrimSuffix(managementURL, "/")
-
-	return &AzureCloudConfig{
-		Name:                    cloudConfig.Name,
-		ManagementEndpointURL:   managementURL,
-		ResourceManagerEndpoint: cloudConfig.Endpoints.ResourceManager,
-		ActiveDirectoryEndpoint: cloudConfig.Endpoints.ActiveDirectory,
-	}, nil
+	return c.FetchRealProviderOperations(context.Background(), "")
 }
 
 // buildProviderOperationsURL constructs the provider operations URL for the current cloud
 func (c *Client) buildProviderOperationsURL() (string, error) {
-	// Allow management endpoint to be overridden via environment variable
-	if envEndpoint := os.Getenv("AZPERM_MANAGEMENT_ENDPOINT"); envEndpoint != "" {
-		envEndpoint = strings.TrimSuffix(envEndpoint, "/")
-		return fmt.Sprintf("%s/providers/Microsoft.Authorization/providerOperations?api-version=%s&$expand=resourceTypes", 
-			envEndpoint, c.apiVersion), nil
-	}
-	
-	cloudConfig, err := c.getAzureCloudConfig()
+	endpoint, err := c.GetEffectiveEndpoint()
 	if err != nil {
-		// Fallback to public cloud if we can't detect the environment
-		return fmt.Sprintf("https://management.azure.com/providers/Microsoft.Authorization/providerOperations?api-version=%s&$expand=resourceTypes", c.apiVersion), nil
+		return "", err
 	}
 
-	return fmt.Sprintf("%s/providers/Microsoft.Authorization/providerOperations?api-version=%s&$expand=resourceTypes", 
-		cloudConfig.ManagementEndpointURL, c.apiVersion), nil
+	return fmt.Sprintf("%s/providers/Microsoft.Authorization/providerOperations?api-version=%s&$expand=resourceTypes",
+		endpoint, c.apiVersion), nil
 }
 
-// FetchRealProviderOperations fetches real data from Azure Management API
-func (c *Client) FetchRealProviderOperations(accessToken string) (map[string]models.ProviderOperationsResponse, error) {
-	// Build URL dynamically based on current Azure cloud configuration
-	url, err := c.buildProviderOperationsURL()
+// FetchProviderNamespace fetches the operations of a single resource provider namespace
+func (c *Client) FetchProviderNamespace(ctx context.Context, accessToken, namespace string) (models.ProviderOperationsResponse, error) {
+	endpoint, err := c.GetEffectiveEndpoint()
 	if err != nil {
-		return nil, fmt.Errorf("failed to build provider operations URL: %w", err)
+		return models.ProviderOperationsResponse{}, fmt.Errorf("failed to build provider operations URL: %w", err)
 	}
-	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	requestURL := fmt.Sprintf("%s/providers/Microsoft.Authorization/providerOperations/%s?api-version=%s&$expand=resourceTypes",
+		endpoint, url.PathEscape(namespace), c.apiVersion)
+	body, err := c.get(ctx, accessToken, requestURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return models.ProviderOperationsResponse{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	var provider models.ProviderOperationsResponse
+	if err := json.Unmarshal(body, &provider); err != nil {
+		return models.ProviderOperationsResponse{}, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	return provider, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// FetchRealProviderOperations fetches real data from Azure Management API, following every page.
+// Providers are decoded one at a time as the response is read.
+func (c *Client) FetchRealProviderOperations(ctx context.Context, accessToken string) (map[string]models.ProviderOperationsResponse, error) {
+	// Build URL dynamically based on current Azure cloud configuration
+	url, err := c.buildProviderOperationsURL()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	var apiResponse struct {
-		Value []models.ProviderOperationsResponse `json:"value"`
-	}
-
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to build provider operations URL: %w", err)
 	}
 
-	// Convert to map for easier lookup
+	// A provider decoded again when a page is retried replaces its earlier copy
 	result := make(map[string]models.ProviderOperationsResponse)
-	for _, provider := range apiResponse.Value {
-		// Extract namespace from the full ID (e.g., "Microsoft.Resources" from "Microsoft.Authorization/providerOperations/Microsoft.Resources")
-		namespace := provider.Namespace
-		if strings.Contains(namespace, "/") {
-			parts := strings.Split(namespace, "/")
-			namespace = parts[len(parts)-1]
-		}
-		result[namespace] = provider
+	err = c.getPages(ctx, accessToken, url, func(body io.Reader) (string, error) {
+		return decodeListPage(body, func(decoder *json.Decoder) error {
+			var provider models.ProviderOperationsResponse
+			if err := decoder.Decode(&provider); err != nil {
+				return err
+			}
+			// Extract namespace from the full ID (e.g., "Microsoft.Resources" from "Microsoft.Authorization/providerOperations/Microsoft.Resources")
+			namespace := provider.Namespace
+			if strings.Contains(namespace, "/") {
+				parts := strings.Split(namespace, "/")
+				namespace = parts[len(parts)-1]
+			}
+			result[namespace] = provider
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return result, nil
@@ -0,0 +1,219 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultCloud is the name of the Azure public cloud
+const DefaultCloud = "AzureCloud"
+
+// Sources of the cloud configuration in effect
+const (
+	CloudSourceProfile     = "Profile"
+	CloudSourceAzureCLI    = "Azure CLI"
+	CloudSourceDefault     = "Default"
+	CloudSourceEnvironment = "Environment Variable"
+)
+
+// CloudProfile holds the endpoints of an Azure cloud
+type CloudProfile struct {
+	Name                     string `json:"name" yaml:"name"`
+	ResourceManagerEndpoint  string `json:"resourceManager" yaml:"resourceManager"`
+	ActiveDirectoryAuthority string `json:"activeDirectory" yaml:"activeDirectory"`
+	Audience                 string `json:"audience" yaml:"audience"`
+}
+
+// ResolvedCloud is the cloud configuration in effect and where it came from
+type ResolvedCloud struct {
+	CloudProfile
+	Source string `json:"source"`
+}
+
+// builtInClouds are the national clouds known to Azure CLI
+var builtInClouds = []CloudProfile{
+	{
+		Name:                     "AzureCloud",
+		ResourceManagerEndpoint:  "https://management.azure.com",
+		ActiveDirectoryAuthority: "https://login.microsoftonline.com",
+		Audience:                 "https://management.core.windows.net/",
+	},
+	{
+		Name:                     "AzureUSGovernment",
+		ResourceManagerEndpoint:  "https://management.usgovcloudapi.net",
+		ActiveDirectoryAuthority: "https://login.microsoftonline.us",
+		Audience:                 "https://management.core.usgovcloudapi.net/",
+	},
+	{
+		Name:                     "AzureChinaCloud",
+		ResourceManagerEndpoint:  "https://management.chinacloudapi.cn",
+		ActiveDirectoryAuthority: "https://login.chinacloudapi.cn",
+		Audience:                 "https://management.core.chinacloudapi.cn/",
+	},
+}
+
+// BuiltInClouds returns the built-in cloud profiles
+func BuiltInClouds() []CloudProfile {
+	return append([]CloudProfile{}, builtInClouds...)
+}
+
+// DefaultCloudProfilesPath returns the file user-defined cloud profiles are loaded from
+func DefaultCloudProfilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "azperm", "clouds.yaml"), nil
+}
+
+// LoadCloudProfiles reads user-defined cloud profiles from a YAML file with a top-level
+// "clouds" list
+func LoadCloudProfiles(path string) ([]CloudProfile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloud profiles: %w", err)
+	}
+
+	var file struct {
+		Clouds []CloudProfile `yaml:"clouds"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cloud profiles %s: %w", path, err)
+	}
+	return file.Clouds, nil
+}
+
+// AddCloudProfiles registers user-defined cloud profiles. A profile with the name of a
+// built-in cloud replaces it.
+func (c *Client) AddCloudProfiles(profiles ...CloudProfile) error {
+	for _, profile := range profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("cloud profile without a name")
+		}
+		if profile.ResourceManagerEndpoint == "" {
+			return fmt.Errorf("cloud profile %s: resourceManager endpoint is required", profile.Name)
+		}
+		profile.ResourceManagerEndpoint = strings.TrimSuffix(profile.ResourceManagerEndpoint, "/")
+		if profile.Audience == "" {
+			profile.Audience = profile.ResourceManagerEndpoint + "/"
+		}
+		c.cloudProfiles[strings.ToLower(profile.Name)] = profile
+	}
+	return nil
+}
+
+// Clouds returns the built-in and user-defined cloud profiles sorted by name
+func (c *Client) Clouds() []CloudProfile {
+	profiles := make(map[string]CloudProfile)
+	for _, profile := range builtInClouds {
+		profiles[strings.ToLower(profile.Name)] = profile
+	}
+	for key, profile := range c.cloudProfiles {
+		profiles[key] = profile
+	}
+
+	clouds := make([]CloudProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		clouds = append(clouds, profile)
+	}
+	sort.Slice(clouds, func(i, j int) bool { return clouds[i].Name < clouds[j].Name })
+	return clouds
+}
+
+// SetCloud selects a cloud profile by name instead of detecting the cloud from Azure CLI.
+// It must be called before the client sends any request.
+func (c *Client) SetCloud(name string) error {
+	if _, ok := c.lookupCloud(name); !ok {
+		var names []string
+		for _, profile := range c.Clouds() {
+			names = append(names, profile.Name)
+		}
+		return fmt.Errorf("unknown cloud: %s (available: %s)", name, strings.Join(names, ", "))
+	}
+	c.cloudName = name
+	return nil
+}
+
+// lookupCloud finds a user-defined or built-in cloud profile by name, ignoring case
+func (c *Client) lookupCloud(name string) (CloudProfile, bool) {
+	if profile, ok := c.cloudProfiles[strings.ToLower(name)]; ok {
+		return profile, true
+	}
+	for _, profile := range builtInClouds {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, true
+		}
+	}
+	return CloudProfile{}, false
+}
+
+// Cloud returns the cloud configuration in effect: the selected profile, or the cloud
+// Azure CLI is configured for, or the public cloud. It is resolved once per client.
+func (c *Client) Cloud() ResolvedCloud {
+	c.cloudOnce.Do(func() {
+		c.cloud = c.resolveCloud()
+	})
+	return c.cloud
+}
+
+// resolveCloud determines the cloud configuration in effect
+func (c *Client) resolveCloud() ResolvedCloud {
+	if c.cloudName != "" {
+		profile, _ := c.lookupCloud(c.cloudName)
+		return ResolvedCloud{CloudProfile: profile, Source: CloudSourceProfile}
+	}
+
+	if profile, err := c.detectCloud(); err == nil {
+		if known, ok := c.lookupCloud(profile.Name); ok && strings.EqualFold(known.ResourceManagerEndpoint, profile.ResourceManagerEndpoint) {
+			profile = known
+		}
+		return ResolvedCloud{CloudProfile: profile, Source: CloudSourceAzureCLI}
+	}
+
+	profile, _ := c.lookupCloud(DefaultCloud)
+	return ResolvedCloud{CloudProfile: profile, Source: CloudSourceDefault}
+}
+
+// azureCLICloud reads the active cloud from Azure CLI
+func azureCLICloud() (CloudProfile, error) {
+	output, err := exec.Command("az", "cloud", "show", "--output", "json").Output()
+	if err != nil {
+		return CloudProfile{}, fmt.Errorf("failed to get Azure cloud configuration from Azure CLI: %w", err)
+	}
+
+	var cloudConfig struct {
+		Name      string `json:"name"`
+		Endpoints struct {
+			Management                string `json:"management"`
+			ResourceManager           string `json:"resourceManager"`
+			ActiveDirectory           string `json:"activeDirectory"`
+			ActiveDirectoryResourceID string `json:"activeDirectoryResourceId"`
+		} `json:"endpoints"`
+	}
+	if err := json.Unmarshal(output, &cloudConfig); err != nil {
+		return CloudProfile{}, fmt.Errorf("failed to parse Azure cloud configuration: %w", err)
+	}
+
+	// Use the Resource Manager endpoint for ARM APIs; the management endpoint is for classic operations
+	endpoint := cloudConfig.Endpoints.ResourceManager
+	if endpoint == "" {
+		endpoint = cloudConfig.Endpoints.Management
+	}
+	if endpoint == "" {
+		return CloudProfile{}, fmt.Errorf("cloud %s of Azure CLI has no Resource Manager endpoint", cloudConfig.Name)
+	}
+
+	return CloudProfile{
+		Name:                     cloudConfig.Name,
+		ResourceManagerEndpoint:  strings.TrimSuffix(endpoint, "/"),
+		ActiveDirectoryAuthority: strings.TrimSuffix(cloudConfig.Endpoints.ActiveDirectory, "/"),
+		Audience:                 cloudConfig.Endpoints.ActiveDirectoryResourceID,
+	}, nil
+}
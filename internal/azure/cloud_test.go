@@ -0,0 +1,173 @@
+package azure
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newCloudTestClient returns a client whose Azure CLI reports the given cloud, and a counter
+// of the times it was asked
+func newCloudTestClient(profile CloudProfile, err error) (*Client, *int) {
+	calls := 0
+	client := NewClient()
+	client.detectCloud = func() (CloudProfile, error) {
+		calls++
+		return profile, err
+	}
+	return client, &calls
+}
+
+func TestSetCloud(t *testing.T) {
+	stack := CloudProfile{Name: "AzureStack", ResourceManagerEndpoint: "https://management.local.azurestack.external/"}
+	public := CloudProfile{Name: "AzureCloud", ResourceManagerEndpoint: "https://arm.contoso.test", Audience: "https://contoso.test/"}
+
+	tests := []struct {
+		name     string
+		profiles []CloudProfile
+		cloud    string
+		want     CloudProfile
+		wantErr  string
+	}{
+		{
+			name:  "built-in ignoring case",
+			cloud: "azureusgovernment",
+			want:  builtInClouds[1],
+		},
+		{
+			name:     "user profile",
+			profiles: []CloudProfile{stack},
+			cloud:    "AzureStack",
+			want: CloudProfile{
+				Name:                    "AzureStack",
+				ResourceManagerEndpoint: "https://management.local.azurestack.external",
+				Audience:                "https://management.local.azurestack.external/",
+			},
+		},
+		{
+			name:     "user profile replacing a built-in one",
+			profiles: []CloudProfile{public},
+			cloud:    "AZURECLOUD",
+			want:     public,
+		},
+		{
+			name:    "unknown",
+			cloud:   "AzureGermanCloud",
+			wantErr: "unknown cloud: AzureGermanCloud (available: AzureChinaCloud, AzureCloud, AzureUSGovernment)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, calls := newCloudTestClient(CloudProfile{}, errors.New("az not installed"))
+			if err := client.AddCloudProfiles(tt.profiles...); err != nil {
+				t.Fatal(err)
+			}
+			err := client.SetCloud(tt.cloud)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("SetCloud() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for range 2 {
+				if got := client.Cloud(); got.CloudProfile != tt.want || got.Source != CloudSourceProfile {
+					t.Errorf("Cloud() = %+v, want %+v from %s", got, tt.want, CloudSourceProfile)
+				}
+			}
+			if *calls != 0 {
+				t.Errorf("Azure CLI was asked for its cloud %d times with a profile set", *calls)
+			}
+		})
+	}
+}
+
+func TestCloudFromAzureCLI(t *testing.T) {
+	tests := []struct {
+		name       string
+		cli        CloudProfile
+		cliErr     error
+		want       CloudProfile
+		wantSource string
+	}{
+		{
+			name:       "known cloud",
+			cli:        CloudProfile{Name: "AzureChinaCloud", ResourceManagerEndpoint: "https://management.chinacloudapi.cn"},
+			want:       builtInClouds[2],
+			wantSource: CloudSourceAzureCLI,
+		},
+		{
+			name:       "registered cloud",
+			cli:        CloudProfile{Name: "AzureStack", ResourceManagerEndpoint: "https://management.local.azurestack.external"},
+			want:       CloudProfile{Name: "AzureStack", ResourceManagerEndpoint: "https://management.local.azurestack.external"},
+			wantSource: CloudSourceAzureCLI,
+		},
+		{
+			name:       "Azure CLI unavailable",
+			cliErr:     errors.New("az not installed"),
+			want:       builtInClouds[0],
+			wantSource: CloudSourceDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, calls := newCloudTestClient(tt.cli, tt.cliErr)
+			for range 3 {
+				if got := client.Cloud(); got.CloudProfile != tt.want || got.Source != tt.wantSource {
+					t.Errorf("Cloud() = %+v, want %+v from %s", got, tt.want, tt.wantSource)
+				}
+			}
+			if *calls != 1 {
+				t.Errorf("Azure CLI was asked for its cloud %d times, want once", *calls)
+			}
+		})
+	}
+}
+
+func TestLoadCloudProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouds.yaml")
+	content := `clouds:
+  - name: AzureStack
+    resourceManager: https://management.local.azurestack.external
+    activeDirectory: https://adfs.local.azurestack.external/adfs
+  - name: Sandbox
+    resourceManager: https://arm.sandbox.test/
+    audience: https://sandbox.test/
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := LoadCloudProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].ActiveDirectoryAuthority != "https://adfs.local.azurestack.external/adfs" || profiles[1].Audience != "https://sandbox.test/" {
+		t.Errorf("LoadCloudProfiles() = %+v", profiles)
+	}
+
+	client := NewClient()
+	if err := client.AddCloudProfiles(profiles...); err != nil {
+		t.Fatal(err)
+	}
+	if clouds := client.Clouds(); len(clouds) != 5 {
+		t.Errorf("Clouds() returned %d clouds, want the 3 built-in and 2 user clouds", len(clouds))
+	}
+	if err := client.AddCloudProfiles(CloudProfile{Name: "Broken"}); err == nil || !strings.Contains(err.Error(), "resourceManager endpoint is required") {
+		t.Errorf("AddCloudProfiles() error = %v, want a missing endpoint error", err)
+	}
+
+	if err := os.WriteFile(path, []byte("clouds: [name"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCloudProfiles(path); err == nil {
+		t.Error("LoadCloudProfiles() accepted malformed YAML")
+	}
+}
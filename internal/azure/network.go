@@ -0,0 +1,279 @@
+package azure
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// caBundleEnv is the variable Azure CLI reads a custom CA bundle from
+const caBundleEnv = "REQUESTS_CA_BUNDLE"
+
+// NetworkOptions configures how the client reaches the Azure Management API
+type NetworkOptions struct {
+	// Proxy is the proxy URL for every request, replacing HTTPS_PROXY. NO_PROXY still applies.
+	Proxy string
+	// CAFile is a PEM bundle of certificates trusted in addition to the system roots.
+	// REQUESTS_CA_BUNDLE is used when it is empty.
+	CAFile string
+	// ClientCert and ClientKey are PEM files of a client certificate for mutual TLS.
+	// The key may be in the certificate file.
+	ClientCert string
+	ClientKey  string
+}
+
+// NetworkInfo describes the effective proxy and TLS configuration for the management endpoint
+type NetworkInfo struct {
+	Endpoint          string    `json:"endpoint"`
+	Proxy             string    `json:"proxy,omitempty"`
+	ProxySource       string    `json:"proxySource"`
+	NoProxy           string    `json:"noProxy,omitempty"`
+	CAFile            string    `json:"caFile,omitempty"`
+	CACertificates    int       `json:"caCertificates,omitempty"`
+	ClientCert        string    `json:"clientCert,omitempty"`
+	ClientCertSubject string    `json:"clientCertSubject,omitempty"`
+	ClientCertExpiry  time.Time `json:"clientCertExpiry,omitzero"`
+}
+
+// Sources of the proxy in effect
+const (
+	ProxySourceOption      = "Option"
+	ProxySourceEnvironment = "Environment Variable"
+	ProxySourceNone        = "None"
+)
+
+// ConfigureNetwork applies proxy, CA bundle and client certificate options to the client's transport
+func (c *Client) ConfigureNetwork(options NetworkOptions) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if options.Proxy != "" {
+		proxyURL, err := parseProxy(options.Proxy)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(req.URL, noProxy()) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	caFile := options.CAFile
+	if caFile == "" {
+		caFile = os.Getenv(caBundleEnv)
+	}
+	if caFile != "" {
+		pool, count, err := loadCABundle(caFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = pool
+		c.network.CAFile, c.network.CACertificates = caFile, count
+	}
+
+	if options.ClientCert != "" {
+		keyFile := options.ClientKey
+		if keyFile == "" {
+			keyFile = options.ClientCert
+		}
+		certificate, err := tls.LoadX509KeyPair(options.ClientCert, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate %s: %w", options.ClientCert, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+		c.network.ClientCert = options.ClientCert
+		if leaf, err := x509.ParseCertificate(certificate.Certificate[0]); err == nil {
+			c.network.ClientCertSubject = leaf.Subject.String()
+			c.network.ClientCertExpiry = leaf.NotAfter
+		}
+	} else if options.ClientKey != "" {
+		return fmt.Errorf("a client key requires a client certificate")
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	c.httpClient.Transport = transport
+	c.proxy = options.Proxy
+	return nil
+}
+
+// NetworkInfo returns the effective proxy and TLS configuration for the management endpoint
+func (c *Client) NetworkInfo() NetworkInfo {
+	info := c.network
+	info.ProxySource = ProxySourceNone
+	info.NoProxy = noProxy()
+
+	endpoint, err := c.GetEffectiveEndpoint()
+	if err != nil {
+		return info
+	}
+	info.Endpoint = endpoint
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return info
+	}
+	proxy := http.ProxyFromEnvironment
+	if transport, ok := c.httpClient.Transport.(*http.Transport); ok && transport.Proxy != nil {
+		proxy = transport.Proxy
+	}
+	if proxyURL, err := proxy(req); err == nil && proxyURL != nil {
+		info.Proxy = proxyURL.Redacted()
+		info.ProxySource = ProxySourceEnvironment
+		if c.proxy != "" {
+			info.ProxySource = ProxySourceOption
+		}
+	}
+	return info
+}
+
+// CheckConnectivity sends an unauthenticated request to the cloud metadata endpoint, which
+// exercises the proxy and TLS configuration without credentials
+func (c *Client) CheckConnectivity() (time.Duration, error) {
+	endpoint, err := c.GetEffectiveEndpoint()
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint+"/metadata/endpoints?api-version=2022-09-01", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach %s: %w", endpoint, err)
+	}
+	resp.Body.Close()
+	return time.Since(start), nil
+}
+
+// loadCABundle returns the system roots extended with the certificates of a PEM file
+func loadCABundle(path string) (*x509.CertPool, int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	count := 0
+	for rest := content; len(rest) > 0; {
+		var certificate *x509.Certificate
+		certificate, rest, err = nextCertificate(rest)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse CA bundle %s: %w", path, err)
+		}
+		if certificate == nil {
+			break
+		}
+		pool.AddCert(certificate)
+		count++
+	}
+	if count == 0 {
+		return nil, 0, fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+	return pool, count, nil
+}
+
+// nextCertificate parses the next PEM certificate, skipping other blocks such as keys. It
+// returns nil when there are no more certificates.
+func nextCertificate(content []byte) (*x509.Certificate, []byte, error) {
+	for {
+		block, rest := pem.Decode(content)
+		if block == nil {
+			return nil, nil, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			content = rest
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return certificate, rest, nil
+	}
+}
+
+// parseProxy validates a proxy URL. A bare host:port is treated as an HTTP proxy.
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: %s", proxy)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+		return proxyURL, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s (supported: http, https, socks5)", proxyURL.Scheme)
+	}
+}
+
+// noProxy returns the hosts that bypass the proxy, like the standard library reads them
+func noProxy() string {
+	if value := os.Getenv("NO_PROXY"); value != "" {
+		return value
+	}
+	return os.Getenv("no_proxy")
+}
+
+// bypassProxy reports whether a request URL matches a NO_PROXY list of host names, domain
+// suffixes, IP addresses or CIDR ranges, optionally with ports. "*" matches every host.
+func bypassProxy(target *url.URL, list string) bool {
+	host, port := strings.ToLower(target.Hostname()), target.Port()
+	if port == "" && target.Scheme == "https" {
+		port = "443"
+	} else if port == "" {
+		port = "80"
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// providersAPIVersion is the Microsoft.Resources API version used to list resource providers
+const providersAPIVersion = "2021-04-01"
+
+// FetchResourceProviders lists the resource providers of the tenant with their resource types
+func (c *Client) FetchResourceProviders(ctx context.Context, accessToken string) ([]models.ResourceProvider, error) {
+	query := url.Values{}
+	query.Set("api-version", providersAPIVersion)
+	query.Set("$expand", "resourceTypes")
+
+	var providers []models.ResourceProvider
+	if err := c.getManagementList(ctx, accessToken, "/providers", query, &providers); err != nil {
+		return nil, fmt.Errorf("failed to list resource providers: %w", err)
+	}
+	return providers, nil
+}
+
+// LoadResourceProviders reads resource providers from the output of "az provider list" or a
+// saved Providers API response with a "value" list
+func LoadResourceProviders(path string) ([]models.ResourceProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource providers: %w", err)
+	}
+
+	var providers []models.ResourceProvider
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		var page struct {
+			Value []models.ResourceProvider `json:"value"`
+		}
+		err = json.Unmarshal(content, &page)
+		providers = page.Value
+	} else {
+		err = json.Unmarshal(content, &providers)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resource providers %s: %w", path, err)
+	}
+	return providers, nil
+}
@@ -0,0 +1,385 @@
+package azure
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout bounds a single request attempt, including reading the response body
+const DefaultTimeout = 2 * time.Minute
+
+// maxErrorMessage limits how much of a response body that is not an ARM error ends up in errors
+const maxErrorMessage = 512
+
+// maxErrorBody limits how much of an error response is read
+const maxErrorBody = 1 << 20
+
+// RetryPolicy controls how failed requests are retried. Throttled (429) and server error
+// (5xx) responses, timeouts and dropped connections are retried with exponential backoff and
+// full jitter, waiting as long as the Retry-After header asks for, up to MaxDelay.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of new clients
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// HTTPError represents an unsuccessful response from the Azure Management API
+type HTTPError struct {
+	StatusCode int
+	// Code is the ARM error code, e.g. AuthorizationFailed
+	Code          string
+	Message       string
+	CorrelationID string
+	RequestID     string
+}
+
+func (e *HTTPError) Error() string {
+	message := fmt.Sprintf("API request failed with status %d", e.StatusCode)
+	if e.Code != "" {
+		message += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+
+	var ids []string
+	if e.CorrelationID != "" {
+		ids = append(ids, "correlation ID: "+e.CorrelationID)
+	}
+	if e.RequestID != "" {
+		ids = append(ids, "request ID: "+e.RequestID)
+	}
+	if len(ids) > 0 {
+		message += " [" + strings.Join(ids, ", ") + "]"
+	}
+	return message
+}
+
+// Retryable reports whether the request may succeed when sent again
+func (e *HTTPError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+// newHTTPError builds an HTTPError from a response, extracting the ARM error details
+func newHTTPError(resp *http.Response, body []byte) *HTTPError {
+	httpErr := &HTTPError{
+		StatusCode:    resp.StatusCode,
+		CorrelationID: resp.Header.Get("x-ms-correlation-request-id"),
+		RequestID:     resp.Header.Get("x-ms-request-id"),
+	}
+
+	var armError struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &armError); err == nil && (armError.Error.Code != "" || armError.Error.Message != "") {
+		httpErr.Code = armError.Error.Code
+		httpErr.Message = armError.Error.Message
+		return httpErr
+	}
+
+	message := strings.TrimSpace(string(body))
+	if len(message) > maxErrorMessage {
+		message = message[:maxErrorMessage] + "..."
+	}
+	httpErr.Message = message
+	return httpErr
+}
+
+// SetTimeout sets the time limit of a single request attempt
+func (c *Client) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// SetRetryPolicy sets how failed requests are retried
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
+// SetUserAgent sets the User-Agent header sent with every request
+func (c *Client) SetUserAgent(userAgent string) {
+	if userAgent != "" {
+		c.userAgent = userAgent
+	}
+}
+
+// get sends an authenticated GET request, retrying throttled and failed attempts, and returns
+// the decompressed response body of the first successful attempt
+func (c *Client) get(ctx context.Context, accessToken, url string) ([]byte, error) {
+	var body []byte
+	err := c.do(ctx, accessToken, url, func(r io.Reader) error {
+		var err error
+		if body, err = io.ReadAll(r); err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// do sends an authenticated GET request and passes the decompressed body of a successful
+// response to read. Throttled and failed attempts, including failures while reading the body,
+// are retried; read is then called again with the body of the new attempt.
+func (c *Client) do(ctx context.Context, accessToken, url string, read func(io.Reader) error) error {
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.attempt(ctx, accessToken, url, read)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.retry.MaxRetries || !retryable(ctx, err) {
+			return err
+		}
+
+		delay := c.backoff(attempt, retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("%w (not retried: the next attempt in %s would exceed the deadline)", err, delay.Round(time.Millisecond))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends a single request and passes the body of a successful response to read. It
+// returns the delay the server asked for on failure.
+func (c *Client) attempt(ctx context.Context, accessToken, url string, read func(io.Reader) error) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(io.LimitReader(reader, maxErrorBody))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read response body: %w", err)
+		}
+		return retryAfter(resp.Header), newHTTPError(resp, body)
+	}
+	return 0, read(reader)
+}
+
+// getPages follows the nextLink of a paged list response. decodePage is called with the body
+// of every attempt to fetch a page and returns the page's nextLink.
+func (c *Client) getPages(ctx context.Context, accessToken, url string, decodePage func(io.Reader) (string, error)) error {
+	seen := make(map[string]bool)
+	for url != "" {
+		if seen[url] {
+			return fmt.Errorf("pagination loop detected at %s", url)
+		}
+		seen[url] = true
+
+		var nextLink string
+		err := c.do(ctx, accessToken, url, func(body io.Reader) error {
+			var err error
+			nextLink, err = decodePage(body)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		url = nextLink
+	}
+	return nil
+}
+
+// decodeListPage stream-decodes a list response page, calling decodeItem for every element of
+// its "value" array as it is read, and returns the page's nextLink
+func decodeListPage(r io.Reader, decodeItem func(*json.Decoder) error) (string, error) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	} else if token != json.Delim('{') {
+		return "", fmt.Errorf("failed to unmarshal response: expected a JSON object")
+	}
+
+	var nextLink string
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		switch key {
+		case "value":
+			if token, err := decoder.Token(); err != nil {
+				return "", fmt.Errorf("failed to unmarshal response: %w", err)
+			} else if token == nil {
+				continue
+			} else if token != json.Delim('[') {
+				return "", fmt.Errorf("failed to unmarshal response: expected \"value\" to be an array")
+			}
+			for decoder.More() {
+				if err := decodeItem(decoder); err != nil {
+					return "", fmt.Errorf("failed to unmarshal response: %w", err)
+				}
+			}
+			if _, err := decoder.Token(); err != nil {
+				return "", fmt.Errorf("failed to unmarshal response: %w", err)
+			}
+		case "nextLink":
+			var link *string
+			if err := decoder.Decode(&link); err != nil {
+				return "", fmt.Errorf("failed to unmarshal response: %w", err)
+			}
+			if link != nil {
+				nextLink = *link
+			}
+		default:
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return "", fmt.Errorf("failed to unmarshal response: %w", err)
+			}
+		}
+	}
+	return nextLink, nil
+}
+
+// getList fetches every page of a list response and decodes the items into out, a pointer to
+// a slice, one at a time as they are read
+func (c *Client) getList(ctx context.Context, accessToken, url string, out interface{}) error {
+	list := reflect.ValueOf(out)
+	if list.Kind() != reflect.Pointer || list.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("list destination must be a pointer to a slice, got %T", out)
+	}
+	list = list.Elem()
+	list.Set(reflect.MakeSlice(list.Type(), 0, 0))
+
+	committed := 0
+	return c.getPages(ctx, accessToken, url, func(body io.Reader) (string, error) {
+		// Drop the items decoded by an earlier, failed attempt at this page
+		list.Set(list.Slice(0, committed))
+		nextLink, err := decodeListPage(body, func(decoder *json.Decoder) error {
+			item := reflect.New(list.Type().Elem())
+			if err := decoder.Decode(item.Interface()); err != nil {
+				return err
+			}
+			list.Set(reflect.Append(list, item.Elem()))
+			return nil
+		})
+		if err == nil {
+			committed = list.Len()
+		}
+		return nextLink, err
+	})
+}
+
+// backoff returns the delay before the next attempt: exponential with full jitter, capped at
+// the maximum delay, but never shorter than the server asked for up to that maximum
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := c.retry.BaseDelay << attempt
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	if delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+	if retryAfter > delay {
+		delay = min(retryAfter, c.retry.MaxDelay)
+	}
+	return delay
+}
+
+// retryable reports whether a failed attempt should be retried: HTTP errors with a retryable
+// status, timeouts, connections reset by the server and truncated responses. Certificate
+// errors and unknown hosts fail again in the same way, so they are not retried.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Retryable()
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthority), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return false
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter parses the delay a throttled response asks for, in seconds or as an HTTP date
+func retryAfter(header http.Header) time.Duration {
+	if milliseconds, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil && milliseconds > 0 {
+		return time.Duration(milliseconds) * time.Millisecond
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,385 @@
+package azure
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testRetryPolicy retries quickly so tests do not wait for real backoff delays
+var testRetryPolicy = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 200 * time.Millisecond}
+
+// newTestClient returns a client with the test retry policy
+func newTestClient() *Client {
+	client := NewClient()
+	client.SetRetryPolicy(testRetryPolicy)
+	return client
+}
+
+// failingHandler answers the first failures requests with the given status and headers, and
+// later requests with body
+func failingHandler(failures int32, status int, header http.Header, body string) (http.Handler, *int32) {
+	var requests int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":{"code":"Failure","message":"try again"}}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}), &requests
+}
+
+func TestGetRetriesThrottledRequests(t *testing.T) {
+	handler, requests := failingHandler(2, http.StatusTooManyRequests, http.Header{"Retry-After-Ms": {"30"}}, `{"ok":true}`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	start := time.Now()
+	body, err := newTestClient().get(context.Background(), "token", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"ok":true}` {
+		t.Errorf("body = %s", body)
+	}
+	if *requests != 3 {
+		t.Errorf("server got %d requests, want 3", *requests)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("retries took %s, want at least the 2x30ms Retry-After", elapsed)
+	}
+}
+
+func TestGetClampsRetryAfter(t *testing.T) {
+	handler, _ := failingHandler(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}}, `{}`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	start := time.Now()
+	if _, err := newTestClient().get(context.Background(), "token", server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("retry waited %s, want at most MaxDelay", elapsed)
+	}
+}
+
+func TestGetRetriesServerErrors(t *testing.T) {
+	handler, requests := failingHandler(2, http.StatusBadGateway, nil, `{"ok":true}`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	if _, err := newTestClient().get(context.Background(), "token", server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if *requests != 3 {
+		t.Errorf("server got %d requests, want 3", *requests)
+	}
+}
+
+func TestGetGivesUpAfterMaxRetries(t *testing.T) {
+	handler, requests := failingHandler(100, http.StatusServiceUnavailable, nil, `{}`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	_, err := newTestClient().get(context.Background(), "token", server.URL)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("get() error = %v, want a 503 HTTPError", err)
+	}
+	if want := int32(testRetryPolicy.MaxRetries + 1); *requests != want {
+		t.Errorf("server got %d requests, want %d", *requests, want)
+	}
+}
+
+func TestGetDoesNotRetryClientErrors(t *testing.T) {
+	handler, requests := failingHandler(100, http.StatusForbidden, nil, `{}`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	if _, err := newTestClient().get(context.Background(), "token", server.URL); err == nil {
+		t.Fatal("get() succeeded, want an error")
+	}
+	if *requests != 1 {
+		t.Errorf("server got %d requests, want 1", *requests)
+	}
+}
+
+func TestHTTPErrorFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-correlation-request-id", "correlation-1")
+		w.Header().Set("x-ms-request-id", "request-1")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":{"code":"AuthorizationFailed","message":"The client does not have authorization"}}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient().get(context.Background(), "token", server.URL)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("get() error = %v, want an HTTPError", err)
+	}
+
+	want := HTTPError{
+		StatusCode:    http.StatusForbidden,
+		Code:          "AuthorizationFailed",
+		Message:       "The client does not have authorization",
+		CorrelationID: "correlation-1",
+		RequestID:     "request-1",
+	}
+	if *httpErr != want {
+		t.Errorf("HTTPError = %+v, want %+v", *httpErr, want)
+	}
+	for _, part := range []string{"403", "AuthorizationFailed", "correlation ID: correlation-1", "request ID: request-1"} {
+		if !strings.Contains(httpErr.Error(), part) {
+			t.Errorf("Error() = %q, want it to contain %q", httpErr.Error(), part)
+		}
+	}
+}
+
+func TestHTTPErrorWithoutARMBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, strings.Repeat("x", 2*maxErrorMessage))
+	}))
+	defer server.Close()
+
+	_, err := newTestClient().get(context.Background(), "token", server.URL)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("get() error = %v, want an HTTPError", err)
+	}
+	if httpErr.Code != "" || len(httpErr.Message) != maxErrorMessage+len("...") {
+		t.Errorf("HTTPError = code %q with a %d byte message, want a truncated message", httpErr.Code, len(httpErr.Message))
+	}
+}
+
+func TestGetDecompressesGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		fmt.Fprint(writer, `{"value":[{"name":"compressed"}]}`)
+		writer.Close()
+	}))
+	defer server.Close()
+
+	var items []struct {
+		Name string `json:"name"`
+	}
+	if err := newTestClient().getList(context.Background(), "token", server.URL, &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Name != "compressed" {
+		t.Errorf("items = %+v", items)
+	}
+}
+
+func TestGetListFollowsNextLink(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"value":[{"name":"a"},{"name":"b"}],"nextLink":"%s?page=2"}`, server.URL)
+		case "2":
+			fmt.Fprintf(w, `{"value":[{"name":"c"}],"nextLink":"%s?page=3"}`, server.URL)
+		default:
+			fmt.Fprint(w, `{"value":[{"name":"d"}]}`)
+		}
+	}))
+	defer server.Close()
+
+	var items []struct {
+		Name string `json:"name"`
+	}
+	if err := newTestClient().getList(context.Background(), "token", server.URL, &items); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	if got := strings.Join(names, ","); got != "a,b,c,d" {
+		t.Errorf("items = %s, want a,b,c,d", got)
+	}
+}
+
+func TestGetListRetriesTruncatedPages(t *testing.T) {
+	var server *httptest.Server
+	var pageRequests int32
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			fmt.Fprintf(w, `{"value":[{"name":"a"}],"nextLink":"%s?page=2"}`, server.URL)
+			return
+		}
+		body := `{"value":[{"name":"b"},{"name":"c"}]}`
+		if atomic.AddInt32(&pageRequests, 1) == 1 {
+			// Announce the full body but close the connection in the middle of the second item
+			w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+			fmt.Fprint(w, body[:25])
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	var items []struct {
+		Name string `json:"name"`
+	}
+	if err := newTestClient().getList(context.Background(), "token", server.URL, &items); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	if got := strings.Join(names, ","); got != "a,b,c" {
+		t.Errorf("items = %s, want a,b,c", got)
+	}
+	if got := atomic.LoadInt32(&pageRequests); got != 2 {
+		t.Errorf("page 2 was requested %d times, want 2", got)
+	}
+}
+
+func TestGetListDetectsPaginationLoops(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"value":[],"nextLink":"%s"}`, server.URL)
+	}))
+	defer server.Close()
+
+	var items []struct{}
+	err := newTestClient().getList(context.Background(), "token", server.URL, &items)
+	if err == nil || !strings.Contains(err.Error(), "pagination loop") {
+		t.Errorf("getList() error = %v, want a pagination loop error", err)
+	}
+}
+
+func TestGetStopsWhenCancelledDuringBackoff(t *testing.T) {
+	handler, requests := failingHandler(100, http.StatusTooManyRequests, http.Header{"Retry-After": {"10"}}, `{}`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := newTestClient()
+	client.SetRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.get(ctx, "token", server.URL)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("get() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("get() returned after %s, want it to stop when cancelled", elapsed)
+	}
+	if *requests != 1 {
+		t.Errorf("server got %d requests, want 1", *requests)
+	}
+}
+
+func TestGetDoesNotWaitPastDeadline(t *testing.T) {
+	handler, requests := failingHandler(100, http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}}, `{}`)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := newTestClient()
+	client.SetRetryPolicy(RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.get(ctx, "token", server.URL)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("get() error = %v, want the 429 HTTPError", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("get() returned after %s, want it to fail without waiting", elapsed)
+	}
+	if *requests != 1 {
+		t.Errorf("server got %d requests, want 1", *requests)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	urlError := func(err error) error {
+		return fmt.Errorf("failed to make request: %w", &url.Error{Op: "Get", URL: "https://management.azure.com", Err: err})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttled", &HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", &HTTPError{StatusCode: http.StatusInternalServerError}, true},
+		{"forbidden", &HTTPError{StatusCode: http.StatusForbidden}, false},
+		{"timeout", urlError(os.ErrDeadlineExceeded), true},
+		{"connection reset", urlError(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"truncated response", fmt.Errorf("failed to read response body: %w", io.ErrUnexpectedEOF), true},
+		{"connection refused", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), false},
+		{"unknown host", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}), false},
+		{"DNS timeout", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}}), true},
+		{"unknown authority", urlError(x509.UnknownAuthorityError{}), false},
+		{"hostname mismatch", urlError(x509.HostnameError{Host: "example.com"}), false},
+		{"other error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(context.Background(), tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if retryable(ctx, &HTTPError{StatusCode: http.StatusServiceUnavailable}) {
+		t.Error("retryable() = true after the context was cancelled")
+	}
+}
+
+func TestGetDoesNotRetryUntrustedCertificates(t *testing.T) {
+	handler, requests := failingHandler(0, http.StatusOK, nil, `{}`)
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	_, err := newTestClient().get(context.Background(), "token", server.URL)
+	if err == nil {
+		t.Fatal("get() succeeded with an untrusted certificate")
+	}
+	if retryable(context.Background(), err) {
+		t.Errorf("retryable(%v) = true, want false", err)
+	}
+	if *requests != 0 {
+		t.Errorf("server handled %d requests, want 0", *requests)
+	}
+}
@@ -0,0 +1,141 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// FetchFunc retrieves a fresh copy of the provider operations catalog
+type FetchFunc func() (map[string]models.ProviderOperationsResponse, error)
+
+// Catalog holds the provider operations catalog in memory and shares it between callers.
+// The catalog map is replaced as a whole on refresh and must be treated as read-only.
+type Catalog struct {
+	fetch          FetchFunc
+	fetchNamespace NamespaceFetchFunc
+
+	mu        sync.RWMutex
+	providers map[string]models.ProviderOperationsResponse
+	store     *Store
+	updatedAt time.Time
+	lastErr   error
+
+	// namespaces caches namespaces fetched on their own before the whole catalog is loaded,
+	// keyed by lowercase name; namespaceCalls holds the fetches in progress
+	namespaces     map[string]cachedNamespace
+	namespaceCalls map[string]*namespaceCall
+	namespaceDir   string
+
+	// loadMu serializes catalog fetches so concurrent callers don't hit the API more than once
+	loadMu sync.Mutex
+}
+
+// New creates a new catalog that loads its data with the given fetch function
+func New(fetch FetchFunc) *Catalog {
+	return &Catalog{fetch: fetch}
+}
+
+// Providers returns the catalog, fetching it on first use
+func (c *Catalog) Providers() (map[string]models.ProviderOperationsResponse, error) {
+	c.mu.RLock()
+	providers := c.providers
+	c.mu.RUnlock()
+
+	if providers != nil {
+		return providers, nil
+	}
+
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	// Another caller may have loaded the catalog while we were waiting
+	c.mu.RLock()
+	providers = c.providers
+	c.mu.RUnlock()
+	if providers != nil {
+		return providers, nil
+	}
+
+	if err := c.refreshLocked(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.providers, nil
+}
+
+// Store returns the indexed operations of the catalog, fetching it on first use. The
+// store is built once per loaded catalog.
+func (c *Catalog) Store() (*Store, error) {
+	if _, err := c.Providers(); err != nil {
+		return nil, err
+	}
+
+	// A refresh clears the store, so it is always built from the current catalog
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		c.store = NewStore(c.providers)
+	}
+	return c.store, nil
+}
+
+// Refresh fetches a fresh catalog and replaces the current one.
+// On failure the previously loaded catalog is kept.
+func (c *Catalog) Refresh() error {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	return c.refreshLocked()
+}
+
+// refreshLocked fetches the catalog; the caller must hold loadMu
+func (c *Catalog) refreshLocked() error {
+	providers, err := c.fetch()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastErr = err
+	if err != nil {
+		return fmt.Errorf("failed to refresh provider operations catalog: %w", err)
+	}
+
+	c.providers = providers
+	c.store = nil
+	c.updatedAt = time.Now()
+	return nil
+}
+
+// StartBackgroundRefresh refreshes the catalog every interval until the context is cancelled.
+// Refresh errors are reported through onError, which may be nil.
+func (c *Catalog) StartBackgroundRefresh(ctx context.Context, interval time.Duration, onError func(error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Refresh(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// Status returns the time of the last successful refresh and the last refresh error
+func (c *Catalog) Status() (time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.updatedAt, c.lastErr
+}
@@ -0,0 +1,142 @@
+// Package catalogtest builds provider operations catalogs for tests and benchmarks.
+package catalogtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/registry"
+)
+
+// Filler namespaces bring the catalog to the size of the public Azure catalog, about 250
+// namespaces and 25,000 operations
+const (
+	fillerNamespaces    = 220
+	fillerResourceTypes = 8
+)
+
+// dataPlaneTypes are child resource types with DataActions, as in the real catalog
+var dataPlaneTypes = map[string][]string{
+	"Microsoft.Storage": {
+		"storageAccounts/blobServices/containers/blobs",
+		"storageAccounts/queueServices/queues/messages",
+		"storageAccounts/fileServices/fileshares/files",
+	},
+	"Microsoft.KeyVault": {"vaults/secrets", "vaults/keys", "vaults/certificates"},
+}
+
+// Realistic returns a deterministic catalog with every namespace and resource type of the
+// built-in service registry, their child types and the data plane types of storage and Key
+// Vault, padded with generated namespaces to the size of the public Azure catalog
+func Realistic() map[string]models.ProviderOperationsResponse {
+	resourceTypes := make(map[string]map[string]bool)
+	addType := func(namespace, resourceType string) {
+		if resourceTypes[namespace] == nil {
+			resourceTypes[namespace] = make(map[string]bool)
+		}
+		// Nested types come with their parents, e.g. servers for servers/databases
+		segments := strings.Split(resourceType, "/")
+		for i := range segments {
+			resourceTypes[namespace][strings.Join(segments[:i+1], "/")] = true
+		}
+	}
+
+	for _, service := range registry.Default().Services() {
+		if service.ResourceType != "" {
+			addType(service.Provider, service.ResourceType)
+			addType(service.Provider, service.ResourceType+"/extensions")
+		}
+		for _, types := range service.Operations {
+			for _, resourceType := range types {
+				addType(service.Provider, resourceType)
+			}
+		}
+	}
+	for namespace, types := range dataPlaneTypes {
+		for _, resourceType := range types {
+			addType(namespace, resourceType)
+		}
+	}
+	for i := range fillerNamespaces {
+		namespace := fmt.Sprintf("Microsoft.Generated%03d", i)
+		for j := range fillerResourceTypes {
+			addType(namespace, fmt.Sprintf("resources%02d/children", j))
+		}
+	}
+
+	providers := make(map[string]models.ProviderOperationsResponse, len(resourceTypes))
+	for namespace, types := range resourceTypes {
+		providers[namespace] = provider(namespace, types)
+	}
+	return providers
+}
+
+// provider builds a namespace with the standard operations of each resource type
+func provider(namespace string, types map[string]bool) models.ProviderOperationsResponse {
+	names := make([]string, 0, len(types))
+	for resourceType := range types {
+		names = append(names, resourceType)
+	}
+	sort.Strings(names)
+
+	response := models.ProviderOperationsResponse{
+		Namespace:   "/providers/Microsoft.Authorization/providerOperations/" + namespace,
+		DisplayName: namespace,
+		Operations: []models.ProviderOperation{
+			operation(namespace, "register/action", "Registers the subscription for the resource provider", false),
+			operation(namespace, "operations/read", "Lists the operations of the resource provider", false),
+		},
+	}
+	for _, resourceType := range names {
+		response.ResourceTypes = append(response.ResourceTypes, models.ProviderResourceType{
+			Name:        resourceType,
+			DisplayName: resourceType,
+			Operations:  operations(namespace, resourceType),
+		})
+	}
+	return response
+}
+
+// operations returns the operations of a resource type: DataActions for data plane types,
+// and read, write, delete and a few actions otherwise
+func operations(namespace, resourceType string) []models.ProviderOperation {
+	prefix := namespace + "/" + resourceType + "/"
+	if isDataPlaneType(namespace, resourceType) {
+		return []models.ProviderOperation{
+			operation(namespace, resourceType+"/read", "Returns the data of "+prefix, true),
+			operation(namespace, resourceType+"/write", "Writes the data of "+prefix, true),
+			operation(namespace, resourceType+"/delete", "Deletes the data of "+prefix, true),
+		}
+	}
+	return []models.ProviderOperation{
+		operation(namespace, resourceType+"/read", "Gets or lists "+prefix+" resources", false),
+		operation(namespace, resourceType+"/write", "Creates or updates "+prefix+" resources", false),
+		operation(namespace, resourceType+"/delete", "Deletes "+prefix+" resources", false),
+		operation(namespace, resourceType+"/start/action", "Starts "+prefix+" resources", false),
+		operation(namespace, resourceType+"/restart/action", "Restarts "+prefix+" resources", false),
+		operation(namespace, resourceType+"/listKeys/action", "Lists the access keys of "+prefix+" resources", false),
+	}
+}
+
+// isDataPlaneType reports whether a resource type has DataActions
+func isDataPlaneType(namespace, resourceType string) bool {
+	for _, dataPlaneType := range dataPlaneTypes[namespace] {
+		if dataPlaneType == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// operation builds a provider operation
+func operation(namespace, name, description string, isDataAction bool) models.ProviderOperation {
+	return models.ProviderOperation{
+		Name:         namespace + "/" + name,
+		DisplayName:  description,
+		Description:  description + ".",
+		Origin:       "user,system",
+		IsDataAction: isDataAction,
+	}
+}
@@ -0,0 +1,195 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// compactMagic starts every compact snapshot
+const compactMagic = "azperm-catalog/1\n"
+
+// compactCatalog is the serialized form of a catalog. Every string is stored once in
+// Strings and referenced by index, since names, origins and descriptions repeat a lot.
+type compactCatalog struct {
+	Strings   []string
+	Providers []compactProvider
+}
+
+// compactProvider is a provider namespace of a compact catalog
+type compactProvider struct {
+	ID, DisplayName int32
+	Operations      []compactOperation
+	ResourceTypes   []compactResourceType
+}
+
+// compactResourceType is a resource type of a compact catalog
+type compactResourceType struct {
+	Name, DisplayName int32
+	Operations        []compactOperation
+}
+
+// compactOperation is an operation of a compact catalog
+type compactOperation struct {
+	Name, DisplayName, Description, Origin int32
+	IsDataAction                           bool
+}
+
+// stringTable interns strings while building a compact catalog
+type stringTable struct {
+	strings []string
+	indexes map[string]int32
+}
+
+// intern returns the index of a string, adding it on first use
+func (t *stringTable) intern(value string) int32 {
+	if index, ok := t.indexes[value]; ok {
+		return index
+	}
+	index := int32(len(t.strings))
+	t.strings = append(t.strings, value)
+	t.indexes[value] = index
+	return index
+}
+
+// compactOperations interns the strings of a list of operations
+func (t *stringTable) compactOperations(operations []models.ProviderOperation) []compactOperation {
+	compacted := make([]compactOperation, 0, len(operations))
+	for _, operation := range operations {
+		compacted = append(compacted, compactOperation{
+			Name:         t.intern(operation.Name),
+			DisplayName:  t.intern(operation.DisplayName),
+			Description:  t.intern(operation.Description),
+			Origin:       t.intern(operation.Origin),
+			IsDataAction: operation.IsDataAction,
+		})
+	}
+	return compacted
+}
+
+// WriteCompact writes a catalog in the compact gzip-compressed form
+func WriteCompact(w io.Writer, providers map[string]models.ProviderOperationsResponse) error {
+	table := &stringTable{indexes: make(map[string]int32)}
+	var compact compactCatalog
+	for _, provider := range sortedProviders(providers) {
+		compacted := compactProvider{
+			ID:          table.intern(provider.Namespace),
+			DisplayName: table.intern(provider.DisplayName),
+			Operations:  table.compactOperations(provider.Operations),
+		}
+		for _, resourceType := range provider.ResourceTypes {
+			compacted.ResourceTypes = append(compacted.ResourceTypes, compactResourceType{
+				Name:        table.intern(resourceType.Name),
+				DisplayName: table.intern(resourceType.DisplayName),
+				Operations:  table.compactOperations(resourceType.Operations),
+			})
+		}
+		compact.Providers = append(compact.Providers, compacted)
+	}
+	compact.Strings = table.strings
+
+	if _, err := io.WriteString(w, compactMagic); err != nil {
+		return err
+	}
+	compressed := gzip.NewWriter(w)
+	if err := gob.NewEncoder(compressed).Encode(compact); err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+	return compressed.Close()
+}
+
+// ReadCompact reads a catalog written by WriteCompact
+func ReadCompact(r io.Reader) (map[string]models.ProviderOperationsResponse, error) {
+	magic := make([]byte, len(compactMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != compactMagic {
+		return nil, fmt.Errorf("not a compact catalog")
+	}
+
+	compressed, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress catalog: %w", err)
+	}
+	defer compressed.Close()
+
+	var compact compactCatalog
+	if err := gob.NewDecoder(compressed).Decode(&compact); err != nil {
+		return nil, fmt.Errorf("failed to decode catalog: %w", err)
+	}
+
+	lookup := func(index int32) (string, error) {
+		if index < 0 || int(index) >= len(compact.Strings) {
+			return "", fmt.Errorf("corrupt catalog: string %d out of range", index)
+		}
+		return compact.Strings[index], nil
+	}
+	expand := func(operations []compactOperation) ([]models.ProviderOperation, error) {
+		expanded := make([]models.ProviderOperation, 0, len(operations))
+		for _, operation := range operations {
+			var values [4]string
+			for i, index := range []int32{operation.Name, operation.DisplayName, operation.Description, operation.Origin} {
+				value, err := lookup(index)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = value
+			}
+			expanded = append(expanded, models.ProviderOperation{
+				Name:         values[0],
+				DisplayName:  values[1],
+				Description:  values[2],
+				Origin:       values[3],
+				IsDataAction: operation.IsDataAction,
+			})
+		}
+		return expanded, nil
+	}
+
+	providers := make(map[string]models.ProviderOperationsResponse, len(compact.Providers))
+	for _, compacted := range compact.Providers {
+		var provider models.ProviderOperationsResponse
+		if provider.Namespace, err = lookup(compacted.ID); err != nil {
+			return nil, err
+		}
+		if provider.DisplayName, err = lookup(compacted.DisplayName); err != nil {
+			return nil, err
+		}
+		if provider.Operations, err = expand(compacted.Operations); err != nil {
+			return nil, err
+		}
+		for _, compactedType := range compacted.ResourceTypes {
+			var resourceType models.ProviderResourceType
+			if resourceType.Name, err = lookup(compactedType.Name); err != nil {
+				return nil, err
+			}
+			if resourceType.DisplayName, err = lookup(compactedType.DisplayName); err != nil {
+				return nil, err
+			}
+			if resourceType.Operations, err = expand(compactedType.Operations); err != nil {
+				return nil, err
+			}
+			provider.ResourceTypes = append(provider.ResourceTypes, resourceType)
+		}
+		providers[Namespace(provider)] = provider
+	}
+	return providers, nil
+}
+
+// SaveCompactFile writes a catalog snapshot in the compact form
+func SaveCompactFile(path string, providers map[string]models.ProviderOperationsResponse) error {
+	var buffer bytes.Buffer
+	if err := WriteCompact(&buffer, providers); err != nil {
+		return err
+	}
+	return writeSnapshot(path, buffer.Bytes())
+}
+
+// isCompact reports whether a reader starts with a compact catalog, without consuming it
+func isCompact(reader *bufio.Reader) bool {
+	magic, err := reader.Peek(len(compactMagic))
+	return err == nil && string(magic) == compactMagic
+}
@@ -0,0 +1,131 @@
+package catalog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// Diff compares two catalog snapshots. Namespaces, resource types and operations are
+// matched case-insensitively; the reported names are taken from the snapshot they appear in.
+func Diff(oldProviders, newProviders map[string]models.ProviderOperationsResponse) models.CatalogDiff {
+	diff := models.CatalogDiff{
+		AddedNamespaces:      []string{},
+		RemovedNamespaces:    []string{},
+		AddedResourceTypes:   []string{},
+		RemovedResourceTypes: []string{},
+		AddedOperations:      []string{},
+		RemovedOperations:    []string{},
+		DataActionChanges:    []models.OperationChange{},
+		DisplayNameChanges:   []models.OperationChange{},
+	}
+
+	oldNamespaces, newNamespaces := namespaceIndex(oldProviders), namespaceIndex(newProviders)
+	diff.AddedNamespaces = missingFrom(newNamespaces, oldNamespaces)
+	diff.RemovedNamespaces = missingFrom(oldNamespaces, newNamespaces)
+
+	oldTypes, newTypes := resourceTypeIndex(oldProviders), resourceTypeIndex(newProviders)
+	diff.AddedResourceTypes = missingFrom(newTypes, oldTypes)
+	diff.RemovedResourceTypes = missingFrom(oldTypes, newTypes)
+
+	oldOperations, newOperations := operationIndex(oldProviders), operationIndex(newProviders)
+	for key, operation := range newOperations {
+		previous, exists := oldOperations[key]
+		if !exists {
+			diff.AddedOperations = append(diff.AddedOperations, operation.Name)
+			continue
+		}
+		if previous.IsDataAction != operation.IsDataAction {
+			diff.DataActionChanges = append(diff.DataActionChanges, models.OperationChange{
+				Operation: operation.Name,
+				Old:       strconv.FormatBool(previous.IsDataAction),
+				New:       strconv.FormatBool(operation.IsDataAction),
+			})
+		}
+		if previous.DisplayName != operation.DisplayName {
+			diff.DisplayNameChanges = append(diff.DisplayNameChanges, models.OperationChange{
+				Operation: operation.Name,
+				Old:       previous.DisplayName,
+				New:       operation.DisplayName,
+			})
+		}
+	}
+	for key, operation := range oldOperations {
+		if _, exists := newOperations[key]; !exists {
+			diff.RemovedOperations = append(diff.RemovedOperations, operation.Name)
+		}
+	}
+
+	sortFold(diff.AddedOperations)
+	sortFold(diff.RemovedOperations)
+	sortChanges(diff.DataActionChanges)
+	sortChanges(diff.DisplayNameChanges)
+
+	return diff
+}
+
+// HasChanges reports whether the diff contains any differences
+func HasChanges(diff models.CatalogDiff) bool {
+	return len(diff.AddedNamespaces) > 0 || len(diff.RemovedNamespaces) > 0 ||
+		len(diff.AddedResourceTypes) > 0 || len(diff.RemovedResourceTypes) > 0 ||
+		len(diff.AddedOperations) > 0 || len(diff.RemovedOperations) > 0 ||
+		len(diff.DataActionChanges) > 0 || len(diff.DisplayNameChanges) > 0
+}
+
+// namespaceIndex maps the lowercase namespaces of a catalog to their original spelling
+func namespaceIndex(providers map[string]models.ProviderOperationsResponse) map[string]string {
+	index := make(map[string]string, len(providers))
+	for namespace := range providers {
+		index[strings.ToLower(namespace)] = namespace
+	}
+	return index
+}
+
+// resourceTypeIndex maps the lowercase "Namespace/resourceType" names of a catalog to their original spelling
+func resourceTypeIndex(providers map[string]models.ProviderOperationsResponse) map[string]string {
+	index := make(map[string]string)
+	for namespace, provider := range providers {
+		for _, resourceType := range provider.ResourceTypes {
+			name := namespace + "/" + resourceType.Name
+			index[strings.ToLower(name)] = name
+		}
+	}
+	return index
+}
+
+// operationIndex maps the lowercase operation names of a catalog to the operations
+func operationIndex(providers map[string]models.ProviderOperationsResponse) map[string]models.OperationInfo {
+	index := make(map[string]models.OperationInfo)
+	for _, operation := range Operations(providers) {
+		index[strings.ToLower(operation.Name)] = operation
+	}
+	return index
+}
+
+// missingFrom returns the sorted names of the index entries that do not exist in other
+func missingFrom(index, other map[string]string) []string {
+	missing := []string{}
+	for key, name := range index {
+		if _, exists := other[key]; !exists {
+			missing = append(missing, name)
+		}
+	}
+	sortFold(missing)
+	return missing
+}
+
+// sortFold sorts names case-insensitively
+func sortFold(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+}
+
+// sortChanges sorts operation changes by operation name
+func sortChanges(changes []models.OperationChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return strings.ToLower(changes[i].Operation) < strings.ToLower(changes[j].Operation)
+	})
+}
@@ -0,0 +1,90 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+func TestDiff(t *testing.T) {
+	oldProviders := map[string]models.ProviderOperationsResponse{
+		"Microsoft.Web": {
+			Namespace: "Microsoft.Web",
+			ResourceTypes: []models.ProviderResourceType{{
+				Name: "sites",
+				Operations: []models.ProviderOperation{
+					{Name: "Microsoft.Web/sites/read", DisplayName: "Get Web App"},
+					{Name: "Microsoft.Web/sites/delete", DisplayName: "Delete Web App"},
+					{Name: "Microsoft.Web/sites/config/list/action", DisplayName: "List config"},
+				},
+			}},
+		},
+		"Microsoft.Legacy": {
+			Namespace: "Microsoft.Legacy",
+			Operations: []models.ProviderOperation{
+				{Name: "Microsoft.Legacy/register/action"},
+			},
+		},
+	}
+	newProviders := map[string]models.ProviderOperationsResponse{
+		"microsoft.web": {
+			Namespace: "microsoft.web",
+			ResourceTypes: []models.ProviderResourceType{
+				{
+					Name: "Sites",
+					Operations: []models.ProviderOperation{
+						{Name: "microsoft.web/sites/read", DisplayName: "Get Web App"},
+						{Name: "Microsoft.Web/sites/config/list/action", DisplayName: "List Web App settings", IsDataAction: true},
+						{Name: "Microsoft.Web/sites/restart/action", DisplayName: "Restart Web App"},
+					},
+				},
+				{
+					Name: "sites/slots",
+					Operations: []models.ProviderOperation{
+						{Name: "Microsoft.Web/sites/slots/read"},
+					},
+				},
+			},
+		},
+		"Microsoft.Chaos": {Namespace: "Microsoft.Chaos"},
+	}
+
+	diff := Diff(oldProviders, newProviders)
+
+	want := models.CatalogDiff{
+		AddedNamespaces:      []string{"Microsoft.Chaos"},
+		RemovedNamespaces:    []string{"Microsoft.Legacy"},
+		AddedResourceTypes:   []string{"microsoft.web/sites/slots"},
+		RemovedResourceTypes: []string{},
+		AddedOperations:      []string{"Microsoft.Web/sites/restart/action", "Microsoft.Web/sites/slots/read"},
+		RemovedOperations:    []string{"Microsoft.Legacy/register/action", "Microsoft.Web/sites/delete"},
+		DataActionChanges: []models.OperationChange{
+			{Operation: "Microsoft.Web/sites/config/list/action", Old: "false", New: "true"},
+		},
+		DisplayNameChanges: []models.OperationChange{
+			{Operation: "Microsoft.Web/sites/config/list/action", Old: "List config", New: "List Web App settings"},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("Diff() =\n%+v\nwant\n%+v", diff, want)
+	}
+	if !HasChanges(diff) {
+		t.Error("HasChanges() = false, want true")
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	providers := map[string]models.ProviderOperationsResponse{
+		"Microsoft.Web": {
+			Namespace: "Microsoft.Web",
+			ResourceTypes: []models.ProviderResourceType{{
+				Name:       "sites",
+				Operations: []models.ProviderOperation{{Name: "Microsoft.Web/sites/read"}},
+			}},
+		},
+	}
+	if diff := Diff(providers, providers); HasChanges(diff) {
+		t.Errorf("Diff() of identical catalogs reported changes: %+v", diff)
+	}
+}
@@ -0,0 +1,208 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// ErrNamespaceNotFound is returned by a NamespaceFetchFunc for a namespace the API does not know
+var ErrNamespaceNotFound = errors.New("provider namespace not found")
+
+// NamespaceFetchFunc retrieves the operations of a single provider namespace
+type NamespaceFetchFunc func(namespace string) (models.ProviderOperationsResponse, error)
+
+// NamespaceCacheMaxAge is how long a namespace persisted by an earlier run is used before it
+// is fetched again
+const NamespaceCacheMaxAge = 24 * time.Hour
+
+// SetNamespaceFetch enables fetching single namespaces on demand instead of the whole catalog
+func (c *Catalog) SetNamespaceFetch(fetch NamespaceFetchFunc) {
+	c.fetchNamespace = fetch
+}
+
+// SetNamespaceCache persists namespaces fetched on their own in dir, one snapshot file per
+// namespace, so later runs reuse them for NamespaceCacheMaxAge
+func (c *Catalog) SetNamespaceCache(dir string) {
+	c.namespaceDir = dir
+}
+
+// DefaultNamespaceCacheDir returns the directory of the per-namespace cache files, next to
+// the cached provider operations catalog
+func DefaultNamespaceCacheDir() (string, error) {
+	path, err := DefaultCachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "namespaces"), nil
+}
+
+// Namespaces returns the operations of the given provider namespaces. Without a namespace
+// fetch function, or once the whole catalog is loaded, they are taken from the catalog.
+// Otherwise the namespaces are fetched concurrently on first use and each is cached on its
+// own, in memory and in the namespace cache directory if one is set. Namespaces the API does not know are left
+// out of the result.
+func (c *Catalog) Namespaces(names ...string) (map[string]models.ProviderOperationsResponse, error) {
+	c.mu.RLock()
+	providers := c.providers
+	c.mu.RUnlock()
+
+	if providers == nil && c.fetchNamespace == nil {
+		var err error
+		if providers, err = c.Providers(); err != nil {
+			return nil, err
+		}
+	}
+	if providers != nil {
+		subset := make(map[string]models.ProviderOperationsResponse, len(names))
+		for _, name := range names {
+			if provider, ok := LookupNamespace(providers, name); ok {
+				subset[name] = provider
+			}
+		}
+		return subset, nil
+	}
+
+	// Namespaces are fetched concurrently; namespace joins calls for the same name
+	results := make([]struct {
+		cached cachedNamespace
+		err    error
+	}, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cached := &results[i].cached
+			cached.provider, cached.found, results[i].err = c.namespace(name)
+		}()
+	}
+	wg.Wait()
+
+	subset := make(map[string]models.ProviderOperationsResponse, len(names))
+	for i, result := range results {
+		if result.err != nil {
+			return nil, result.err
+		}
+		if result.cached.found {
+			subset[names[i]] = result.cached.provider
+		}
+	}
+	return subset, nil
+}
+
+// LookupNamespace finds a provider namespace in a catalog, ignoring case as Azure does
+func LookupNamespace(providers map[string]models.ProviderOperationsResponse, name string) (models.ProviderOperationsResponse, bool) {
+	if provider, ok := providers[name]; ok {
+		return provider, true
+	}
+	for namespace, provider := range providers {
+		if strings.EqualFold(namespace, name) {
+			return provider, true
+		}
+	}
+	return models.ProviderOperationsResponse{}, false
+}
+
+// cachedNamespace is a fetched namespace, or a record that the API does not know it
+type cachedNamespace struct {
+	provider models.ProviderOperationsResponse
+	found    bool
+}
+
+// namespaceCall is a namespace fetch in progress. Callers asking for the same namespace
+// wait for it instead of fetching it again.
+type namespaceCall struct {
+	done   chan struct{}
+	cached cachedNamespace
+	err    error
+}
+
+// namespace returns a cached namespace, reading it from the namespace cache directory or
+// fetching it on first use. Different namespaces are fetched concurrently.
+func (c *Catalog) namespace(name string) (models.ProviderOperationsResponse, bool, error) {
+	key := strings.ToLower(name)
+
+	c.mu.Lock()
+	if cached, ok := c.namespaces[key]; ok {
+		c.mu.Unlock()
+		return cached.provider, cached.found, nil
+	}
+	if call, ok := c.namespaceCalls[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.cached.provider, call.cached.found, call.err
+	}
+	call := &namespaceCall{done: make(chan struct{})}
+	if c.namespaceCalls == nil {
+		c.namespaceCalls = make(map[string]*namespaceCall)
+	}
+	c.namespaceCalls[key] = call
+	c.mu.Unlock()
+
+	call.cached, call.err = c.loadNamespace(name)
+
+	c.mu.Lock()
+	delete(c.namespaceCalls, key)
+	if call.err == nil {
+		if c.namespaces == nil {
+			c.namespaces = make(map[string]cachedNamespace)
+		}
+		c.namespaces[key] = call.cached
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.cached.provider, call.cached.found, call.err
+}
+
+// loadNamespace reads a namespace persisted by an earlier run, or fetches it and persists it
+func (c *Catalog) loadNamespace(name string) (cachedNamespace, error) {
+	if provider, ok := c.readNamespaceCache(name); ok {
+		return cachedNamespace{provider: provider, found: true}, nil
+	}
+
+	provider, err := c.fetchNamespace(name)
+	if errors.Is(err, ErrNamespaceNotFound) {
+		return cachedNamespace{}, nil
+	}
+	if err != nil {
+		return cachedNamespace{}, fmt.Errorf("failed to fetch provider namespace %s: %w", name, err)
+	}
+
+	// The namespace cache only saves later runs a request, so failing to write it is not an error
+	if c.namespaceDir != "" {
+		_ = SaveFile(namespaceCacheFile(c.namespaceDir, name), map[string]models.ProviderOperationsResponse{name: provider})
+	}
+	return cachedNamespace{provider: provider, found: true}, nil
+}
+
+// readNamespaceCache reads a namespace from the namespace cache directory unless it is
+// missing or older than NamespaceCacheMaxAge
+func (c *Catalog) readNamespaceCache(name string) (models.ProviderOperationsResponse, bool) {
+	if c.namespaceDir == "" {
+		return models.ProviderOperationsResponse{}, false
+	}
+
+	path := namespaceCacheFile(c.namespaceDir, name)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > NamespaceCacheMaxAge {
+		return models.ProviderOperationsResponse{}, false
+	}
+	providers, err := LoadFile(path)
+	if err != nil {
+		return models.ProviderOperationsResponse{}, false
+	}
+	return LookupNamespace(providers, name)
+}
+
+// namespaceCacheFile returns the cache file of a namespace; namespaces are case-insensitive
+func namespaceCacheFile(dir, name string) string {
+	return filepath.Join(dir, strings.ToLower(name)+".json")
+}
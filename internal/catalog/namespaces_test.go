@@ -0,0 +1,188 @@
+package catalog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// namespaceProvider returns a provider namespace with a single operation
+func namespaceProvider(name string) models.ProviderOperationsResponse {
+	return models.ProviderOperationsResponse{
+		Namespace:  name,
+		Operations: []models.ProviderOperation{{Name: name + "/register/action"}},
+	}
+}
+
+func TestNamespacesFetchConcurrently(t *testing.T) {
+	release := make(chan struct{})
+	var slowFetches atomic.Int32
+
+	c := New(nil)
+	c.SetNamespaceFetch(func(namespace string) (models.ProviderOperationsResponse, error) {
+		if namespace == "Microsoft.Slow" {
+			slowFetches.Add(1)
+			<-release
+		}
+		return namespaceProvider(namespace), nil
+	})
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if providers, err := c.Namespaces("Microsoft.Slow"); err != nil || len(providers) != 1 {
+				t.Errorf("Namespaces(Microsoft.Slow) = %v, %v", providers, err)
+			}
+		}()
+	}
+
+	// A different namespace must not wait for the slow fetch
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if providers, err := c.Namespaces("Microsoft.Fast"); err != nil || len(providers) != 1 {
+			t.Errorf("Namespaces(Microsoft.Fast) = %v, %v", providers, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Namespaces(Microsoft.Fast) waited for the Microsoft.Slow fetch")
+	}
+
+	close(release)
+	wg.Wait()
+	if got := slowFetches.Load(); got != 1 {
+		t.Errorf("Microsoft.Slow was fetched %d times, want 1", got)
+	}
+}
+
+func TestNamespacesFetchInParallel(t *testing.T) {
+	names := []string{"Microsoft.Compute", "Microsoft.Network", "Microsoft.Storage", "Microsoft.Compute"}
+
+	// Every fetch waits until all distinct namespaces are being fetched at once
+	var started sync.WaitGroup
+	started.Add(3)
+	var fetches atomic.Int32
+	c := New(nil)
+	c.SetNamespaceFetch(func(namespace string) (models.ProviderOperationsResponse, error) {
+		fetches.Add(1)
+		started.Done()
+		started.Wait()
+		if namespace == "Microsoft.Storage" {
+			return models.ProviderOperationsResponse{}, ErrNamespaceNotFound
+		}
+		return namespaceProvider(namespace), nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		providers, err := c.Namespaces(names...)
+		if err != nil || len(providers) != 2 {
+			t.Errorf("Namespaces() = %v, %v, want Microsoft.Compute and Microsoft.Network", providers, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Namespaces() fetched the namespaces one at a time")
+	}
+	if got := fetches.Load(); got != 3 {
+		t.Errorf("fetched %d times, want 3", got)
+	}
+
+	// The error of any namespace fails the call
+	c = New(nil)
+	c.SetNamespaceFetch(func(namespace string) (models.ProviderOperationsResponse, error) {
+		if namespace == "Microsoft.Network" {
+			return models.ProviderOperationsResponse{}, errors.New("connection reset")
+		}
+		return namespaceProvider(namespace), nil
+	})
+	if _, err := c.Namespaces(names...); err == nil {
+		t.Error("Namespaces() returned no error")
+	}
+}
+
+func TestNamespacesCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		cached      string
+		age         time.Duration
+		fetchErr    error
+		wantFetched bool
+		wantFound   bool
+		wantCached  bool
+	}{
+		{name: "fetched and persisted", wantFetched: true, wantFound: true, wantCached: true},
+		{name: "fresh cache file", cached: "Microsoft.Compute", age: time.Hour, wantFound: true, wantCached: true},
+		{name: "stale cache file", cached: "Microsoft.Compute", age: 2 * NamespaceCacheMaxAge, wantFetched: true, wantFound: true, wantCached: true},
+		{name: "unknown namespace", fetchErr: ErrNamespaceNotFound, wantFetched: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "microsoft.compute.json")
+			if tt.cached != "" {
+				if err := SaveFile(path, map[string]models.ProviderOperationsResponse{tt.cached: namespaceProvider(tt.cached)}); err != nil {
+					t.Fatal(err)
+				}
+				modified := time.Now().Add(-tt.age)
+				if err := os.Chtimes(path, modified, modified); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fetched := false
+			c := New(nil)
+			c.SetNamespaceCache(dir)
+			c.SetNamespaceFetch(func(namespace string) (models.ProviderOperationsResponse, error) {
+				fetched = true
+				return namespaceProvider(namespace), tt.fetchErr
+			})
+
+			providers, err := c.Namespaces("microsoft.Compute")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fetched != tt.wantFetched {
+				t.Errorf("fetched = %v, want %v", fetched, tt.wantFetched)
+			}
+			if _, found := providers["microsoft.Compute"]; found != tt.wantFound {
+				t.Errorf("found = %v, want %v", found, tt.wantFound)
+			}
+			if _, err := os.Stat(path); (err == nil) != tt.wantCached {
+				t.Errorf("cache file exists = %v, want %v", err == nil, tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestNamespacesFetchError(t *testing.T) {
+	fetches := 0
+	c := New(nil)
+	c.SetNamespaceFetch(func(namespace string) (models.ProviderOperationsResponse, error) {
+		fetches++
+		return models.ProviderOperationsResponse{}, errors.New("connection reset")
+	})
+
+	// Errors are not cached, so the next lookup fetches again
+	for range 2 {
+		if _, err := c.Namespaces("Microsoft.Compute"); err == nil {
+			t.Fatal("Namespaces() returned no error")
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("fetched %d times, want 2", fetches)
+	}
+}
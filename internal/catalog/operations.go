@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// Operations flattens the catalog into a sorted list of operations
+func Operations(providers map[string]models.ProviderOperationsResponse) []models.OperationInfo {
+	var operations []models.OperationInfo
+
+	for namespace, provider := range providers {
+		for _, operation := range provider.Operations {
+			operations = append(operations, models.OperationInfo{
+				ProviderOperation: operation,
+				Provider:          namespace,
+			})
+		}
+		for _, resourceType := range provider.ResourceTypes {
+			for _, operation := range resourceType.Operations {
+				operations = append(operations, models.OperationInfo{
+					ProviderOperation: operation,
+					Provider:          namespace,
+					ResourceType:      resourceType.Name,
+				})
+			}
+		}
+	}
+
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].Name < operations[j].Name
+	})
+
+	return operations
+}
+
+// findOperations returns the given operations whose name, display name or description
+// contains the query. A limit of zero or less returns all matches.
+func findOperations(operations []models.OperationInfo, query string, limit int) []models.OperationInfo {
+	query = strings.ToLower(strings.TrimSpace(query))
+	matches := []models.OperationInfo{}
+
+	for _, operation := range operations {
+		if query != "" &&
+			!strings.Contains(strings.ToLower(operation.Name), query) &&
+			!strings.Contains(strings.ToLower(operation.DisplayName), query) &&
+			!strings.Contains(strings.ToLower(operation.Description), query) {
+			continue
+		}
+
+		matches = append(matches, operation)
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+	}
+
+	return matches
+}
+
+// LookupOperation finds an operation by name, ignoring case
+func LookupOperation(providers map[string]models.ProviderOperationsResponse, name string) (models.OperationInfo, bool) {
+	namespace, _, _ := strings.Cut(name, "/")
+
+	for providerName, provider := range providers {
+		if !strings.EqualFold(providerName, namespace) {
+			continue
+		}
+
+		for _, operation := range provider.Operations {
+			if strings.EqualFold(operation.Name, name) {
+				return models.OperationInfo{ProviderOperation: operation, Provider: providerName}, true
+			}
+		}
+		for _, resourceType := range provider.ResourceTypes {
+			for _, operation := range resourceType.Operations {
+				if strings.EqualFold(operation.Name, name) {
+					return models.OperationInfo{ProviderOperation: operation, Provider: providerName, ResourceType: resourceType.Name}, true
+				}
+			}
+		}
+	}
+
+	return models.OperationInfo{}, false
+}
@@ -0,0 +1,135 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// SearchOptions filters the operations considered by Search
+type SearchOptions struct {
+	// Provider restricts results to a namespace (e.g. "Microsoft.Compute"), ignoring case
+	Provider string
+	// ResourceType restricts results to resource types containing this value, ignoring case
+	ResourceType string
+	// DataActionsOnly restricts results to data plane operations
+	DataActionsOnly bool
+	// Origin restricts results to an origin such as "user" or "system", ignoring case
+	Origin string
+	// Limit caps the number of results; zero or less returns all matches
+	Limit int
+}
+
+// Scores awarded per search term, depending on where the term matches
+const (
+	scoreNameExact      = 100
+	scoreNameSegment    = 40
+	scoreNameContains   = 20
+	scoreDisplayName    = 10
+	scoreDescription    = 5
+	scoreDisplayNameAll = 15
+)
+
+// Search ranks the operations whose name, display name or description contain every search term.
+// Matches in the operation name rank above matches in the display name and description.
+// An empty query returns all operations that pass the filters, sorted by name.
+func Search(providers map[string]models.ProviderOperationsResponse, query string, options SearchOptions) []models.OperationMatch {
+	return searchOperations(Operations(providers), query, options)
+}
+
+// searchOperations ranks the given operations, which must be sorted by name
+func searchOperations(operations []models.OperationInfo, query string, options SearchOptions) []models.OperationMatch {
+	terms := strings.Fields(strings.ToLower(query))
+	matches := []models.OperationMatch{}
+
+	for _, operation := range operations {
+		if !matchesFilters(operation, options) {
+			continue
+		}
+
+		score, matched := scoreOperation(operation, terms)
+		if !matched {
+			continue
+		}
+		matches = append(matches, models.OperationMatch{OperationInfo: operation, Score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return strings.ToLower(matches[i].Name) < strings.ToLower(matches[j].Name)
+	})
+
+	if options.Limit > 0 && len(matches) > options.Limit {
+		matches = matches[:options.Limit]
+	}
+	return matches
+}
+
+// matchesFilters reports whether an operation passes the search filters
+func matchesFilters(operation models.OperationInfo, options SearchOptions) bool {
+	if options.Provider != "" && !strings.EqualFold(operation.Provider, options.Provider) {
+		return false
+	}
+	if options.ResourceType != "" && !strings.Contains(strings.ToLower(operation.ResourceType), strings.ToLower(options.ResourceType)) {
+		return false
+	}
+	if options.DataActionsOnly && !operation.IsDataAction {
+		return false
+	}
+	if options.Origin != "" && !strings.Contains(strings.ToLower(operation.Origin), strings.ToLower(options.Origin)) {
+		return false
+	}
+	return true
+}
+
+// scoreOperation scores an operation against the search terms. Every term must match somewhere.
+func scoreOperation(operation models.OperationInfo, terms []string) (int, bool) {
+	name := strings.ToLower(operation.Name)
+	displayName := strings.ToLower(operation.DisplayName)
+	description := strings.ToLower(operation.Description)
+	segments := strings.Split(name, "/")
+
+	score := 0
+	for _, term := range terms {
+		termScore := 0
+		switch {
+		case name == term:
+			termScore = scoreNameExact
+		case containsSegment(segments, term):
+			termScore = scoreNameSegment
+		case strings.Contains(name, term):
+			termScore = scoreNameContains
+		}
+		if strings.Contains(displayName, term) {
+			termScore += scoreDisplayName
+		}
+		if strings.Contains(description, term) {
+			termScore += scoreDescription
+		}
+
+		if termScore == 0 {
+			return 0, false
+		}
+		score += termScore
+	}
+
+	// Favour operations whose display name contains the whole query as a phrase
+	if len(terms) > 1 && strings.Contains(displayName, strings.Join(terms, " ")) {
+		score += scoreDisplayNameAll
+	}
+
+	return score, true
+}
+
+// containsSegment reports whether a term equals one of the segments of an operation name
+func containsSegment(segments []string, term string) bool {
+	for _, segment := range segments {
+		if segment == term {
+			return true
+		}
+	}
+	return false
+}
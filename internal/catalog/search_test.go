@@ -0,0 +1,114 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+)
+
+func TestSearchRanking(t *testing.T) {
+	providers := catalogtest.Realistic()
+	store := NewStore(providers)
+
+	// match is an expected result: operation name and score
+	type match struct {
+		name  string
+		score int
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		options SearchOptions
+		want    []match
+	}{
+		{
+			name:    "exact name",
+			query:   "Microsoft.Web/sites/read",
+			options: SearchOptions{Limit: 4},
+			want:    []match{{"Microsoft.Web/sites/read", scoreNameExact}},
+		},
+		{
+			// "start" is a segment of start/action but only part of restart/action
+			name:    "segment above substring",
+			query:   "start",
+			options: SearchOptions{Provider: "microsoft.compute", ResourceType: "virtualMachines", Limit: 6},
+			want: []match{
+				{"Microsoft.Compute/virtualMachines/extensions/start/action", 55},
+				{"Microsoft.Compute/virtualMachines/instanceView/start/action", 55},
+				{"Microsoft.Compute/virtualMachines/start/action", 55},
+				{"Microsoft.Compute/virtualMachineScaleSets/extensions/start/action", 55},
+				{"Microsoft.Compute/virtualMachineScaleSets/start/action", 55},
+				{"Microsoft.Compute/virtualMachines/extensions/restart/action", 35},
+			},
+		},
+		{
+			name:    "every term",
+			query:   "list keys",
+			options: SearchOptions{Provider: "Microsoft.Storage", Limit: 2},
+			want: []match{
+				{"Microsoft.Storage/storageAccounts/blobServices/containers/extensions/listKeys/action", 70},
+				{"Microsoft.Storage/storageAccounts/blobServices/containers/listKeys/action", 70},
+			},
+		},
+		{
+			name:    "display name phrase",
+			query:   "lists the",
+			options: SearchOptions{Provider: "Microsoft.ManagedIdentity", ResourceType: "userAssignedIdentities"},
+			want: []match{
+				{"Microsoft.ManagedIdentity/userAssignedIdentities/extensions/listKeys/action", 2*(scoreDisplayName+scoreDescription) + scoreDisplayNameAll},
+				{"Microsoft.ManagedIdentity/userAssignedIdentities/listKeys/action", 2*(scoreDisplayName+scoreDescription) + scoreDisplayNameAll},
+			},
+		},
+		{
+			name:    "data actions",
+			query:   "read",
+			options: SearchOptions{DataActionsOnly: true, Provider: "Microsoft.KeyVault"},
+			want: []match{
+				{"Microsoft.KeyVault/vaults/certificates/read", scoreNameSegment},
+				{"Microsoft.KeyVault/vaults/keys/read", scoreNameSegment},
+				{"Microsoft.KeyVault/vaults/secrets/read", scoreNameSegment},
+			},
+		},
+		{
+			name:    "empty query sorted by name",
+			options: SearchOptions{Provider: "Microsoft.ManagedIdentity", ResourceType: "userAssignedIdentities/extensions", Limit: 3},
+			want: []match{
+				{"Microsoft.ManagedIdentity/userAssignedIdentities/extensions/delete", 0},
+				{"Microsoft.ManagedIdentity/userAssignedIdentities/extensions/listKeys/action", 0},
+				{"Microsoft.ManagedIdentity/userAssignedIdentities/extensions/read", 0},
+			},
+		},
+		{
+			name:    "term without a match",
+			query:   "start nonexistent",
+			options: SearchOptions{Provider: "Microsoft.Compute"},
+			want:    []match{},
+		},
+		{
+			name:    "unknown provider",
+			query:   "read",
+			options: SearchOptions{Provider: "Microsoft.Missing"},
+			want:    []match{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := store.Search(tt.query, tt.options)
+			got := []match{}
+			for _, result := range results {
+				got = append(got, match{result.Name, result.Score})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+
+			// Searching the catalog ranks like the store
+			if catalogResults := Search(providers, tt.query, tt.options); !reflect.DeepEqual(catalogResults, results) {
+				t.Errorf("Search() on the catalog = %v, want %v", catalogResults, results)
+			}
+		})
+	}
+}
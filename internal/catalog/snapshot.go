@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// DefaultCachePath returns the location of the cached provider operations catalog
+func DefaultCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "azperm", "provider-operations.json"), nil
+}
+
+// LoadFile reads a provider operations snapshot. It accepts the ARM list response
+// ({"value": [...]}), the array printed by 'az provider operation list' and the compact
+// form written by SaveCompactFile. JSON snapshots are decoded one provider at a time.
+func LoadFile(path string) (map[string]models.ProviderOperationsResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog snapshot: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	if isCompact(reader) {
+		providers, err := ReadCompact(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse catalog snapshot %s: %w", path, err)
+		}
+		return providers, nil
+	}
+
+	providers := make(map[string]models.ProviderOperationsResponse)
+	err = DecodeProviders(reader, func(provider models.ProviderOperationsResponse) {
+		providers[Namespace(provider)] = provider
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse catalog snapshot %s: %w", path, err)
+	}
+	return providers, nil
+}
+
+// DecodeProviders stream-decodes a provider list, either an array or an object with a
+// "value" array, and passes each provider to add as soon as it is decoded
+func DecodeProviders(r io.Reader, add func(models.ProviderOperationsResponse)) error {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('['):
+		return decodeProviderArray(decoder, add)
+	case json.Delim('{'):
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if key != "value" {
+				var skipped json.RawMessage
+				if err := decoder.Decode(&skipped); err != nil {
+					return err
+				}
+				continue
+			}
+			if token, err := decoder.Token(); err != nil {
+				return err
+			} else if token != json.Delim('[') {
+				return fmt.Errorf("expected \"value\" to be an array")
+			}
+			if err := decodeProviderArray(decoder, add); err != nil {
+				return err
+			}
+		}
+		_, err := decoder.Token()
+		return err
+	default:
+		return fmt.Errorf("expected a JSON array or object")
+	}
+}
+
+// decodeProviderArray decodes the elements of an array whose opening bracket was read
+func decodeProviderArray(decoder *json.Decoder, add func(models.ProviderOperationsResponse)) error {
+	for decoder.More() {
+		var provider models.ProviderOperationsResponse
+		if err := decoder.Decode(&provider); err != nil {
+			return err
+		}
+		add(provider)
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// SaveFile writes a provider operations snapshot in the ARM list response format
+func SaveFile(path string, providers map[string]models.ProviderOperationsResponse) error {
+	list := struct {
+		Value []models.ProviderOperationsResponse `json:"value"`
+	}{Value: sortedProviders(providers)}
+
+	content, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog snapshot: %w", err)
+	}
+	return writeSnapshot(path, content)
+}
+
+// sortedProviders lists the providers of a catalog sorted by namespace
+func sortedProviders(providers map[string]models.ProviderOperationsResponse) []models.ProviderOperationsResponse {
+	namespaces := make([]string, 0, len(providers))
+	for namespace := range providers {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	list := make([]models.ProviderOperationsResponse, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		list = append(list, providers[namespace])
+	}
+	return list
+}
+
+// writeSnapshot writes a snapshot atomically so a concurrent reader never sees a partial one
+func writeSnapshot(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write catalog snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write catalog snapshot: %w", err)
+	}
+	return nil
+}
+
+// FromList converts a list of providers into a catalog keyed by namespace
+func FromList(providers []models.ProviderOperationsResponse) map[string]models.ProviderOperationsResponse {
+	result := make(map[string]models.ProviderOperationsResponse, len(providers))
+	for _, provider := range providers {
+		result[Namespace(provider)] = provider
+	}
+	return result
+}
+
+// Namespace extracts the namespace from a provider's ID
+// (e.g. "Microsoft.Compute" from "/providers/Microsoft.Authorization/providerOperations/Microsoft.Compute")
+func Namespace(provider models.ProviderOperationsResponse) string {
+	namespace := provider.Namespace
+	if index := strings.LastIndex(namespace, "/"); index >= 0 {
+		namespace = namespace[index+1:]
+	}
+	return namespace
+}
@@ -0,0 +1,71 @@
+package catalog
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// saveFunc writes a catalog snapshot
+type saveFunc func(path string, providers map[string]models.ProviderOperationsResponse) error
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	providers := catalogtest.Realistic()
+
+	tests := []struct {
+		name string
+		save saveFunc
+	}{
+		{"json", SaveFile},
+		{"compact", SaveCompactFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "provider-operations.json")
+			if err := tt.save(path, providers); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(Operations(loaded), Operations(providers)) {
+				t.Error("LoadFile() returned different operations than were saved")
+			}
+		})
+	}
+}
+
+// writeRealisticSnapshot saves the realistic test catalog and returns the snapshot path
+func writeRealisticSnapshot(b *testing.B, save saveFunc) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "provider-operations.json")
+	if err := save(path, catalogtest.Realistic()); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkLoadFile(b *testing.B) {
+	path := writeRealisticSnapshot(b, SaveFile)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := LoadFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadCompact(b *testing.B) {
+	path := writeRealisticSnapshot(b, SaveCompactFile)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := LoadFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// Store holds the operations of a catalog flattened and indexed by name, namespace, resource
+// type path segment and verb, so lookups don't scan the whole catalog. It is read-only once built.
+type Store struct {
+	providers  map[string]models.ProviderOperationsResponse
+	operations []models.OperationInfo
+
+	byName      map[string]int
+	byNamespace map[string][]int
+	bySegment   map[string][]int
+	byVerb      map[string][]int
+}
+
+// StoreQuery selects operations by the indexed parts of their name. Empty fields match
+// everything and matching ignores case.
+type StoreQuery struct {
+	// Namespace is the provider namespace, e.g. Microsoft.Compute
+	Namespace string
+	// Segment is a resource type path segment, e.g. virtualMachines
+	Segment string
+	// Verb is the last segment of the operation name (read, write, delete or action), or
+	// the name of an action such as start
+	Verb string
+}
+
+// NewStore flattens and indexes a catalog
+func NewStore(providers map[string]models.ProviderOperationsResponse) *Store {
+	store := &Store{
+		providers:   providers,
+		operations:  Operations(providers),
+		byName:      make(map[string]int),
+		byNamespace: make(map[string][]int),
+		bySegment:   make(map[string][]int),
+		byVerb:      make(map[string][]int),
+	}
+
+	for i, operation := range store.operations {
+		name := strings.ToLower(operation.Name)
+		namespace := strings.ToLower(operation.Provider)
+		store.byName[name] = i
+		store.byNamespace[namespace] = append(store.byNamespace[namespace], i)
+
+		segments := strings.Split(name, "/")
+		if len(segments) < 2 {
+			continue
+		}
+		verb := segments[len(segments)-1]
+		middle := segments[1 : len(segments)-1]
+		store.byVerb[verb] = append(store.byVerb[verb], i)
+		if verb == "action" && len(middle) > 0 {
+			action := middle[len(middle)-1]
+			store.byVerb[action] = append(store.byVerb[action], i)
+			middle = middle[:len(middle)-1]
+		}
+		for _, segment := range uniqueSegments(middle) {
+			store.bySegment[segment] = append(store.bySegment[segment], i)
+		}
+	}
+	return store
+}
+
+// uniqueSegments drops repeated segments so an operation is indexed once per segment
+func uniqueSegments(segments []string) []string {
+	unique := segments[:0:0]
+	for _, segment := range segments {
+		if !containsSegment(unique, segment) {
+			unique = append(unique, segment)
+		}
+	}
+	return unique
+}
+
+// Len returns the number of operations in the store
+func (s *Store) Len() int {
+	return len(s.operations)
+}
+
+// Providers returns the catalog the store was built from
+func (s *Store) Providers() map[string]models.ProviderOperationsResponse {
+	return s.providers
+}
+
+// Operations returns every operation sorted by name. The slice must not be modified.
+func (s *Store) Operations() []models.OperationInfo {
+	return s.operations
+}
+
+// Lookup finds an operation by name, ignoring case
+func (s *Store) Lookup(name string) (models.OperationInfo, bool) {
+	index, ok := s.byName[strings.ToLower(name)]
+	if !ok {
+		return models.OperationInfo{}, false
+	}
+	return s.operations[index], true
+}
+
+// Query returns the operations matching every field of the query, sorted by name
+func (s *Store) Query(query StoreQuery) []models.OperationInfo {
+	var lists [][]int
+	if query.Namespace != "" {
+		lists = append(lists, s.byNamespace[strings.ToLower(query.Namespace)])
+	}
+	if query.Segment != "" {
+		lists = append(lists, s.bySegment[strings.ToLower(query.Segment)])
+	}
+	if query.Verb != "" {
+		lists = append(lists, s.byVerb[strings.ToLower(query.Verb)])
+	}
+	if len(lists) == 0 {
+		return s.operations
+	}
+
+	// Intersect starting from the shortest posting list; all lists are in ascending order
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	indexes := lists[0]
+	for _, list := range lists[1:] {
+		indexes = intersect(indexes, list)
+	}
+
+	operations := make([]models.OperationInfo, 0, len(indexes))
+	for _, index := range indexes {
+		operations = append(operations, s.operations[index])
+	}
+	return operations
+}
+
+// Search ranks operations like Search, using the namespace index when a provider filter is set
+func (s *Store) Search(query string, options SearchOptions) []models.OperationMatch {
+	operations := s.operations
+	if options.Provider != "" {
+		operations = s.Query(StoreQuery{Namespace: options.Provider})
+	}
+	return searchOperations(operations, query, options)
+}
+
+// Find returns the operations whose name, display name or description contains the query,
+// in name order. A limit of zero or less returns all matches.
+func (s *Store) Find(query string, limit int) []models.OperationInfo {
+	return findOperations(s.operations, query, limit)
+}
+
+// intersect returns the values present in both ascending lists
+func intersect(a, b []int) []int {
+	result := make([]int, 0, min(len(a), len(b)))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+)
+
+func TestStoreQuery(t *testing.T) {
+	store := NewStore(catalogtest.Realistic())
+
+	tests := []struct {
+		name  string
+		query StoreQuery
+		want  []string
+	}{
+		{
+			name:  "namespace, segment and verb",
+			query: StoreQuery{Namespace: "microsoft.compute", Segment: "VirtualMachines", Verb: "read"},
+			want: []string{
+				"Microsoft.Compute/virtualMachines/extensions/read",
+				"Microsoft.Compute/virtualMachines/instanceView/read",
+				"Microsoft.Compute/virtualMachines/read",
+			},
+		},
+		{
+			name:  "action name",
+			query: StoreQuery{Namespace: "Microsoft.Web", Segment: "sites", Verb: "restart"},
+			want: []string{
+				"Microsoft.Web/sites/extensions/restart/action",
+				"Microsoft.Web/sites/restart/action",
+			},
+		},
+		{
+			name:  "data plane type",
+			query: StoreQuery{Segment: "blobs"},
+			want: []string{
+				"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/delete",
+				"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read",
+				"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/write",
+			},
+		},
+		{
+			name:  "unknown namespace",
+			query: StoreQuery{Namespace: "Microsoft.Missing", Verb: "read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations := store.Query(tt.query)
+			if len(operations) != len(tt.want) {
+				t.Fatalf("Query() returned %d operations, want %d: %v", len(operations), len(tt.want), operations)
+			}
+			for i, operation := range operations {
+				if operation.Name != tt.want[i] {
+					t.Errorf("operation %d = %s, want %s", i, operation.Name, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if operation, ok := store.Lookup("microsoft.storage/storageaccounts/listkeys/action"); !ok || operation.ResourceType != "storageAccounts" {
+		t.Errorf("Lookup() = %+v, %v", operation, ok)
+	}
+}
+
+func BenchmarkStoreFind(b *testing.B) {
+	store := NewStore(catalogtest.Realistic())
+	b.ReportAllocs()
+	for b.Loop() {
+		if len(store.Find("virtualMachines/start", 0)) == 0 {
+			b.Fatal("Find() found nothing")
+		}
+	}
+}
@@ -0,0 +1,541 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setting keys
+const (
+	KeyCloud              = "cloud"
+	KeyTenant             = "tenant"
+	KeySubscription       = "subscription"
+	KeyOutput             = "output"
+	KeyCatalog            = "catalog"
+	KeyOffline            = "offline"
+	KeyMappings           = "mappings"
+	KeyPolicy             = "policy"
+	KeyAPIVersion         = "api-version"
+	KeyManagementEndpoint = "management-endpoint"
+	KeyTimeout            = "timeout"
+	KeyRetries            = "retries"
+	KeyProxy              = "proxy"
+	KeyCAFile             = "ca-file"
+	KeyClientCert         = "client-cert"
+	KeyClientKey          = "client-key"
+	KeyProviders          = "providers"
+)
+
+// Layer names, from lowest to highest precedence
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerUser    = "user"
+	LayerRepo    = "repo"
+	LayerProfile = "profile"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+)
+
+// RepoFile is the name of the repository config file
+const RepoFile = ".azperm.yaml"
+
+// profileEnv selects the profile, like --profile
+const profileEnv = "AZPERM_PROFILE"
+
+// Key describes a setting
+type Key struct {
+	Name        string
+	Env         string
+	Description string
+	// Path settings in config files are relative to the file
+	Path     bool
+	Bool     bool
+	Duration bool
+	Int      bool
+}
+
+// Keys lists the supported settings
+var Keys = []Key{
+	{Name: KeyCloud, Env: "AZPERM_CLOUD", Description: "Azure cloud profile (AzureCloud, AzureUSGovernment, AzureChinaCloud or user-defined)"},
+	{Name: KeyTenant, Env: "AZPERM_TENANT", Description: "Tenant to request access tokens for"},
+	{Name: KeySubscription, Env: "AZPERM_SUBSCRIPTION", Description: "Subscription used instead of the current az subscription"},
+	{Name: KeyOutput, Env: "AZPERM_OUTPUT", Description: "Output format: text, json, markdown, csv, html or sarif"},
+	{Name: KeyCatalog, Env: "AZPERM_CATALOG", Description: "Provider operations catalog snapshot file", Path: true},
+	{Name: KeyOffline, Env: "AZPERM_OFFLINE", Description: "Use the cached provider operations catalog instead of the live API", Bool: true},
+	{Name: KeyMappings, Env: "AZPERM_MAPPINGS", Description: "JSON file with command to permission mapping overrides", Path: true},
+	{Name: KeyPolicy, Env: "AZPERM_POLICY", Description: "Permission policy file", Path: true},
+	{Name: KeyAPIVersion, Env: "AZPERM_API_VERSION", Description: "Provider operations API version"},
+	{Name: KeyManagementEndpoint, Env: "AZPERM_MANAGEMENT_ENDPOINT", Description: "Resource Manager endpoint, overriding the cloud profile"},
+	{Name: KeyTimeout, Env: "AZPERM_TIMEOUT", Description: "Time limit of a single Azure API request, e.g. 90s", Duration: true},
+	{Name: KeyRetries, Env: "AZPERM_RETRIES", Description: "Retries of throttled or failed Azure API requests", Int: true},
+	{Name: KeyProxy, Env: "AZPERM_PROXY", Description: "Proxy URL for Azure API requests, replacing HTTPS_PROXY"},
+	{Name: KeyCAFile, Env: "AZPERM_CA_FILE", Description: "PEM bundle of additional trusted CAs (default: REQUESTS_CA_BUNDLE)", Path: true},
+	{Name: KeyClientCert, Env: "AZPERM_CLIENT_CERT", Description: "PEM client certificate for mutual TLS", Path: true},
+	{Name: KeyClientKey, Env: "AZPERM_CLIENT_KEY", Description: "PEM key of the client certificate, if not in the certificate file", Path: true},
+	{Name: KeyProviders, Env: "AZPERM_PROVIDERS", Description: "Resource providers file (az provider list output) used to match unknown services", Path: true},
+}
+
+// defaults are the values used when no layer sets a key
+var defaults = map[string]string{
+	KeyOutput:  "text",
+	KeyOffline: "false",
+}
+
+// LookupKey returns the description of a setting
+func LookupKey(name string) (Key, bool) {
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Layer is a source of settings
+type Layer struct {
+	Name   string            `json:"name"`
+	Path   string            `json:"path,omitempty"`
+	Values map[string]string `json:"values"`
+}
+
+// file is a parsed config file
+type file struct {
+	values   map[string]string
+	profile  string
+	profiles map[string]map[string]string
+}
+
+// Config holds the settings of every layer and resolves them by precedence
+type Config struct {
+	layers        []Layer
+	profile       string
+	profileSource string
+	profiles      map[string][]Layer
+}
+
+// Value is the effective value of a setting and the layer it comes from
+type Value struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Path   string `json:"path,omitempty"`
+}
+
+// Explanation lists every layer that sets a key, from highest to lowest precedence.
+// The first candidate is the effective value.
+type Explanation struct {
+	Key        string  `json:"key"`
+	Env        string  `json:"env"`
+	Candidates []Value `json:"candidates"`
+}
+
+// Profile is a named profile and the config files defining it
+type Profile struct {
+	Name   string            `json:"name"`
+	Active bool              `json:"active"`
+	Files  []string          `json:"files"`
+	Values map[string]string `json:"values"`
+}
+
+// SystemPath returns the system-wide config file
+func SystemPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "azperm", "config.yaml")
+	}
+	return "/etc/azperm/config.yaml"
+}
+
+// UserPath returns the config file of the current user
+func UserPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "azperm", "config.yaml"), nil
+}
+
+// RepoPath returns the repository config file: the closest .azperm.yaml between the working
+// directory and the repository root, or .azperm.yaml in the working directory if there is none
+func RepoPath() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return RepoFile
+	}
+
+	for current := dir; ; {
+		candidate := filepath.Join(current, RepoFile)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+	return filepath.Join(dir, RepoFile)
+}
+
+// Load reads the system, user and repository config files and the environment. flags holds
+// the settings given on the command line, including "profile" to select a profile.
+func Load(flags map[string]string) (*Config, error) {
+	config := &Config{profiles: make(map[string][]Layer)}
+	config.layers = append(config.layers, Layer{Name: LayerDefault, Values: defaults})
+
+	paths := []struct{ name, path string }{{LayerSystem, SystemPath()}}
+	if userPath, err := UserPath(); err == nil {
+		paths = append(paths, struct{ name, path string }{LayerUser, userPath})
+	}
+	paths = append(paths, struct{ name, path string }{LayerRepo, RepoPath()})
+
+	for _, source := range paths {
+		parsed, err := readFile(source.path)
+		if err != nil {
+			return nil, err
+		}
+		if parsed == nil {
+			continue
+		}
+		config.layers = append(config.layers, Layer{Name: source.name, Path: source.path, Values: parsed.values})
+
+		if parsed.profile != "" {
+			config.profile, config.profileSource = parsed.profile, source.name
+		}
+		for name, values := range parsed.profiles {
+			config.profiles[name] = append(config.profiles[name], Layer{Name: LayerProfile + ":" + name, Path: source.path, Values: values})
+		}
+	}
+
+	if profile := os.Getenv(profileEnv); profile != "" {
+		config.profile, config.profileSource = profile, LayerEnv
+	}
+	if profile := flags["profile"]; profile != "" {
+		config.profile, config.profileSource = profile, LayerFlag
+	}
+	if config.profile != "" {
+		layers, ok := config.profiles[config.profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile: %s (selected by %s)", config.profile, config.profileSource)
+		}
+		config.layers = append(config.layers, layers...)
+	}
+
+	env := make(map[string]string)
+	for _, key := range Keys {
+		if value := os.Getenv(key.Env); value != "" {
+			env[key.Name] = value
+		}
+	}
+	config.layers = append(config.layers, Layer{Name: LayerEnv, Values: env})
+
+	flagValues := make(map[string]string)
+	for name, value := range flags {
+		if name != "profile" {
+			flagValues[name] = value
+		}
+	}
+	config.layers = append(config.layers, Layer{Name: LayerFlag, Values: flagValues})
+
+	for _, layer := range config.layers {
+		if err := validate(layer.Values); err != nil {
+			return nil, fmt.Errorf("%s: %w", layerLabel(layer), err)
+		}
+	}
+	return config, nil
+}
+
+// readFile parses a config file, or returns nil if it does not exist. Values are read as
+// the raw scalars so versions such as 2022-04-01 are not interpreted as dates.
+func readFile(path string) (*file, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	parsed := &file{values: make(map[string]string), profiles: make(map[string]map[string]string)}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if len(document.Content) == 0 {
+		return parsed, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config %s is not a YAML mapping", path)
+	}
+
+	dir := filepath.Dir(path)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		name, value := root.Content[i].Value, root.Content[i+1]
+		switch name {
+		case "profile":
+			parsed.profile = value.Value
+		case "profiles":
+			if value.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("%s: profiles must be a mapping of profile names to settings", path)
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				profileName := value.Content[j].Value
+				values, err := settingValues(value.Content[j+1], dir)
+				if err != nil {
+					return nil, fmt.Errorf("%s: profile %s: %w", path, profileName, err)
+				}
+				parsed.profiles[profileName] = values
+			}
+		default:
+			if err := setValue(parsed.values, name, value, dir); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// settingValues reads a mapping of settings
+func settingValues(node *yaml.Node, dir string) (map[string]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of settings")
+	}
+
+	values := make(map[string]string)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if err := setValue(values, node.Content[i].Value, node.Content[i+1], dir); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// setValue stores a scalar setting, resolving paths relative to dir
+func setValue(values map[string]string, name string, node *yaml.Node, dir string) error {
+	key, ok := LookupKey(name)
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", name)
+	}
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("%s must be a single value", name)
+	}
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	value := node.Value
+	if key.Path && value != "" && !filepath.IsAbs(value) {
+		value = filepath.Join(dir, value)
+	}
+	values[name] = value
+	return nil
+}
+
+// validate checks the values of a layer
+func validate(values map[string]string) error {
+	for name, value := range values {
+		if err := ValidateValue(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateValue checks that a setting is known and its value is valid
+func ValidateValue(name, value string) error {
+	key, ok := LookupKey(name)
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", name)
+	}
+	if key.Bool {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", name, value)
+		}
+	}
+	if key.Duration {
+		if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+			return fmt.Errorf("%s must be a positive duration such as 30s or 2m, got %q", name, value)
+		}
+	}
+	if key.Int {
+		if number, err := strconv.Atoi(value); err != nil || number < 0 {
+			return fmt.Errorf("%s must be a non-negative number, got %q", name, value)
+		}
+	}
+	return nil
+}
+
+// layerLabel describes a layer for messages, e.g. "repo (/src/.azperm.yaml)"
+func layerLabel(layer Layer) string {
+	if layer.Path != "" {
+		return fmt.Sprintf("%s (%s)", layer.Name, layer.Path)
+	}
+	return layer.Name
+}
+
+// Get returns the effective value of a setting and the layer it comes from
+func (c *Config) Get(name string) Value {
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		if value, ok := c.layers[i].Values[name]; ok {
+			return Value{Key: name, Value: value, Source: c.layers[i].Name, Path: c.layers[i].Path}
+		}
+	}
+	return Value{Key: name}
+}
+
+// String returns the effective value of a setting, or ""
+func (c *Config) String(name string) string {
+	return c.Get(name).Value
+}
+
+// Bool returns the effective value of a boolean setting
+func (c *Config) Bool(name string) bool {
+	value, _ := strconv.ParseBool(c.Get(name).Value)
+	return value
+}
+
+// Duration returns the effective value of a duration setting, or 0
+func (c *Config) Duration(name string) time.Duration {
+	value, _ := time.ParseDuration(c.Get(name).Value)
+	return value
+}
+
+// Int returns the effective value of a numeric setting and whether it is set
+func (c *Config) Int(name string) (int, bool) {
+	value, err := strconv.Atoi(c.Get(name).Value)
+	return value, err == nil
+}
+
+// List returns the effective value of every setting that is set
+func (c *Config) List() []Value {
+	var values []Value
+	for _, key := range Keys {
+		if value := c.Get(key.Name); value.Source != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Explain lists the layers that set a setting, from highest to lowest precedence
+func (c *Config) Explain(name string) Explanation {
+	key, _ := LookupKey(name)
+	explanation := Explanation{Key: name, Env: key.Env, Candidates: []Value{}}
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		if value, ok := c.layers[i].Values[name]; ok {
+			explanation.Candidates = append(explanation.Candidates, Value{Key: name, Value: value, Source: c.layers[i].Name, Path: c.layers[i].Path})
+		}
+	}
+	return explanation
+}
+
+// Precedence returns the names of the layers in effect, from lowest to highest precedence
+func (c *Config) Precedence() []string {
+	var names []string
+	for _, layer := range c.layers {
+		names = append(names, layer.Name)
+	}
+	return names
+}
+
+// ActiveProfile returns the selected profile and the layer that selected it
+func (c *Config) ActiveProfile() (string, string) {
+	return c.profile, c.profileSource
+}
+
+// Profiles returns the profiles defined in the config files, sorted by name. Settings of
+// files with higher precedence override those of lower ones.
+func (c *Config) Profiles() []Profile {
+	profiles := []Profile{}
+	for name, layers := range c.profiles {
+		profile := Profile{Name: name, Active: name == c.profile, Values: make(map[string]string)}
+		for _, layer := range layers {
+			profile.Files = append(profile.Files, layer.Path)
+			for key, value := range layer.Values {
+				profile.Values[key] = value
+			}
+		}
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// Set writes a setting to a config file, inside the named profile when profile is not empty.
+// Comments and other settings in the file are preserved.
+func Set(path, profile, name, value string) error {
+	if err := ValidateValue(name, value); err != nil {
+		return err
+	}
+
+	var document yaml.Node
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(strings.TrimSpace(string(content))) > 0 {
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+	if len(document.Content) == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s is not a YAML mapping", path)
+	}
+	if profile != "" {
+		root = mappingChild(mappingChild(root, "profiles"), profile)
+	}
+	setScalar(root, name, value)
+
+	out, err := yaml.Marshal(&document)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// mappingChild returns the mapping stored under key, creating it if needed
+func mappingChild(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.MappingNode {
+			return node.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child
+}
+
+// setScalar sets or adds a scalar value in a mapping
+func setScalar(node *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
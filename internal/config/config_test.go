@@ -0,0 +1,224 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupLayers isolates a test from the real config files and environment: the user config
+// directory and working directory are temporary, and every setting variable is cleared.
+// It returns the user and repository config file paths.
+func setupLayers(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := os.Stat(SystemPath()); err == nil {
+		t.Skipf("system config %s exists", SystemPath())
+	}
+
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("HOME", home)
+	t.Setenv("AppData", filepath.Join(home, "config"))
+	t.Setenv(profileEnv, "")
+	for _, key := range Keys {
+		t.Setenv(key.Env, "")
+	}
+
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(repo)
+
+	userPath, err := UserPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userPath, filepath.Join(repo, RepoFile)
+}
+
+// writeConfig writes a config file, creating its directory
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	userPath, repoPath := setupLayers(t)
+	writeConfig(t, userPath, `output: json
+cloud: AzureUSGovernment
+tenant: user-tenant
+subscription: user-subscription
+api-version: 2022-04-01
+profiles:
+  ci:
+    subscription: ci-subscription
+    retries: 5
+`)
+	writeConfig(t, repoPath, `cloud: AzureChinaCloud
+catalog: snapshots/catalog.json
+profile: ci
+profiles:
+  ci:
+    timeout: 45s
+`)
+	t.Setenv("AZPERM_TENANT", "env-tenant")
+	t.Setenv("AZPERM_RETRIES", "7")
+
+	config, err := Load(map[string]string{KeyRetries: "9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource string
+	}{
+		{KeyOffline, "false", LayerDefault},
+		{KeyOutput, "json", LayerUser},
+		{KeyAPIVersion, "2022-04-01", LayerUser},
+		{KeyCloud, "AzureChinaCloud", LayerRepo},
+		{KeyCatalog, filepath.Join(filepath.Dir(repoPath), "snapshots", "catalog.json"), LayerRepo},
+		{KeySubscription, "ci-subscription", LayerProfile + ":ci"},
+		{KeyTimeout, "45s", LayerProfile + ":ci"},
+		{KeyTenant, "env-tenant", LayerEnv},
+		{KeyRetries, "9", LayerFlag},
+		{KeyPolicy, "", ""},
+	}
+	for _, tt := range tests {
+		got := config.Get(tt.key)
+		if got.Value != tt.wantValue || got.Source != tt.wantSource {
+			t.Errorf("Get(%s) = %q from %q, want %q from %q", tt.key, got.Value, got.Source, tt.wantValue, tt.wantSource)
+		}
+	}
+
+	if profile, source := config.ActiveProfile(); profile != "ci" || source != LayerRepo {
+		t.Errorf("ActiveProfile() = %s, %s, want ci, %s", profile, source, LayerRepo)
+	}
+	wantPrecedence := []string{LayerDefault, LayerUser, LayerRepo, LayerProfile + ":ci", LayerProfile + ":ci", LayerEnv, LayerFlag}
+	if got := config.Precedence(); !reflect.DeepEqual(got, wantPrecedence) {
+		t.Errorf("Precedence() = %v, want %v", got, wantPrecedence)
+	}
+
+	explanation := config.Explain(KeyRetries)
+	var sources []string
+	for _, candidate := range explanation.Candidates {
+		sources = append(sources, candidate.Source)
+	}
+	if want := []string{LayerFlag, LayerEnv, LayerProfile + ":ci"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("Explain(retries) sources = %v, want %v", sources, want)
+	}
+	if timeout := config.Duration(KeyTimeout); timeout.Seconds() != 45 {
+		t.Errorf("Duration(timeout) = %v, want 45s", timeout)
+	}
+}
+
+func TestLoadProfileSelection(t *testing.T) {
+	userPath, _ := setupLayers(t)
+	writeConfig(t, userPath, `profile: dev
+profiles:
+  dev: {tenant: dev-tenant}
+  prod: {tenant: prod-tenant}
+  test: {tenant: test-tenant}
+`)
+
+	tests := []struct {
+		name       string
+		env        string
+		flags      map[string]string
+		wantTenant string
+		wantSource string
+	}{
+		{"config file", "", nil, "dev-tenant", LayerUser},
+		{"environment", "prod", nil, "prod-tenant", LayerEnv},
+		{"flag", "prod", map[string]string{"profile": "test"}, "test-tenant", LayerFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(profileEnv, tt.env)
+			config, err := Load(tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tenant := config.String(KeyTenant); tenant != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", tenant, tt.wantTenant)
+			}
+			if _, source := config.ActiveProfile(); source != tt.wantSource {
+				t.Errorf("profile selected by %q, want %q", source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		flags   map[string]string
+		wantErr string
+	}{
+		{"unknown setting", "colour: blue\n", nil, nil, "unknown setting: colour"},
+		{"not a mapping", "- output\n", nil, nil, "not a YAML mapping"},
+		{"nested value", "output: [json]\n", nil, nil, "must be a single value"},
+		{"invalid bool", "offline: maybe\n", nil, nil, "offline must be true or false"},
+		{"invalid duration in env", "", map[string]string{"AZPERM_TIMEOUT": "soon"}, nil, "timeout must be a positive duration"},
+		{"negative retries flag", "", nil, map[string]string{KeyRetries: "-1"}, "retries must be a non-negative number"},
+		{"unknown profile", "", nil, map[string]string{"profile": "missing"}, "unknown profile: missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userPath, _ := setupLayers(t)
+			if tt.content != "" {
+				writeConfig(t, userPath, tt.content)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			_, err := Load(tt.flags)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "# team defaults\noutput: text\n")
+
+	if err := Set(path, "", KeyOutput, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "ci", KeyRetries, "4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "", KeyOffline, "sometimes"); err == nil {
+		t.Error("Set() accepted an invalid boolean")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "# team defaults") {
+		t.Errorf("Set() dropped the comment:\n%s", content)
+	}
+
+	parsed, err := readFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.values[KeyOutput] != "json" || parsed.profiles["ci"][KeyRetries] != "4" {
+		t.Errorf("Set() wrote values %v and profiles %v", parsed.values, parsed.profiles)
+	}
+}
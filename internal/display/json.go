@@ -0,0 +1,14 @@
+package display
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// WriteJSON writes a value as indented JSON
+func WriteJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
@@ -2,11 +2,14 @@ package display
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+	"github.com/mathwro/azperm/internal/policy"
 )
 
 // Colors holds the color configurations for different output types
@@ -79,8 +82,8 @@ func (c *Colors) DisplayPermissions(cmd *models.AzureCommand, permissions []stri
 	fmt.Println()
 }
 
-// DisplayPermissionsWithLiveQuery shows permissions with live query indication
-func (c *Colors) DisplayPermissionsWithLiveQuery(cmd *models.AzureCommand, permissions []string) {
+// DisplayPermissionsWithLiveQuery shows permissions with live query indication and their risk
+func (c *Colors) DisplayPermissionsWithLiveQuery(cmd *models.AzureCommand, details []models.PermissionDetail, confidence models.ConfidenceLevel) {
 	// Header  
 	c.Header.Printf("🔍 Command: %s\n", cmd.FullCmd)
 
@@ -100,20 +103,529 @@ func (c *Colors) DisplayPermissionsWithLiveQuery(cmd *models.AzureCommand, permi
 	fmt.Println()
 	
 	// Always show as live queried
-	c.Success.Println("🔐 Required RBAC Permissions:")
+	if confidence == models.ConfidenceMedium {
+		c.Info.Println("🔐 Required RBAC Permissions (Medium Confidence - Provider Discovered):")
+	} else {
+		c.Success.Println("🔐 Required RBAC Permissions:")
+	}
 
 	// Sort permissions for consistent output
-	sort.Strings(permissions)
+	sorted := append([]models.PermissionDetail{}, details...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
 
-	for _, permission := range permissions {
-		fmt.Printf("  • %s\n", permission)
+	for _, permission := range sorted {
+		risk := permissions.RiskOf(permission)
+		fmt.Printf("  • %s %s\n", permission.Name, c.riskBadge(risk))
+		if risk.Level == models.RiskHigh || risk.Level == models.RiskCritical {
+			fmt.Printf("      ↳ %s\n", risk.Rationale)
+		}
 	}
 
+	fmt.Println()
+	c.displayRiskScore(permissions.RiskScore(details))
+
 	fmt.Println()
 	fmt.Println(strings.Repeat("─", 70))
 	fmt.Println()
 }
 
+// riskBadge returns a colored badge with the level and category of a risk
+func (c *Colors) riskBadge(risk models.Risk) string {
+	badge := fmt.Sprintf("[%s · %s]", strings.ToUpper(string(risk.Level)), risk.Category)
+	switch risk.Level {
+	case models.RiskCritical, models.RiskHigh:
+		return c.Error.Sprint(badge)
+	case models.RiskMedium:
+		return c.Warning.Sprint(badge)
+	default:
+		return c.Info.Sprint(badge)
+	}
+}
+
+// displayRiskScore prints an overall risk score
+func (c *Colors) displayRiskScore(score int, level models.RiskLevel) {
+	message := fmt.Sprintf("⚖️  Risk score: %d/100 (%s)", score, level)
+	switch level {
+	case models.RiskCritical, models.RiskHigh:
+		c.Error.Println(message)
+	case models.RiskMedium:
+		c.Warning.Println(message)
+	default:
+		c.Info.Println(message)
+	}
+}
+
+// displayActions prints aggregated Actions and DataActions with their risk badges
+func (c *Colors) displayActions(actions, dataActions []string) {
+	for _, action := range actions {
+		c.Success.Printf("    🔐 %s ", action)
+		fmt.Println(c.riskBadge(permissions.ClassifyRisk(action, false)))
+	}
+	for _, dataAction := range dataActions {
+		c.Success.Printf("    🔐 %s [DataAction] ", dataAction)
+		fmt.Println(c.riskBadge(permissions.ClassifyRisk(dataAction, true)))
+	}
+}
+
+// DisplayScanResult shows the permissions for every Azure CLI command found in a script
+func (c *Colors) DisplayScanResult(result models.ScanResult) {
+	if result.Source != "" {
+		c.Header.Printf("📄 Script: %s\n", result.Source)
+	}
+	c.Info.Printf("Found %d Azure CLI command(s)\n", len(result.Commands))
+	c.displayRiskScore(result.RiskScore, result.RiskLevel)
+	fmt.Println()
+
+	for _, command := range result.Commands {
+		c.Header.Printf("🔍 Line %d: %s\n", command.Line, command.Command)
+		if command.Error != "" {
+			c.Warning.Printf("  ⚠️  %s\n", command.Error)
+			continue
+		}
+		for _, permission := range command.Permissions {
+			fmt.Printf("  • %s %s\n", permission.Name, c.riskBadge(permissions.RiskOf(permission)))
+		}
+	}
+
+	fmt.Println()
+	c.Success.Println("🔐 Combined RBAC Permissions:")
+	if len(result.Actions) > 0 {
+		c.Info.Println("  Actions:")
+		for _, action := range result.Actions {
+			fmt.Printf("    • %s\n", action)
+		}
+	}
+	if len(result.DataActions) > 0 {
+		c.Info.Println("  DataActions:")
+		for _, action := range result.DataActions {
+			fmt.Printf("    • %s\n", action)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(strings.Repeat("─", 70))
+	fmt.Println()
+}
+
+// DisplayErrorExplanations shows parsed authorization failures with remediation steps
+func (c *Colors) DisplayErrorExplanations(explanations []models.ErrorExplanation) {
+	for _, explanation := range explanations {
+		c.Error.Printf("🚫 %s\n", explanation.Code)
+		if explanation.Principal != "" {
+			fmt.Printf("   Principal: %s\n", explanation.Principal)
+		}
+		if explanation.ObjectID != "" {
+			fmt.Printf("   Object ID: %s\n", explanation.ObjectID)
+		}
+		if explanation.GrantedAction != "" {
+			fmt.Printf("   Granted:   %s on %s\n", explanation.GrantedAction, explanation.GrantedScope)
+		}
+		for _, scope := range explanation.Scopes {
+			fmt.Printf("   Scope:     %s\n", scope)
+		}
+
+		fmt.Println()
+		c.Warning.Println("   Missing permission(s):")
+		for _, operation := range explanation.Operations {
+			kind := "Action"
+			if operation.IsDataAction {
+				kind = "DataAction"
+			}
+			fmt.Printf("     • %s [%s]\n", operation.Name, kind)
+			if operation.Description != "" {
+				fmt.Printf("         %s\n", operation.Description)
+			}
+		}
+
+		if len(explanation.SuggestedRoles) > 0 {
+			fmt.Println()
+			c.Info.Println("   💡 Suggested built-in role(s):")
+			for _, role := range explanation.SuggestedRoles {
+				fmt.Printf("     • %s\n", role.Name)
+			}
+		}
+
+		fmt.Println()
+		c.Success.Println("   🛠  Grant access with:")
+		for _, command := range explanation.Commands {
+			fmt.Printf("     %s\n", command)
+		}
+
+		fmt.Println()
+		fmt.Println(strings.Repeat("─", 70))
+		fmt.Println()
+	}
+}
+
+// DisplayActivityReport shows the permissions observed in an Activity Log export and the derived role
+func (c *Colors) DisplayActivityReport(report models.ActivityReport) {
+	if report.Caller != "" {
+		c.Header.Printf("📜 Activity for: %s\n", report.Caller)
+	}
+	c.Info.Printf("Analyzed %d event(s), found %d distinct operation(s)\n", report.Events, len(report.Actions))
+	fmt.Println()
+
+	c.Success.Println("🔐 Observed Permissions:")
+	for _, action := range report.Actions {
+		kind := "Action"
+		if action.IsDataAction {
+			kind = "DataAction"
+		}
+		fmt.Printf("  • %s [%s] (%d succeeded, %d failed)\n", action.Action, kind, action.Succeeded, action.Failed)
+		if action.Description != "" {
+			fmt.Printf("      %s\n", action.Description)
+		}
+	}
+
+	if report.Comparison != nil {
+		fmt.Println()
+		c.Header.Printf("⚖️  Compared with: %s\n", strings.Join(report.Comparison.AssignedRoles, ", "))
+		if len(report.Comparison.UncoveredActions) > 0 {
+			c.Error.Println("  Used but not granted by the assigned roles:")
+			for _, action := range report.Comparison.UncoveredActions {
+				fmt.Printf("    • %s\n", action)
+			}
+		}
+		if len(report.Comparison.UnusedPatterns) > 0 {
+			c.Warning.Println("  Granted but never used (candidates for removal):")
+			for _, pattern := range report.Comparison.UnusedPatterns {
+				fmt.Printf("    • %s\n", pattern)
+			}
+		}
+	}
+
+	fmt.Println()
+	c.Success.Println("📝 Least-Privilege Custom Role:")
+	WriteJSON(os.Stdout, report.Role)
+
+	fmt.Println()
+	c.Warning.Println("💡 The Activity Log records write, delete and action operations only.")
+	c.Warning.Println("   Read permissions and data plane operations are not captured and must be added separately.")
+	fmt.Println()
+}
+
+// DisplayCatalogDiff shows the differences between two provider operations catalog snapshots
+func (c *Colors) DisplayCatalogDiff(diff models.CatalogDiff) {
+	c.Header.Println("🗂️  Provider Operations Catalog Diff")
+	fmt.Println()
+
+	sections := []struct {
+		title string
+		names []string
+		added bool
+	}{
+		{"Added namespaces", diff.AddedNamespaces, true},
+		{"Removed namespaces", diff.RemovedNamespaces, false},
+		{"Added resource types", diff.AddedResourceTypes, true},
+		{"Removed resource types", diff.RemovedResourceTypes, false},
+		{"Added operations", diff.AddedOperations, true},
+		{"Removed operations", diff.RemovedOperations, false},
+	}
+	for _, section := range sections {
+		if len(section.names) == 0 {
+			continue
+		}
+		if section.added {
+			c.Success.Printf("➕ %s (%d):\n", section.title, len(section.names))
+		} else {
+			c.Error.Printf("➖ %s (%d):\n", section.title, len(section.names))
+		}
+		for _, name := range section.names {
+			fmt.Printf("  • %s\n", name)
+		}
+		fmt.Println()
+	}
+
+	if len(diff.DataActionChanges) > 0 {
+		c.Warning.Printf("🔀 Changed IsDataAction (%d):\n", len(diff.DataActionChanges))
+		for _, change := range diff.DataActionChanges {
+			fmt.Printf("  • %s: %s → %s\n", change.Operation, change.Old, change.New)
+		}
+		fmt.Println()
+	}
+
+	if len(diff.DisplayNameChanges) > 0 {
+		c.Info.Printf("✏️  Changed display names (%d):\n", len(diff.DisplayNameChanges))
+		for _, change := range diff.DisplayNameChanges {
+			fmt.Printf("  • %s: %q → %q\n", change.Operation, change.Old, change.New)
+		}
+		fmt.Println()
+	}
+
+	if len(diff.RoleReferences) > 0 {
+		c.Error.Printf("🚨 Role patterns referencing removed operations (%d):\n", len(diff.RoleReferences))
+		for _, reference := range diff.RoleReferences {
+			status := ""
+			if reference.Orphaned {
+				status = " (no longer matches any operation)"
+			}
+			fmt.Printf("  • %s: %s%s\n", reference.Role, reference.Pattern, status)
+			for _, operation := range reference.Operations {
+				fmt.Printf("      - %s\n", operation)
+			}
+		}
+		fmt.Println()
+	}
+
+	if len(diff.AddedNamespaces)+len(diff.RemovedNamespaces)+len(diff.AddedResourceTypes)+len(diff.RemovedResourceTypes)+
+		len(diff.AddedOperations)+len(diff.RemovedOperations)+len(diff.DataActionChanges)+len(diff.DisplayNameChanges) == 0 {
+		c.Success.Println("✅ No differences found")
+		fmt.Println()
+	}
+}
+
+// DisplayPermissionExpansion shows the concrete operations granted by a set of permission patterns
+func (c *Colors) DisplayPermissionExpansion(expansion models.PermissionExpansion) {
+	if expansion.Role != "" {
+		c.Header.Printf("🔎 Role: %s\n", expansion.Role)
+	}
+	patterns := []struct {
+		label    string
+		patterns []string
+	}{
+		{"Actions", expansion.Permission.Actions},
+		{"NotActions", expansion.Permission.NotActions},
+		{"DataActions", expansion.Permission.DataActions},
+		{"NotDataActions", expansion.Permission.NotDataActions},
+	}
+	for _, group := range patterns {
+		if len(group.patterns) > 0 {
+			fmt.Printf("📋 %s: %s\n", group.label, strings.Join(group.patterns, ", "))
+		}
+	}
+	fmt.Println()
+
+	if len(expansion.Operations) == 0 {
+		c.Warning.Println("⚠️  The patterns do not match any operation in the provider catalog")
+		fmt.Println()
+		return
+	}
+
+	c.Success.Printf("🔐 Granted Operations (%d):\n", len(expansion.Operations))
+	for _, operation := range expansion.Operations {
+		if operation.IsDataAction {
+			fmt.Printf("  • %s [DataAction]\n", operation.Name)
+		} else {
+			fmt.Printf("  • %s\n", operation.Name)
+		}
+		if operation.Description != "" {
+			fmt.Printf("      %s\n", operation.Description)
+		}
+	}
+	fmt.Println()
+}
+
+// DisplayRoleInspection shows which Azure CLI commands a role definition enables
+func (c *Colors) DisplayRoleInspection(inspection models.RoleInspection, showBlocked bool) {
+	c.Header.Printf("🔎 Role: %s\n", inspection.Role.Name)
+	if inspection.Role.Description != "" {
+		fmt.Printf("   %s\n", inspection.Role.Description)
+	}
+	c.Info.Printf("Grants %d operation(s) in the provider catalog\n", inspection.GrantedOperations)
+	fmt.Println()
+
+	c.Success.Printf("✅ Fully allowed commands (%d):\n", len(inspection.Allowed))
+	for _, access := range inspection.Allowed {
+		fmt.Printf("  • %s\n", access.Command)
+	}
+	fmt.Println()
+
+	if len(inspection.Partial) > 0 {
+		c.Warning.Printf("⚠️  Partially allowed commands (%d):\n", len(inspection.Partial))
+		for _, access := range inspection.Partial {
+			fmt.Printf("  • %s\n", access.Command)
+			for _, missing := range access.Missing {
+				fmt.Printf("      missing: %s\n", missing)
+			}
+		}
+		fmt.Println()
+	}
+
+	if showBlocked {
+		c.Error.Printf("❌ Blocked commands (%d):\n", len(inspection.Blocked))
+		for _, access := range inspection.Blocked {
+			fmt.Printf("  • %s\n", access.Command)
+		}
+		fmt.Println()
+	} else if len(inspection.Blocked) > 0 {
+		c.Info.Printf("❌ %d command(s) blocked (use --show-blocked to list them)\n", len(inspection.Blocked))
+		fmt.Println()
+	}
+}
+
+// DisplayCommandReferences shows the Azure CLI commands that require a permission
+func (c *Colors) DisplayCommandReferences(permission string, references []models.CommandReference) {
+	c.Header.Printf("🔎 Commands requiring: %s\n", permission)
+	fmt.Println()
+
+	if len(references) == 0 {
+		c.Warning.Println("⚠️  No known az command requires this permission")
+		c.Warning.Println("   Use --resolve to also search the live provider catalog")
+		fmt.Println()
+		return
+	}
+
+	for _, reference := range references {
+		fmt.Printf("  • %s", reference.Command)
+		if reference.Condition != "" {
+			c.Warning.Printf(" (when %s is used)", reference.Condition)
+		}
+		fmt.Println()
+		if !strings.EqualFold(reference.Permission, permission) {
+			fmt.Printf("      %s\n", reference.Permission)
+		}
+	}
+	fmt.Println()
+}
+
+// DisplayOperationMatches shows the ranked results of a catalog search
+func (c *Colors) DisplayOperationMatches(query string, matches []models.OperationMatch) {
+	if len(matches) == 0 {
+		c.Warning.Printf("⚠️  No operations found matching: %s\n", query)
+		return
+	}
+
+	c.Header.Printf("🔎 %d operation(s) matching: %s\n", len(matches), query)
+	fmt.Println()
+	for _, match := range matches {
+		if match.IsDataAction {
+			c.Success.Printf("  • %s", match.Name)
+			fmt.Println(" [DataAction]")
+		} else {
+			c.Success.Printf("  • %s\n", match.Name)
+		}
+		if match.DisplayName != "" {
+			fmt.Printf("      %s\n", match.DisplayName)
+		}
+	}
+	fmt.Println()
+}
+
+// DisplayOperation shows the full details of a provider operation
+func (c *Colors) DisplayOperation(operation models.OperationInfo) {
+	c.Header.Printf("🔐 %s\n", operation.Name)
+	fmt.Println()
+	fmt.Printf("  Display name:   %s\n", operation.DisplayName)
+	fmt.Printf("  Description:    %s\n", operation.Description)
+	fmt.Printf("  Provider:       %s\n", operation.Provider)
+	if operation.ResourceType != "" {
+		fmt.Printf("  Resource type:  %s\n", operation.ResourceType)
+	}
+	fmt.Printf("  Origin:         %s\n", operation.Origin)
+	if operation.IsDataAction {
+		fmt.Println("  Kind:           DataAction (grant via dataActions)")
+	} else {
+		fmt.Println("  Kind:           Action (grant via actions)")
+	}
+	fmt.Println()
+}
+
+// DisplayWorkflowResult shows the permissions required by each job of a CI definition
+func (c *Colors) DisplayWorkflowResult(result models.WorkflowResult) {
+	title := result.Source
+	if result.Name != "" {
+		title = fmt.Sprintf("%s (%s)", result.Name, result.Source)
+	}
+	c.Header.Printf("⚙️  Workflow: %s\n", title)
+	fmt.Println()
+
+	for _, job := range result.Jobs {
+		if len(job.Commands) == 0 {
+			continue
+		}
+
+		name := job.ID
+		if job.Name != "" {
+			name = fmt.Sprintf("%s (%s)", job.ID, job.Name)
+		}
+		c.Info.Printf("📦 Job: %s\n", name)
+		if job.Identity != "" {
+			fmt.Printf("   Identity: %s\n", job.Identity)
+		}
+
+		for _, command := range job.Commands {
+			if command.Error != "" {
+				c.Warning.Printf("  ⚠️  %d:%d %s: %s\n", command.Line, command.Column, command.Command, command.Error)
+				continue
+			}
+			fmt.Printf("  • %d:%d %s\n", command.Line, command.Column, command.Command)
+		}
+		c.displayActions(job.Actions, job.DataActions)
+		fmt.Println()
+	}
+
+	if len(result.Actions) == 0 && len(result.DataActions) == 0 {
+		c.Warning.Println("No Azure CLI permissions required by this workflow")
+		fmt.Println()
+	}
+}
+
+// DisplayPipelineResult shows which permissions each service connection of a pipeline needs
+func (c *Colors) DisplayPipelineResult(result models.PipelineResult) {
+	title := result.Source
+	if result.Name != "" {
+		title = fmt.Sprintf("%s (%s)", result.Name, result.Source)
+	}
+	c.Header.Printf("⚙️  Pipeline: %s\n", title)
+	if len(result.Files) > 1 {
+		fmt.Printf("   Templates: %s\n", strings.Join(result.Files[1:], ", "))
+	}
+	fmt.Println()
+
+	for _, connection := range result.Connections {
+		if connection.Name != "" {
+			c.Info.Printf("🔌 Service connection: %s\n", connection.Name)
+		} else {
+			c.Info.Println("🔌 Script steps without a service connection:")
+		}
+
+		for _, command := range connection.Commands {
+			location := fmt.Sprintf("%s:%d:%d", command.File, command.Line, command.Column)
+			if command.Error != "" {
+				c.Warning.Printf("  ⚠️  %s %s: %s\n", location, command.Command, command.Error)
+				continue
+			}
+			fmt.Printf("  • %s %s\n", location, command.Command)
+		}
+		c.displayActions(connection.Actions, connection.DataActions)
+		fmt.Println()
+	}
+
+	if len(result.Connections) == 0 {
+		c.Warning.Println("No Azure CLI commands found in this pipeline")
+		fmt.Println()
+	}
+}
+
+// DisplayPolicyViolations reports policy violations on stderr so they do not mix with the analysis output
+func (c *Colors) DisplayPolicyViolations(violations []models.PolicyViolation) {
+	if len(violations) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr)
+	c.Header.Fprintf(os.Stderr, "📜 Policy violations (%d):\n", len(violations))
+	for _, violation := range violations {
+		location := ""
+		if violation.Line > 0 {
+			location = fmt.Sprintf("%d:%d: ", violation.Line, violation.Column)
+			if violation.File != "" {
+				location = violation.File + ":" + location
+			}
+		}
+
+		switch violation.Severity {
+		case policy.SeverityDeny:
+			c.Error.Fprintf(os.Stderr, "  ❌ [%s] ", violation.RuleID)
+		case policy.SeverityWarn:
+			c.Warning.Fprintf(os.Stderr, "  ⚠️  [%s] ", violation.RuleID)
+		default:
+			c.Info.Fprintf(os.Stderr, "  ℹ️  [%s] ", violation.RuleID)
+		}
+		fmt.Fprintf(os.Stderr, "%s%s\n", location, violation.Message)
+	}
+}
+
 // ShowUsage displays the usage information
 func (c *Colors) ShowUsage() {
 	c.Header.Println("Azure CLI Permissions Analyzer (azperm) v2.2")
@@ -132,6 +644,36 @@ func (c *Colors) ShowUsage() {
 	fmt.Println("  --help, -h              Show this help message")
 	fmt.Println("  --debug, -d             Enable debug mode with verbose output")
 	fmt.Println("  --last, -l              Analyze the last Azure CLI command from shell history")
+	fmt.Println("  --output, -o FORMAT     Output format: text (default), json, or markdown, csv, html, sarif reports")
+	fmt.Println("  --catalog FILE          Load the provider operations catalog from a snapshot file")
+	fmt.Println("  --offline               Use the cached catalog (see 'catalog save') instead of the live API")
+	fmt.Println("  --cloud NAME            Azure cloud profile (default: the cloud configured in az)")
+	fmt.Println("  --policy FILE           Check results against a policy (default: .azperm-policy.yaml if present)")
+	fmt.Println("  --profile NAME          Use a named profile from the config files")
+	fmt.Println("  --proxy URL             Proxy for Azure API requests (default: HTTPS_PROXY, honoring NO_PROXY)")
+	fmt.Println("  --ca-file FILE          PEM bundle of additional trusted CAs (default: REQUESTS_CA_BUNDLE)")
+	fmt.Println("  --client-cert FILE      PEM client certificate for mutual TLS (--client-key FILE for a separate key)")
+	fmt.Println("  --providers FILE        az provider list output used to match unknown services to providers")
+	fmt.Println()
+	c.Info.Println("SUBCOMMANDS:")
+	fmt.Println("  scan [script-file]              Analyze every az command in a bash/PowerShell script")
+	fmt.Println("  workflow [files | dirs]         Analyze az usage per job in GitHub Actions workflows")
+	fmt.Println("  pipeline [azure-pipelines.yml]  Analyze az usage per service connection in Azure Pipelines")
+	fmt.Println("  role create [script | az ...]   Generate a least-privilege custom role definition")
+	fmt.Println("  role inspect <role.json | name> List the az commands a role allows, partially allows or blocks")
+	fmt.Println("  repl                            Interactive session with completion and role building")
+	fmt.Println("  exec -- az <command> [args]     Run az only if the required permissions are present")
+	fmt.Println("  explain-error [--last-error]    Explain AuthorizationFailed errors from az output")
+	fmt.Println("  activity <export.json|.csv>     Derive a least-privilege role from an Activity Log export")
+	fmt.Println("  expand <pattern>...             List the operations a wildcard permission pattern grants")
+	fmt.Println("  which-commands <permission>     List the az commands that require a permission")
+	fmt.Println("  ops search <terms> | show <op>  Search the provider operations catalog")
+	fmt.Println("  catalog save [--compact] [file] Save the live provider operations catalog")
+	fmt.Println("  catalog diff <old> [new]        Compare catalog snapshots (live when new is omitted)")
+	fmt.Println("  serve [--listen :8080]          Run the HTTP API server")
+	fmt.Println("  config <get|set|list|profiles|explain>  Show and edit the configuration files")
+	fmt.Println("  doctor [--no-connect]           Report the effective cloud, proxy and TLS setup and check connectivity")
+	fmt.Println("  registry <list|validate>        List the service registry or check it against the catalog")
 	fmt.Println()
 	c.Info.Println("DESCRIPTION:")
 	fmt.Println("  This tool analyzes Azure CLI commands and shows the required RBAC permissions.")
@@ -166,6 +708,10 @@ func (c *Colors) ShowUsage() {
 	fmt.Println("  Environment variables:")
 	fmt.Println("  • AZPERM_API_VERSION - Override Azure Management API version")
 	fmt.Println("  • AZPERM_MANAGEMENT_ENDPOINT - Override management endpoint URL")
+	fmt.Println("  • AZPERM_CLOUD - Select a cloud profile, like --cloud")
+	fmt.Println("  • AZPERM_PROFILE - Select a configuration profile, like --profile")
+	fmt.Println("  Config files (later wins): /etc/azperm/config.yaml, ~/.config/azperm/config.yaml, .azperm.yaml")
+	fmt.Println("  Run 'azperm config explain' to see where each setting comes from")
 }
 
 // ShowNoPermissionsWarning displays a warning when no permissions are found
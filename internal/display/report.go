@@ -0,0 +1,281 @@
+package display
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// reportSummary aggregates the permissions of all commands in a report
+type reportSummary struct {
+	Commands    int
+	Resolved    int
+	Unresolved  int
+	Actions     []string
+	DataActions []string
+	Providers   []providerCount
+	RiskScore   int
+	RiskLevel   models.RiskLevel
+}
+
+// providerCount is the number of distinct permissions required from a resource provider
+type providerCount struct {
+	Provider string
+	Count    int
+}
+
+// summarize builds the aggregated summary section of a report
+func summarize(result models.ScanResult) reportSummary {
+	summary := reportSummary{
+		Commands:    len(result.Commands),
+		Actions:     result.Actions,
+		DataActions: result.DataActions,
+		RiskScore:   result.RiskScore,
+		RiskLevel:   result.RiskLevel,
+	}
+
+	providers := make(map[string]map[string]bool)
+	for _, command := range result.Commands {
+		if command.Error != "" {
+			summary.Unresolved++
+			continue
+		}
+		summary.Resolved++
+		for _, permission := range command.Permissions {
+			if providers[permission.Provider] == nil {
+				providers[permission.Provider] = make(map[string]bool)
+			}
+			providers[permission.Provider][permission.Name] = true
+		}
+	}
+
+	for provider, names := range providers {
+		summary.Providers = append(summary.Providers, providerCount{Provider: provider, Count: len(names)})
+	}
+	sort.Slice(summary.Providers, func(i, j int) bool {
+		return summary.Providers[i].Provider < summary.Providers[j].Provider
+	})
+
+	return summary
+}
+
+// permissionKind returns "DataAction" or "Action" for a permission
+func permissionKind(permission models.PermissionDetail) string {
+	if permission.IsDataAction {
+		return "DataAction"
+	}
+	return "Action"
+}
+
+// riskLabel returns the level and category of the risk of a permission, e.g. "high (secret-exposure)"
+func riskLabel(permission models.PermissionDetail) string {
+	risk := permissions.RiskOf(permission)
+	return fmt.Sprintf("%s (%s)", risk.Level, risk.Category)
+}
+
+// commandScopes returns every scope of a command, separated by spaces
+func commandScopes(command models.AnalysisResult) string {
+	if len(command.Scopes) > 0 {
+		return strings.Join(command.Scopes, " ")
+	}
+	return command.Scope
+}
+
+// reportTitle returns the heading of a report
+func reportTitle(result models.ScanResult) string {
+	if result.Source != "" {
+		return "Azure RBAC Permissions Report: " + result.Source
+	}
+	return "Azure RBAC Permissions Report"
+}
+
+// WriteMarkdownReport writes the analysis results as a Markdown document with a table per command
+func WriteMarkdownReport(w io.Writer, result models.ScanResult) error {
+	var b strings.Builder
+	summary := summarize(result)
+
+	fmt.Fprintf(&b, "# %s\n\n", reportTitle(result))
+
+	b.WriteString("## Summary\n\n")
+	fmt.Fprintf(&b, "- **Commands:** %d (%d resolved, %d unresolved)\n", summary.Commands, summary.Resolved, summary.Unresolved)
+	fmt.Fprintf(&b, "- **Actions:** %d\n", len(summary.Actions))
+	fmt.Fprintf(&b, "- **DataActions:** %d\n", len(summary.DataActions))
+	fmt.Fprintf(&b, "- **Risk score:** %d/100 (%s)\n\n", summary.RiskScore, summary.RiskLevel)
+	if len(summary.Providers) > 0 {
+		b.WriteString("| Provider | Permissions |\n|----------|-------------|\n")
+		for _, provider := range summary.Providers {
+			fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(provider.Provider), provider.Count)
+		}
+		b.WriteString("\n")
+	}
+	writeMarkdownList(&b, "Actions", summary.Actions)
+	writeMarkdownList(&b, "DataActions", summary.DataActions)
+
+	b.WriteString("## Commands\n")
+	for _, command := range result.Commands {
+		fmt.Fprintf(&b, "\n### `%s`\n\n", strings.ReplaceAll(command.Command, "`", "'"))
+		if command.Line > 0 {
+			fmt.Fprintf(&b, "- **Line:** %d\n", command.Line)
+		}
+		fmt.Fprintf(&b, "- **Confidence:** %s\n", command.Confidence)
+		if command.Error == "" {
+			fmt.Fprintf(&b, "- **Risk score:** %d/100 (%s)\n", command.RiskScore, command.RiskLevel)
+		}
+		if len(command.Scopes) > 0 {
+			fmt.Fprintf(&b, "- **Scopes:** `%s`\n", strings.Join(command.Scopes, "`, `"))
+		} else if command.Scope != "" {
+			fmt.Fprintf(&b, "- **Scope:** `%s`\n", command.Scope)
+		}
+		if command.Error != "" {
+			fmt.Fprintf(&b, "- **Error:** %s\n", markdownCell(command.Error))
+			continue
+		}
+
+		b.WriteString("\n| Permission | Type | Risk | Provider | Resource Type | Description |\n")
+		b.WriteString("|------------|------|------|----------|---------------|-------------|\n")
+		for _, permission := range command.Permissions {
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s | %s |\n",
+				permission.Name, permissionKind(permission), riskLabel(permission), markdownCell(permission.Provider),
+				markdownCell(permission.ResourceType), markdownCell(permission.Description))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownList writes a titled bullet list of permissions, if there are any
+func writeMarkdownList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "**%s:**\n\n", title)
+	for _, item := range items {
+		fmt.Fprintf(b, "- `%s`\n", item)
+	}
+	b.WriteString("\n")
+}
+
+// markdownCell escapes a value for use in a Markdown table cell
+func markdownCell(value string) string {
+	value = strings.NewReplacer("|", "\\|", "<", "&lt;", ">", "&gt;").Replace(value)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// WriteCSVReport writes the analysis results as CSV with one row per command and permission.
+// Unresolved commands are included with an empty permission and the error in the description.
+func WriteCSVReport(w io.Writer, result models.ScanResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"command", "line", "permission", "type", "risk", "risk_category", "provider", "resource_type", "description", "confidence", "scope"}); err != nil {
+		return err
+	}
+
+	for _, command := range result.Commands {
+		line := ""
+		if command.Line > 0 {
+			line = strconv.Itoa(command.Line)
+		}
+
+		if command.Error != "" {
+			if err := writer.Write([]string{command.Command, line, "", "", "", "", "", "", command.Error, string(command.Confidence), commandScopes(command)}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, permission := range command.Permissions {
+			risk := permissions.RiskOf(permission)
+			record := []string{
+				command.Command, line, permission.Name, permissionKind(permission), string(risk.Level), risk.Category, permission.Provider,
+				permission.ResourceType, permission.Description, string(command.Confidence), commandScopes(command),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// htmlReport is the data passed to the HTML report template
+type htmlReport struct {
+	Title    string
+	Summary  reportSummary
+	Commands []models.AnalysisResult
+}
+
+// htmlReportTemplate renders a self-contained HTML report; all styles are inline so the
+// file can be attached to tickets and opened without network access
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"kind": permissionKind,
+	"risk": riskLabel,
+	"riskLevel": func(permission models.PermissionDetail) string {
+		return string(permissions.RiskOf(permission).Level)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.6em; border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+h2 { font-size: 1.3em; margin-top: 1.5em; }
+h3 { font-size: 1em; margin-top: 1.5em; }
+code { font-family: SFMono-Regular, Consolas, "Liberation Mono", monospace; background: #f6f8fa; padding: .1em .3em; border-radius: 4px; }
+table { border-collapse: collapse; margin: .5em 0; }
+th, td { border: 1px solid #d0d7de; padding: .35em .7em; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+.meta { color: #59636e; }
+.error { color: #cf222e; }
+.data { color: #8250df; }
+.risk-critical, .risk-high { color: #cf222e; font-weight: 600; }
+.risk-medium { color: #9a6700; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+
+<h2>Summary</h2>
+<p>{{.Summary.Commands}} command(s): {{.Summary.Resolved}} resolved, {{.Summary.Unresolved}} unresolved.
+{{len .Summary.Actions}} Action(s) and {{len .Summary.DataActions}} DataAction(s) required.
+Risk score: <span class="risk-{{.Summary.RiskLevel}}">{{.Summary.RiskScore}}/100 ({{.Summary.RiskLevel}})</span>.</p>
+{{if .Summary.Providers}}<table>
+<tr><th>Provider</th><th>Permissions</th></tr>
+{{range .Summary.Providers}}<tr><td>{{.Provider}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Summary.Actions}}<h3>Actions</h3>
+<ul>{{range .Summary.Actions}}<li><code>{{.}}</code></li>{{end}}</ul>{{end}}
+{{if .Summary.DataActions}}<h3>DataActions</h3>
+<ul>{{range .Summary.DataActions}}<li><code>{{.}}</code></li>{{end}}</ul>{{end}}
+
+<h2>Commands</h2>
+{{range .Commands}}<h3><code>{{.Command}}</code></h3>
+<p class="meta">{{if .Line}}Line {{.Line}} · {{end}}Confidence: {{.Confidence}}{{if not .Error}} · Risk score: {{.RiskScore}}/100 ({{.RiskLevel}}){{end}}{{if .Scopes}} · Scopes: {{range $i, $scope := .Scopes}}{{if $i}}, {{end}}<code>{{$scope}}</code>{{end}}{{else if .Scope}} · Scope: <code>{{.Scope}}</code>{{end}}</p>
+{{if .Error}}<p class="error">{{.Error}}</p>
+{{else}}<table>
+<tr><th>Permission</th><th>Type</th><th>Risk</th><th>Provider</th><th>Resource Type</th><th>Description</th></tr>
+{{range .Permissions}}<tr><td><code>{{.Name}}</code></td><td{{if .IsDataAction}} class="data"{{end}}>{{kind .}}</td><td class="risk-{{riskLevel .}}">{{risk .}}</td><td>{{.Provider}}</td><td>{{.ResourceType}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// WriteHTMLReport writes the analysis results as a single self-contained HTML document
+func WriteHTMLReport(w io.Writer, result models.ScanResult) error {
+	return htmlReportTemplate.Execute(w, htmlReport{
+		Title:    reportTitle(result),
+		Summary:  summarize(result),
+		Commands: result.Commands,
+	})
+}
@@ -0,0 +1,249 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+	"github.com/mathwro/azperm/internal/policy"
+)
+
+// SARIF rule IDs reported by azperm
+const (
+	sarifRuleRequiredPermissions = "azperm/required-permissions"
+	sarifRuleHighRiskPermissions = "azperm/high-risk-permissions"
+	sarifRuleUnresolvedCommand   = "azperm/unresolved-command"
+
+	// sarifPolicyRulePrefix prefixes the IDs of permission policy rules
+	sarifPolicyRulePrefix = "azperm/policy/"
+)
+
+// sarifLog is the root object of a SARIF 2.1.0 log
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConfig   `json:"defaultConfiguration"`
+	Properties           map[string]string `json:"properties,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifRules describes the rules results can refer to
+var sarifRules = []sarifRule{
+	{
+		ID:                   sarifRuleRequiredPermissions,
+		Name:                 "RequiredPermissions",
+		ShortDescription:     sarifMessage{Text: "Azure CLI command requires RBAC permissions"},
+		DefaultConfiguration: sarifRuleConfig{Level: "note"},
+	},
+	{
+		ID:                   sarifRuleHighRiskPermissions,
+		Name:                 "HighRiskPermissions",
+		ShortDescription:     sarifMessage{Text: "Azure CLI command requires high-risk RBAC permissions"},
+		DefaultConfiguration: sarifRuleConfig{Level: "error"},
+		Properties:           map[string]string{"security-severity": "8.0"},
+	},
+	{
+		ID:                   sarifRuleUnresolvedCommand,
+		Name:                 "UnresolvedCommand",
+		ShortDescription:     sarifMessage{Text: "Permissions of Azure CLI command could not be resolved"},
+		DefaultConfiguration: sarifRuleConfig{Level: "warning"},
+	},
+}
+
+// WriteSARIFReport writes the analysis results as a SARIF 2.1.0 log with one result per command
+// and one per policy violation. Commands requiring high-risk permissions are reported as errors
+// and unresolved commands as warnings.
+func WriteSARIFReport(w io.Writer, result models.ScanResult, toolVersion string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "azperm",
+			Version:        toolVersion,
+			InformationURI: "https://github.com/mathwro/azperm",
+			Rules:          append([]sarifRule{}, sarifRules...),
+		}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+
+	uri := "stdin"
+	if result.Source != "" {
+		uri = filepath.ToSlash(result.Source)
+	}
+
+	for _, command := range result.Commands {
+		sarif := sarifResult{
+			RuleID:     sarifRuleRequiredPermissions,
+			Level:      "note",
+			Properties: map[string]interface{}{"command": command.Command},
+		}
+
+		if command.Line > 0 {
+			sarif.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(uri, command.File)},
+				Region:           &sarifRegion{StartLine: command.Line, StartColumn: command.Column},
+			}}}
+		}
+
+		if command.Error != "" {
+			sarif.RuleID = sarifRuleUnresolvedCommand
+			sarif.Level = "warning"
+			sarif.Message.Text = fmt.Sprintf("%s: %s", command.Command, command.Error)
+			run.Results = append(run.Results, sarif)
+			continue
+		}
+
+		var names, highRisk []string
+		for _, permission := range command.Permissions {
+			names = append(names, permission.Name)
+			if permissions.IsHighRisk(permission) {
+				highRisk = append(highRisk, permission.Name)
+			}
+		}
+		sarif.Properties["permissions"] = names
+		sarif.Properties["riskScore"] = command.RiskScore
+		sarif.Properties["riskLevel"] = command.RiskLevel
+		if command.Scope != "" {
+			sarif.Properties["scope"] = command.Scope
+		}
+		if len(command.Scopes) > 0 {
+			sarif.Properties["scopes"] = command.Scopes
+		}
+
+		sarif.Message.Text = fmt.Sprintf("%s requires: %s", command.Command, strings.Join(names, ", "))
+		if len(highRisk) > 0 {
+			sarif.RuleID = sarifRuleHighRiskPermissions
+			sarif.Level = "error"
+			sarif.Properties["highRiskPermissions"] = highRisk
+			sarif.Message.Text = fmt.Sprintf("%s requires high-risk permission(s): %s (all permissions: %s)",
+				command.Command, strings.Join(highRisk, ", "), strings.Join(names, ", "))
+		}
+
+		run.Results = append(run.Results, sarif)
+	}
+
+	for _, violation := range result.Violations {
+		ruleID := sarifPolicyRulePrefix + violation.RuleID
+		if !hasSARIFRule(run.Tool.Driver.Rules, ruleID) {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:                   ruleID,
+				Name:                 violation.RuleID,
+				ShortDescription:     sarifMessage{Text: "Permission policy rule " + violation.RuleID},
+				DefaultConfiguration: sarifRuleConfig{Level: policySARIFLevel(violation.Severity)},
+			})
+		}
+
+		sarif := sarifResult{
+			RuleID:     ruleID,
+			Level:      policySARIFLevel(violation.Severity),
+			Message:    sarifMessage{Text: violation.Message},
+			Properties: map[string]interface{}{"command": violation.Command},
+		}
+		if violation.Permission != "" {
+			sarif.Properties["permission"] = violation.Permission
+		}
+		if violation.Line > 0 {
+			sarif.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(uri, violation.File)},
+				Region:           &sarifRegion{StartLine: violation.Line, StartColumn: violation.Column},
+			}}}
+		}
+		run.Results = append(run.Results, sarif)
+	}
+
+	return WriteJSON(w, sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifURI returns the artifact URI of a result: the file it was found in, such as an
+// included pipeline template, or the scanned source
+func sarifURI(source, file string) string {
+	if file == "" {
+		return source
+	}
+	return filepath.ToSlash(file)
+}
+
+// hasSARIFRule reports whether a rule with the given ID is already described
+func hasSARIFRule(rules []sarifRule, id string) bool {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// policySARIFLevel maps a policy severity to a SARIF result level
+func policySARIFLevel(severity string) string {
+	switch severity {
+	case policy.SeverityDeny:
+		return "error"
+	case policy.SeverityWarn:
+		return "warning"
+	default:
+		return "note"
+	}
+}
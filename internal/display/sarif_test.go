@@ -0,0 +1,106 @@
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+func TestWriteSARIFReport(t *testing.T) {
+	result := models.ScanResult{
+		Source: "deploy.sh",
+		Commands: []models.AnalysisResult{
+			{
+				Command: "az vm list",
+				Line:    3, Column: 5,
+				Permissions: []models.PermissionDetail{{Name: "Microsoft.Compute/virtualMachines/read"}},
+			},
+			{
+				Command: "az role assignment create --role Owner",
+				Line:    7, Column: 1,
+				Permissions: []models.PermissionDetail{{Name: "Microsoft.Authorization/roleAssignments/write"}},
+			},
+			{
+				Command: "az unknown thing",
+				File:    "templates/steps.yml",
+				Line:    2, Column: 3,
+				Error: "unknown service",
+			},
+			{
+				Command:     "az group list",
+				Permissions: []models.PermissionDetail{{Name: "Microsoft.Resources/subscriptions/resourceGroups/read"}},
+			},
+		},
+		Violations: []models.PolicyViolation{
+			{RuleID: "no-owner", Severity: "deny", Message: "Owner assignments are not allowed", Command: "az role assignment create --role Owner", File: "deploy.sh", Line: 7, Column: 1},
+			{RuleID: "no-owner", Severity: "warn", Message: "second hit", Command: "az role assignment create --role Owner"},
+		},
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteSARIFReport(&buffer, result, "1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buffer.Bytes(), &log); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log header: version %q with %d run(s)", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Version != "1.2.3" {
+		t.Errorf("driver version = %q, want 1.2.3", run.Tool.Driver.Version)
+	}
+	if !hasSARIFRule(run.Tool.Driver.Rules, sarifPolicyRulePrefix+"no-owner") {
+		t.Error("policy rule is not described in the driver rules")
+	}
+	if len(run.Tool.Driver.Rules) != len(sarifRules)+1 {
+		t.Errorf("driver has %d rules, want %d", len(run.Tool.Driver.Rules), len(sarifRules)+1)
+	}
+
+	type location struct {
+		uri          string
+		line, column int
+	}
+	want := []struct {
+		ruleID   string
+		level    string
+		location *location
+	}{
+		{sarifRuleRequiredPermissions, "note", &location{"deploy.sh", 3, 5}},
+		{sarifRuleHighRiskPermissions, "error", &location{"deploy.sh", 7, 1}},
+		{sarifRuleUnresolvedCommand, "warning", &location{"templates/steps.yml", 2, 3}},
+		{sarifRuleRequiredPermissions, "note", nil},
+		{sarifPolicyRulePrefix + "no-owner", "error", &location{"deploy.sh", 7, 1}},
+		{sarifPolicyRulePrefix + "no-owner", "warning", nil},
+	}
+	if len(run.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(want))
+	}
+
+	for i, w := range want {
+		got := run.Results[i]
+		if got.RuleID != w.ruleID || got.Level != w.level {
+			t.Errorf("result %d: rule %s level %s, want rule %s level %s", i, got.RuleID, got.Level, w.ruleID, w.level)
+		}
+		if w.location == nil {
+			if len(got.Locations) != 0 {
+				t.Errorf("result %d: unexpected locations %+v", i, got.Locations)
+			}
+			continue
+		}
+		if len(got.Locations) != 1 || got.Locations[0].PhysicalLocation.Region == nil {
+			t.Errorf("result %d: want one location with a region, got %+v", i, got.Locations)
+			continue
+		}
+		physical := got.Locations[0].PhysicalLocation
+		gotLocation := location{physical.ArtifactLocation.URI, physical.Region.StartLine, physical.Region.StartColumn}
+		if gotLocation != *w.location {
+			t.Errorf("result %d: location %+v, want %+v", i, gotLocation, *w.location)
+		}
+	}
+}
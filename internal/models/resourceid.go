@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResourceID is a parsed Azure Resource Manager resource ID or scope
+type ResourceID struct {
+	// ID is the ID as given, without a trailing slash
+	ID string `json:"id"`
+	// SubscriptionID is empty for tenant and management group scopes
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	ResourceGroup  string `json:"resourceGroup,omitempty"`
+	// Provider is the namespace of the resource, e.g. Microsoft.Web; empty for subscription
+	// and resource group scopes
+	Provider string `json:"provider,omitempty"`
+	// ResourceType is the type path within the provider, including the types of parent
+	// resources, e.g. sites/slots
+	ResourceType string `json:"resourceType,omitempty"`
+	// Name is the name of the resource; empty when the ID ends with a resource type
+	Name string `json:"name,omitempty"`
+	// Parent is the resource an extension resource such as a role assignment or lock is
+	// attached to, or nil
+	Parent *ResourceID `json:"parent,omitempty"`
+}
+
+// ParseResourceID parses a resource ID such as
+// /subscriptions/{id}/resourceGroups/{group}/providers/Microsoft.Web/sites/{name}/slots/{slot},
+// a subscription or resource group scope, or an extension resource like
+// {scope}/providers/Microsoft.Authorization/roleAssignments/{name}. Keywords are matched
+// ignoring case.
+func ParseResourceID(id string) (ResourceID, error) {
+	trimmed := strings.TrimSuffix(strings.TrimSpace(id), "/")
+	if !strings.HasPrefix(trimmed, "/") {
+		return ResourceID{}, fmt.Errorf("invalid resource ID %q: must start with /", id)
+	}
+	segments := strings.Split(strings.TrimPrefix(trimmed, "/"), "/")
+	for _, segment := range segments {
+		if segment == "" {
+			return ResourceID{}, fmt.Errorf("invalid resource ID %q: empty segment", id)
+		}
+	}
+
+	result := ResourceID{ID: trimmed}
+	if strings.EqualFold(segments[0], "subscriptions") {
+		if len(segments) < 2 {
+			return ResourceID{}, fmt.Errorf("invalid resource ID %q: missing subscription ID", id)
+		}
+		result.SubscriptionID = segments[1]
+		segments = segments[2:]
+
+		if len(segments) > 0 && strings.EqualFold(segments[0], "resourceGroups") {
+			if len(segments) < 2 {
+				return ResourceID{}, fmt.Errorf("invalid resource ID %q: missing resource group name", id)
+			}
+			result.ResourceGroup = segments[1]
+			segments = segments[2:]
+		}
+	}
+
+	// Every "providers" segment in the position of a resource type starts a resource; each
+	// after the first is an extension resource of the one before it
+	for len(segments) > 0 {
+		if !strings.EqualFold(segments[0], "providers") || len(segments) < 3 {
+			return ResourceID{}, fmt.Errorf("invalid resource ID %q: expected providers/{namespace}/{type}", id)
+		}
+
+		if result.Provider != "" {
+			parent := result
+			parent.ID = trimmed[:len(trimmed)-len(strings.Join(segments, "/"))-1]
+			result = ResourceID{
+				ID:             trimmed,
+				SubscriptionID: parent.SubscriptionID,
+				ResourceGroup:  parent.ResourceGroup,
+				Parent:         &parent,
+			}
+		}
+
+		result.Provider = segments[1]
+		var types []string
+		end := len(segments)
+		for i := 2; i < len(segments); i += 2 {
+			if strings.EqualFold(segments[i], "providers") {
+				end = i
+				break
+			}
+			types = append(types, segments[i])
+			result.Name = ""
+			if i+1 < len(segments) {
+				result.Name = segments[i+1]
+			}
+		}
+		result.ResourceType = strings.Join(types, "/")
+		segments = segments[end:]
+	}
+
+	return result, nil
+}
+
+// IsSubscription reports whether the ID is a subscription scope
+func (id ResourceID) IsSubscription() bool {
+	return id.SubscriptionID != "" && id.ResourceGroup == "" && id.Provider == ""
+}
+
+// IsResourceGroup reports whether the ID is a resource group scope
+func (id ResourceID) IsResourceGroup() bool {
+	return id.ResourceGroup != "" && id.Provider == ""
+}
+
+// FullType returns the namespace and type path, e.g. Microsoft.Web/sites/slots, or "" for
+// subscription and resource group scopes
+func (id ResourceID) FullType() string {
+	if id.Provider == "" {
+		return ""
+	}
+	return id.Provider + "/" + id.ResourceType
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResourceID(t *testing.T) {
+	const (
+		group = "/subscriptions/0000/resourceGroups/rg"
+		site  = group + "/providers/Microsoft.Web/sites/app"
+	)
+
+	tests := []struct {
+		name    string
+		id      string
+		want    ResourceID
+		wantErr bool
+	}{
+		{
+			name: "subscription",
+			id:   "/subscriptions/0000/",
+			want: ResourceID{ID: "/subscriptions/0000", SubscriptionID: "0000"},
+		},
+		{
+			name: "resource group",
+			id:   "/SUBSCRIPTIONS/0000/resourcegroups/rg",
+			want: ResourceID{ID: "/SUBSCRIPTIONS/0000/resourcegroups/rg", SubscriptionID: "0000", ResourceGroup: "rg"},
+		},
+		{
+			name: "resource",
+			id:   site,
+			want: ResourceID{ID: site, SubscriptionID: "0000", ResourceGroup: "rg", Provider: "Microsoft.Web", ResourceType: "sites", Name: "app"},
+		},
+		{
+			name: "nested resource",
+			id:   site + "/slots/staging",
+			want: ResourceID{ID: site + "/slots/staging", SubscriptionID: "0000", ResourceGroup: "rg", Provider: "Microsoft.Web", ResourceType: "sites/slots", Name: "staging"},
+		},
+		{
+			name: "nested resource type",
+			id:   site + "/config",
+			want: ResourceID{ID: site + "/config", SubscriptionID: "0000", ResourceGroup: "rg", Provider: "Microsoft.Web", ResourceType: "sites/config"},
+		},
+		{
+			name: "extension resource",
+			id:   site + "/providers/Microsoft.Authorization/roleAssignments/ra",
+			want: ResourceID{
+				ID:             site + "/providers/Microsoft.Authorization/roleAssignments/ra",
+				SubscriptionID: "0000",
+				ResourceGroup:  "rg",
+				Provider:       "Microsoft.Authorization",
+				ResourceType:   "roleAssignments",
+				Name:           "ra",
+				Parent:         &ResourceID{ID: site, SubscriptionID: "0000", ResourceGroup: "rg", Provider: "Microsoft.Web", ResourceType: "sites", Name: "app"},
+			},
+		},
+		{
+			name: "extension resource of a resource group",
+			id:   group + "/providers/Microsoft.Authorization/locks/lock",
+			want: ResourceID{ID: group + "/providers/Microsoft.Authorization/locks/lock", SubscriptionID: "0000", ResourceGroup: "rg", Provider: "Microsoft.Authorization", ResourceType: "locks", Name: "lock"},
+		},
+		{
+			name: "tenant level resource",
+			id:   "/providers/Microsoft.Management/managementGroups/mg",
+			want: ResourceID{ID: "/providers/Microsoft.Management/managementGroups/mg", Provider: "Microsoft.Management", ResourceType: "managementGroups", Name: "mg"},
+		},
+		{name: "relative", id: "subscriptions/0000", wantErr: true},
+		{name: "empty segment", id: "/subscriptions//resourceGroups/rg", wantErr: true},
+		{name: "missing subscription", id: "/subscriptions", wantErr: true},
+		{name: "missing resource group", id: "/subscriptions/0000/resourceGroups", wantErr: true},
+		{name: "missing resource type", id: group + "/providers/Microsoft.Web", wantErr: true},
+		{name: "not a provider", id: group + "/sites/app", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResourceID(tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseResourceID(%q) = %+v, want an error", tt.id, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResourceID(%q) = %+v, want %+v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceIDScopes(t *testing.T) {
+	tests := []struct {
+		id            string
+		subscription  bool
+		resourceGroup bool
+		fullType      string
+	}{
+		{"/subscriptions/0000", true, false, ""},
+		{"/subscriptions/0000/resourceGroups/rg", false, true, ""},
+		{"/subscriptions/0000/resourceGroups/rg/providers/Microsoft.Web/sites/app/slots/staging", false, false, "Microsoft.Web/sites/slots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			id, err := ParseResourceID(tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := id.IsSubscription(); got != tt.subscription {
+				t.Errorf("IsSubscription() = %v, want %v", got, tt.subscription)
+			}
+			if got := id.IsResourceGroup(); got != tt.resourceGroup {
+				t.Errorf("IsResourceGroup() = %v, want %v", got, tt.resourceGroup)
+			}
+			if got := id.FullType(); got != tt.fullType {
+				t.Errorf("FullType() = %q, want %q", got, tt.fullType)
+			}
+		})
+	}
+}
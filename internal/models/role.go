@@ -0,0 +1,22 @@
+package models
+
+// SetPermissions stores the permission entries of a role definition. A single entry is kept in the
+// flat Actions, NotActions, DataActions and NotDataActions fields. Several entries are kept separately
+// in Permissions, because the NotActions of an entry only subtract from the Actions of that entry;
+// the flat Actions and DataActions then list the patterns of all entries and the flat NotActions and
+// NotDataActions stay empty.
+func (r *RoleDefinition) SetPermissions(entries []Permission) {
+	if len(entries) == 1 {
+		r.Actions = append(r.Actions, entries[0].Actions...)
+		r.NotActions = append(r.NotActions, entries[0].NotActions...)
+		r.DataActions = append(r.DataActions, entries[0].DataActions...)
+		r.NotDataActions = append(r.NotDataActions, entries[0].NotDataActions...)
+		return
+	}
+
+	for _, entry := range entries {
+		r.Permissions = append(r.Permissions, entry)
+		r.Actions = append(r.Actions, entry.Actions...)
+		r.DataActions = append(r.DataActions, entry.DataActions...)
+	}
+}
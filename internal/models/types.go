@@ -3,16 +3,37 @@ package models
 // PermissionMapping represents the structure for command-to-permission mappings
 type PermissionMapping struct {
 	Commands    map[string][]string `json:"commands"`
+	Rules       []ConditionalRule   `json:"rules,omitempty"`
 	LastUpdated string              `json:"last_updated,omitempty"`
 	Source      string              `json:"source,omitempty"`
 }
 
+// ConditionalRule adds permissions to a command when one of its parameters is used.
+// An empty Value matches any value of the parameter.
+type ConditionalRule struct {
+	Command     string   `json:"command"`
+	Parameter   string   `json:"parameter"`
+	Value       string   `json:"value,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// CommandReference describes an Azure CLI command that requires a permission
+type CommandReference struct {
+	Command    string `json:"command"`
+	Permission string `json:"permission"`
+	Condition  string `json:"condition,omitempty"`
+	Source     string `json:"source"`
+}
+
 // AzureCommand represents a parsed Azure CLI command
 type AzureCommand struct {
 	Service    string            `json:"service"`
 	Operation  string            `json:"operation"`
 	Parameters map[string]string `json:"parameters"`
 	FullCmd    string            `json:"full_command"`
+	// Subcommand holds the command words after Operation for groups nested more than two
+	// levels deep, e.g. "list" in "webapp config appsettings list"
+	Subcommand []string `json:"subcommand,omitempty"`
 }
 
 // ProviderOperation represents an Azure Resource Provider operation
@@ -24,6 +45,13 @@ type ProviderOperation struct {
 	IsDataAction bool   `json:"isDataAction"`
 }
 
+// OperationInfo describes a provider operation together with where it lives in the catalog
+type OperationInfo struct {
+	ProviderOperation
+	Provider     string `json:"provider"`
+	ResourceType string `json:"resourceType,omitempty"`
+}
+
 // ResourceType represents an Azure resource type with operations
 type ResourceType struct {
 	Name         string   `json:"name"`
@@ -48,10 +76,12 @@ type ProviderOperationsResponse struct {
 	ResourceTypes []ProviderResourceType  `json:"resourceTypes"`
 }
 
-// ResourceProvider represents an Azure Resource Provider
+// ResourceProvider represents an Azure Resource Provider, as returned by the Providers API
+// and "az provider list"
 type ResourceProvider struct {
-	Namespace  string              `json:"namespace"`
-	Operations []ProviderOperation `json:"operations"`
+	Namespace     string              `json:"namespace"`
+	Operations    []ProviderOperation `json:"operations,omitempty"`
+	ResourceTypes []ResourceType      `json:"resourceTypes,omitempty"`
 }
 
 // CommandToAPIMapping represents mapping from CLI command to REST API
@@ -71,3 +101,265 @@ const (
 	ConfidenceMedium ConfidenceLevel = "medium"
 	ConfidenceLow    ConfidenceLevel = "low"
 )
+
+// RiskLevel represents how much damage misuse of a permission can cause
+type RiskLevel string
+
+const (
+	RiskNone     RiskLevel = "none"
+	RiskLow      RiskLevel = "low"
+	RiskMedium   RiskLevel = "medium"
+	RiskHigh     RiskLevel = "high"
+	RiskCritical RiskLevel = "critical"
+)
+
+// Risk classifies a permission. Heuristic is set when the classification was derived from
+// the operation verb rather than a known rule.
+type Risk struct {
+	Level     RiskLevel `json:"level"`
+	Category  string    `json:"category"`
+	Rationale string    `json:"rationale"`
+	Heuristic bool      `json:"heuristic,omitempty"`
+}
+
+// PermissionDetail describes a single resolved RBAC permission
+type PermissionDetail struct {
+	Name         string `json:"name"`
+	IsDataAction bool   `json:"isDataAction"`
+	Provider     string `json:"provider"`
+	ResourceType string `json:"resourceType,omitempty"`
+	DisplayName  string `json:"displayName,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Risk         *Risk  `json:"risk,omitempty"`
+}
+
+// AnalysisResult represents the permissions resolved for a single Azure CLI command
+type AnalysisResult struct {
+	Command     string             `json:"command"`
+	Service     string             `json:"service"`
+	Operation   string             `json:"operation"`
+	Parameters  map[string]string  `json:"parameters,omitempty"`
+	File        string             `json:"file,omitempty"`
+	Line        int                `json:"line,omitempty"`
+	Column      int                `json:"column,omitempty"`
+	Scope       string             `json:"scope,omitempty"`
+	Scopes      []string           `json:"scopes,omitempty"` // every scope when there are several, e.g. one per --ids resource
+	Permissions []PermissionDetail `json:"permissions"`
+	Confidence  ConfidenceLevel    `json:"confidence"`
+	RiskScore   int                `json:"riskScore"`
+	RiskLevel   RiskLevel          `json:"riskLevel"`
+	Error       string             `json:"error,omitempty"`
+}
+
+// ScanResult represents the aggregated permissions for all Azure CLI commands in a script
+type ScanResult struct {
+	Source      string            `json:"source,omitempty"`
+	Commands    []AnalysisResult  `json:"commands"`
+	Actions     []string          `json:"actions"`
+	DataActions []string          `json:"dataActions"`
+	RiskScore   int               `json:"riskScore"`
+	RiskLevel   RiskLevel         `json:"riskLevel"`
+	Violations  []PolicyViolation `json:"violations,omitempty"`
+}
+
+// RoleDefinition represents an Azure custom role definition in the format accepted by 'az role definition create'
+type RoleDefinition struct {
+	ID               string       `json:"Id,omitempty"`
+	Name             string       `json:"Name"`
+	IsCustom         bool         `json:"IsCustom"`
+	Description      string       `json:"Description"`
+	Actions          []string     `json:"Actions"`
+	NotActions       []string     `json:"NotActions"`
+	DataActions      []string     `json:"DataActions"`
+	NotDataActions   []string     `json:"NotDataActions"`
+	Permissions      []Permission `json:"Permissions,omitempty"`
+	AssignableScopes []string     `json:"AssignableScopes"`
+}
+
+// RoleResult represents a generated custom role together with the commands it covers
+type RoleResult struct {
+	Role     RoleDefinition   `json:"role"`
+	Commands []AnalysisResult `json:"commands"`
+}
+
+// Permission represents a set of allowed and excluded actions, as granted by a role
+// or returned by the Azure permissions API for the current principal
+type Permission struct {
+	Actions        []string `json:"actions"`
+	NotActions     []string `json:"notActions"`
+	DataActions    []string `json:"dataActions"`
+	NotDataActions []string `json:"notDataActions"`
+}
+
+// RoleSuggestion represents a role that grants a set of required permissions.
+// A lower privilege score indicates a narrower role.
+type RoleSuggestion struct {
+	Name           string `json:"name"`
+	ID             string `json:"id,omitempty"`
+	Description    string `json:"description,omitempty"`
+	PrivilegeScore int    `json:"privilegeScore"`
+}
+
+// AuthorizationFailure represents an authorization error reported by Azure for a principal
+type AuthorizationFailure struct {
+	Code      string   `json:"code"`
+	Principal string   `json:"principal,omitempty"`
+	ObjectID  string   `json:"objectId,omitempty"`
+	Actions   []string `json:"actions"`
+	Scopes    []string `json:"scopes"`
+	// GrantedAction and GrantedScope describe the part of a linked authorization that succeeded
+	GrantedAction string `json:"grantedAction,omitempty"`
+	GrantedScope  string `json:"grantedScope,omitempty"`
+}
+
+// ErrorExplanation is an authorization failure enriched with catalog details and remediation steps
+type ErrorExplanation struct {
+	AuthorizationFailure
+	Operations     []OperationInfo  `json:"operations"`
+	SuggestedRoles []RoleSuggestion `json:"suggestedRoles"`
+	Commands       []string         `json:"commands"`
+}
+
+// ObservedAction represents an action recorded in the Azure Activity Log for a caller
+type ObservedAction struct {
+	Action       string   `json:"action"`
+	IsDataAction bool     `json:"isDataAction"`
+	Description  string   `json:"description,omitempty"`
+	Scopes       []string `json:"scopes"`
+	Succeeded    int      `json:"succeeded"`
+	Failed       int      `json:"failed"`
+}
+
+// RoleComparison compares the permissions observed in use with the currently assigned roles
+type RoleComparison struct {
+	AssignedRoles []string `json:"assignedRoles"`
+	// UnusedPatterns are assigned actions that matched no observed operation
+	UnusedPatterns []string `json:"unusedPatterns"`
+	// UncoveredActions are observed operations that the assigned roles do not grant
+	UncoveredActions []string `json:"uncoveredActions"`
+}
+
+// ActivityReport represents the least-privilege permissions derived from an Activity Log export
+type ActivityReport struct {
+	Source     string           `json:"source,omitempty"`
+	Caller     string           `json:"caller,omitempty"`
+	Events     int              `json:"events"`
+	Actions    []ObservedAction `json:"actions"`
+	Role       RoleDefinition   `json:"role"`
+	Comparison *RoleComparison  `json:"comparison,omitempty"`
+}
+
+// OperationChange describes a changed attribute of a provider operation
+type OperationChange struct {
+	Operation string `json:"operation"`
+	Old       string `json:"old"`
+	New       string `json:"new"`
+}
+
+// RoleReference describes a role pattern that references operations removed from the catalog
+type RoleReference struct {
+	Role       string   `json:"role"`
+	Pattern    string   `json:"pattern"`
+	Operations []string `json:"removedOperations"`
+	// Orphaned is set when the pattern no longer matches any operation at all
+	Orphaned bool `json:"orphaned"`
+}
+
+// CatalogDiff represents the differences between two provider operations catalog snapshots
+type CatalogDiff struct {
+	AddedNamespaces      []string          `json:"addedNamespaces"`
+	RemovedNamespaces    []string          `json:"removedNamespaces"`
+	AddedResourceTypes   []string          `json:"addedResourceTypes"`
+	RemovedResourceTypes []string          `json:"removedResourceTypes"`
+	AddedOperations      []string          `json:"addedOperations"`
+	RemovedOperations    []string          `json:"removedOperations"`
+	DataActionChanges    []OperationChange `json:"dataActionChanges"`
+	DisplayNameChanges   []OperationChange `json:"displayNameChanges"`
+	RoleReferences       []RoleReference   `json:"roleReferences,omitempty"`
+}
+
+// PermissionExpansion lists the concrete operations granted by a set of permission patterns
+type PermissionExpansion struct {
+	Role       string          `json:"role,omitempty"`
+	Permission Permission      `json:"permission"`
+	Operations []OperationInfo `json:"operations"`
+}
+
+// Command access levels reported by role inspection
+const (
+	AccessAllowed = "allowed"
+	AccessPartial = "partial"
+	AccessBlocked = "blocked"
+)
+
+// CommandAccess describes whether a role permits an Azure CLI command
+type CommandAccess struct {
+	Command  string   `json:"command"`
+	Access   string   `json:"access"`
+	Required []string `json:"required"`
+	Missing  []string `json:"missing"`
+}
+
+// RoleInspection reports which known Azure CLI commands a role definition enables
+type RoleInspection struct {
+	Role              RoleDefinition  `json:"role"`
+	GrantedOperations int             `json:"grantedOperations"`
+	Allowed           []CommandAccess `json:"allowed"`
+	Partial           []CommandAccess `json:"partial"`
+	Blocked           []CommandAccess `json:"blocked"`
+	Unresolved        []string        `json:"unresolved"`
+}
+
+// OperationMatch is a provider operation found by a catalog search, with its relevance score
+type OperationMatch struct {
+	OperationInfo
+	Score int `json:"score"`
+}
+
+// JobResult represents the Azure CLI commands of a CI job and the permissions they require
+type JobResult struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name,omitempty"`
+	Identity    string           `json:"identity,omitempty"`
+	Commands    []AnalysisResult `json:"commands"`
+	Actions     []string         `json:"actions"`
+	DataActions []string         `json:"dataActions"`
+}
+
+// WorkflowResult represents the permissions required by the jobs of a CI definition
+type WorkflowResult struct {
+	Source      string      `json:"source"`
+	Name        string      `json:"name,omitempty"`
+	Jobs        []JobResult `json:"jobs"`
+	Actions     []string    `json:"actions"`
+	DataActions []string    `json:"dataActions"`
+}
+
+// ConnectionResult represents the permissions an Azure Pipelines service connection needs
+type ConnectionResult struct {
+	Name        string           `json:"name"`
+	Commands    []AnalysisResult `json:"commands"`
+	Actions     []string         `json:"actions"`
+	DataActions []string         `json:"dataActions"`
+}
+
+// PipelineResult represents the permissions required by an Azure Pipelines definition, per service connection
+type PipelineResult struct {
+	Source      string             `json:"source"`
+	Name        string             `json:"name,omitempty"`
+	Files       []string           `json:"files"`
+	Connections []ConnectionResult `json:"connections"`
+	Warnings    []string           `json:"warnings,omitempty"`
+}
+
+// PolicyViolation represents a command that violates a permission policy rule
+type PolicyViolation struct {
+	RuleID     string `json:"ruleId"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	Command    string `json:"command"`
+	Permission string `json:"permission,omitempty"`
+	File       string `json:"file,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+}
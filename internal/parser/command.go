@@ -7,6 +7,20 @@ import (
 	"github.com/mathwro/azperm/internal/models"
 )
 
+// shortParameterAliases maps common Azure CLI short options to their long parameter names
+var shortParameterAliases = map[string]string{
+	"n": "name",
+	"g": "resource-group",
+	"l": "location",
+	"o": "output",
+	"h": "help",
+}
+
+// multiValueParameters are the parameters that take a space-separated list of values
+var multiValueParameters = map[string]bool{
+	"ids": true,
+}
+
 // ParseAzureCommand parses an Azure CLI command string into a structured command
 func ParseAzureCommand(input string) (*models.AzureCommand, error) {
 	// Remove 'az' prefix if present and normalize
@@ -28,23 +42,38 @@ func ParseAzureCommand(input string) (*models.AzureCommand, error) {
 	operation := parts[1]
 
 	// Handle multi-part services (e.g., "network vnet", "storage account")
-	if len(parts) > 2 && !strings.HasPrefix(parts[2], "--") {
+	if len(parts) > 2 && !strings.HasPrefix(parts[2], "-") && !isRedirection(parts[2]) {
 		service = parts[0] + " " + parts[1]
 		operation = parts[2]
 		parts = parts[1:] // Adjust parts for parameter parsing
 	}
 
+	// Deeper groups leave words before the first parameter, e.g. "list" in
+	// "webapp config appsettings list"
+	var subcommand []string
+	for i := 2; i < len(parts) && !strings.HasPrefix(parts[i], "-") && !isRedirection(parts[i]); i++ {
+		subcommand = append(subcommand, parts[i])
+	}
+
 	// Parse parameters
 	parameters := make(map[string]string)
 	for i := 2; i < len(parts); i++ {
-		if strings.HasPrefix(parts[i], "--") {
-			paramName := strings.TrimPrefix(parts[i], "--")
-			paramValue := ""
-
-			// Check if next part is the value (not another parameter)
-			if i+1 < len(parts) && !strings.HasPrefix(parts[i+1], "--") {
-				paramValue = parts[i+1]
-				i++ // Skip the value in next iteration
+		if strings.HasPrefix(parts[i], "-") {
+			option, paramValue, hasValue := strings.Cut(parts[i], "=")
+			paramName := parameterName(option)
+
+			// Check if next part is the value (not another parameter). Parameters taking a
+			// list, such as "--ids id1 id2", keep every value, joined with spaces.
+			if !hasValue {
+				var values []string
+				for i+1 < len(parts) && !strings.HasPrefix(parts[i+1], "-") && !isRedirection(parts[i+1]) {
+					values = append(values, parts[i+1])
+					i++ // Skip the value in next iteration
+					if !multiValueParameters[paramName] {
+						break
+					}
+				}
+				paramValue = strings.Join(values, " ")
 			}
 
 			parameters[paramName] = paramValue
@@ -56,5 +85,31 @@ func ParseAzureCommand(input string) (*models.AzureCommand, error) {
 		Operation:  operation,
 		Parameters: parameters,
 		FullCmd:    fmt.Sprintf("%s %s", service, operation),
+		Subcommand: subcommand,
 	}, nil
 }
+
+// parameterName normalizes a "--name" or "-n" option to its long parameter name
+func parameterName(option string) string {
+	if strings.HasPrefix(option, "--") {
+		return strings.TrimPrefix(option, "--")
+	}
+
+	short := strings.TrimPrefix(option, "-")
+	if long, exists := shortParameterAliases[short]; exists {
+		return long
+	}
+	return short
+}
+
+// isRedirection reports whether a command line part is a shell redirection: a part starting
+// with ">" or "<", such as ">", ">>", ">out.txt" or "<in.txt", or with a file descriptor
+// number followed by ">", such as "2>&1". Values containing ">", such as a --query
+// expression, are not redirections.
+func isRedirection(part string) bool {
+	if strings.HasPrefix(part, ">") || strings.HasPrefix(part, "<") {
+		return true
+	}
+	rest := strings.TrimLeft(part, "0123456789")
+	return len(rest) < len(part) && strings.HasPrefix(rest, ">")
+}
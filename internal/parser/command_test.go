@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAzureCommand(t *testing.T) {
+	const (
+		site = "/subscriptions/0000/resourceGroups/rg/providers/Microsoft.Web/sites/app"
+		slot = site + "/slots/staging"
+	)
+
+	tests := []struct {
+		command        string
+		wantService    string
+		wantOperation  string
+		wantSubcommand []string
+		wantParameters map[string]string
+	}{
+		{
+			command:        "az vm create -n vm1 -g rg --image Ubuntu2204",
+			wantService:    "vm",
+			wantOperation:  "create",
+			wantParameters: map[string]string{"name": "vm1", "resource-group": "rg", "image": "Ubuntu2204"},
+		},
+		{
+			command:        "az storage account show --name=st",
+			wantService:    "storage account",
+			wantOperation:  "show",
+			wantParameters: map[string]string{"name": "st"},
+		},
+		{
+			command:        "az webapp config appsettings list --ids " + site,
+			wantService:    "webapp config",
+			wantOperation:  "appsettings",
+			wantSubcommand: []string{"list"},
+			wantParameters: map[string]string{"ids": site},
+		},
+		{
+			command:        "az webapp show --ids " + site + " " + slot + " > out.txt",
+			wantService:    "webapp",
+			wantOperation:  "show",
+			wantParameters: map[string]string{"ids": site + " " + slot},
+		},
+		{
+			command:        "az vm list >vms.json 2>&1",
+			wantService:    "vm",
+			wantOperation:  "list",
+			wantParameters: map[string]string{},
+		},
+		{
+			command:        "az vm show -n vm1 2> errors.txt",
+			wantService:    "vm",
+			wantOperation:  "show",
+			wantParameters: map[string]string{"name": "vm1"},
+		},
+		{
+			command:        "az vm list --query [?size>2] < input.txt",
+			wantService:    "vm",
+			wantOperation:  "list",
+			wantParameters: map[string]string{"query": "[?size>2]"},
+		},
+		{
+			command:        "az vm list --query name->id",
+			wantService:    "vm",
+			wantOperation:  "list",
+			wantParameters: map[string]string{"query": "name->id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			cmd, err := ParseAzureCommand(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmd.Service != tt.wantService || cmd.Operation != tt.wantOperation {
+				t.Errorf("ParseAzureCommand() = %q %q, want %q %q", cmd.Service, cmd.Operation, tt.wantService, tt.wantOperation)
+			}
+			if !reflect.DeepEqual(cmd.Subcommand, tt.wantSubcommand) {
+				t.Errorf("Subcommand = %q, want %q", cmd.Subcommand, tt.wantSubcommand)
+			}
+			if !reflect.DeepEqual(cmd.Parameters, tt.wantParameters) {
+				t.Errorf("Parameters = %v, want %v", cmd.Parameters, tt.wantParameters)
+			}
+		})
+	}
+
+	if _, err := ParseAzureCommand("az vm"); err == nil {
+		t.Error("ParseAzureCommand() accepted a command without an operation")
+	}
+}
+
+func TestIsRedirection(t *testing.T) {
+	tests := []struct {
+		part string
+		want bool
+	}{
+		{">", true},
+		{">>", true},
+		{"<", true},
+		{"2>&1", true},
+		{"2>", true},
+		{">out.txt", true},
+		{"<in.txt", true},
+		{"[?size>2]", false},
+		{"name->id", false},
+		{"a<b", false},
+		{"2", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.part, func(t *testing.T) {
+			if got := isRedirection(tt.part); got != tt.want {
+				t.Errorf("isRedirection(%q) = %v, want %v", tt.part, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// KnownCommands lists common Azure CLI commands (without the "az" prefix)
+var KnownCommands = []string{
+	"group create", "group delete", "group list", "group show", "group update", "group exists",
+	"vm create", "vm delete", "vm list", "vm show", "vm start", "vm stop", "vm restart",
+	"vm deallocate", "vm update", "vm resize", "vm run-command invoke",
+	"vm identity assign", "vm extension set",
+	"storage account create", "storage account delete", "storage account list", "storage account show",
+	"storage account update", "storage account keys list",
+	"storage container create", "storage container delete", "storage container list",
+	"storage blob upload", "storage blob download", "storage blob delete", "storage blob list",
+	"storage queue create", "storage share create", "storage table create",
+	"webapp create", "webapp delete", "webapp list", "webapp show", "webapp start", "webapp stop",
+	"webapp restart", "webapp config appsettings set", "webapp config appsettings list",
+	"webapp deployment source config-zip",
+	"functionapp create", "functionapp delete", "functionapp list", "functionapp show",
+	"keyvault create", "keyvault delete", "keyvault list", "keyvault show", "keyvault set-policy",
+	"keyvault secret set", "keyvault secret show", "keyvault secret list", "keyvault secret delete",
+	"keyvault key create", "keyvault key list", "keyvault key delete",
+	"keyvault certificate create", "keyvault certificate list",
+	"network vnet create", "network vnet delete", "network vnet list", "network vnet show",
+	"network vnet subnet create", "network vnet subnet list",
+	"network nsg create", "network nsg delete", "network nsg list", "network nsg rule create",
+	"network public-ip create", "network public-ip list", "network nic create", "network lb create",
+	"sql server create", "sql server delete", "sql server list", "sql server show",
+	"sql db create", "sql db delete", "sql db list", "sql db show",
+	"aks create", "aks delete", "aks list", "aks show", "aks start", "aks stop", "aks get-credentials",
+	"aks scale", "aks upgrade",
+	"container create", "container delete", "container list", "container show", "container restart",
+	"role assignment create", "role assignment delete", "role assignment list",
+	"role definition create", "role definition list",
+	"cosmosdb create", "cosmosdb delete", "cosmosdb list", "cosmosdb show",
+	"redis create", "redis delete", "redis list", "redis show",
+	"acr create", "acr delete", "acr list", "acr show", "acr login",
+	"monitor diagnostic-settings create", "monitor metrics list", "monitor activity-log list",
+	"identity create", "identity delete", "identity list", "identity show",
+	"resource list", "resource show", "resource delete", "resource tag",
+	"deployment group create", "deployment sub create",
+}
+
+// CommonParameters lists parameters accepted by most Azure CLI commands
+var CommonParameters = []string{
+	"--name", "--resource-group", "--subscription", "--location", "--ids", "--tags",
+	"--no-wait", "--yes", "--output", "--query", "--debug", "--verbose", "--only-show-errors",
+}
+
+// CommandParameters lists command specific parameters for known commands
+var CommandParameters = map[string][]string{
+	"vm create": {
+		"--image", "--size", "--admin-username", "--admin-password", "--generate-ssh-keys",
+		"--vnet-name", "--subnet", "--nsg", "--public-ip-address", "--assign-identity", "--zone",
+	},
+	"vm run-command invoke":  {"--command-id", "--scripts"},
+	"storage account create": {"--sku", "--kind", "--access-tier", "--https-only", "--allow-blob-public-access"},
+	"storage container create": {
+		"--account-name", "--auth-mode", "--public-access", "--account-key",
+	},
+	"storage blob upload":   {"--account-name", "--container-name", "--file", "--auth-mode", "--overwrite"},
+	"storage blob download": {"--account-name", "--container-name", "--file", "--auth-mode"},
+	"webapp create":         {"--plan", "--runtime", "--deployment-container-image-name", "--assign-identity"},
+	"keyvault create":       {"--sku", "--enable-rbac-authorization", "--retention-days"},
+	"keyvault secret set":   {"--vault-name", "--value", "--file", "--expires"},
+	"keyvault secret show":  {"--vault-name", "--version"},
+	"network vnet create":   {"--address-prefixes", "--subnet-name", "--subnet-prefixes"},
+	"aks create": {
+		"--node-count", "--node-vm-size", "--enable-managed-identity", "--attach-acr",
+		"--vnet-subnet-id", "--generate-ssh-keys",
+	},
+	"role assignment create": {"--assignee", "--assignee-object-id", "--role", "--scope"},
+}
+
+// CompleteCommand returns the candidates for the next word of a partially typed command.
+// words are the complete words typed so far (without "az"), prefix is the word being typed.
+func CompleteCommand(words []string, prefix string) []string {
+	typed := strings.Join(words, " ")
+	seen := make(map[string]bool)
+	var candidates []string
+
+	for _, command := range KnownCommands {
+		parts := strings.Fields(command)
+		if len(parts) <= len(words) || strings.Join(parts[:len(words)], " ") != typed {
+			continue
+		}
+
+		next := parts[len(words)]
+		if strings.HasPrefix(next, prefix) && !seen[next] {
+			seen[next] = true
+			candidates = append(candidates, next)
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
+
+// CompleteParameter returns the parameter candidates for a command that match the typed prefix
+func CompleteParameter(command string, prefix string) []string {
+	seen := make(map[string]bool)
+	var candidates []string
+
+	for _, parameter := range append(append([]string{}, CommandParameters[command]...), CommonParameters...) {
+		if strings.HasPrefix(parameter, prefix) && !seen[parameter] {
+			seen[parameter] = true
+			candidates = append(candidates, parameter)
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
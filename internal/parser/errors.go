@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// Authorization failure codes recognized by ParseAuthorizationErrors
+const (
+	CodeAuthorizationFailed       = "AuthorizationFailed"
+	CodeLinkedAuthorizationFailed = "LinkedAuthorizationFailed"
+	CodeForbiddenByRbac           = "ForbiddenByRbac"
+)
+
+var (
+	// authorizationFailedRegex matches ARM "AuthorizationFailed" messages
+	authorizationFailedRegex = regexp.MustCompile(`The client '([^']*)' with object id '([^']*)' does not have authorization to perform action '([^']+)' over scope '([^']+)'`)
+
+	// linkedAuthorizationFailedRegex matches ARM "LinkedAuthorizationFailed" messages
+	linkedAuthorizationFailedRegex = regexp.MustCompile(`The client '([^']*)' with object id '([^']*)' has permission to perform action '([^']+)' on scope '([^']+)'; however, it does not have permission to perform action\(s\) '([^']+)' on the linked scope\(s\) '([^']+)'`)
+
+	// forbiddenByRbacRegex matches data plane RBAC errors such as those returned by Key Vault
+	forbiddenByRbacRegex = regexp.MustCompile(`Caller is not authorized to perform action on resource\.(.*?)Action: '([^']+)'\s*Resource: '([^']+)'`)
+
+	// callerObjectIDRegex extracts the object id from the "Caller: appid=...;oid=...;iss=..." line of data plane errors
+	callerObjectIDRegex = regexp.MustCompile(`oid=([0-9a-fA-F-]+)`)
+)
+
+// ParseAuthorizationErrors extracts every authorization failure from Azure CLI error output.
+// Identical failures repeated in the text are reported once.
+func ParseAuthorizationErrors(text string) []models.AuthorizationFailure {
+	// Messages are frequently wrapped over several lines by terminals and log files
+	text = strings.Join(strings.Fields(text), " ")
+
+	type located struct {
+		offset  int
+		failure models.AuthorizationFailure
+	}
+	var found []located
+
+	for _, match := range linkedAuthorizationFailedRegex.FindAllStringSubmatchIndex(text, -1) {
+		group := submatches(text, match)
+		found = append(found, located{match[0], models.AuthorizationFailure{
+			Code:          CodeLinkedAuthorizationFailed,
+			Principal:     group[1],
+			ObjectID:      group[2],
+			GrantedAction: group[3],
+			GrantedScope:  group[4],
+			Actions:       splitList(group[5]),
+			Scopes:        splitList(group[6]),
+		}})
+	}
+
+	for _, match := range authorizationFailedRegex.FindAllStringSubmatchIndex(text, -1) {
+		group := submatches(text, match)
+		found = append(found, located{match[0], models.AuthorizationFailure{
+			Code:      CodeAuthorizationFailed,
+			Principal: group[1],
+			ObjectID:  group[2],
+			Actions:   []string{group[3]},
+			Scopes:    []string{group[4]},
+		}})
+	}
+
+	for _, match := range forbiddenByRbacRegex.FindAllStringSubmatchIndex(text, -1) {
+		group := submatches(text, match)
+		failure := models.AuthorizationFailure{
+			Code:    CodeForbiddenByRbac,
+			Actions: []string{group[2]},
+			Scopes:  []string{group[3]},
+		}
+		if caller := callerObjectIDRegex.FindStringSubmatch(group[1]); caller != nil {
+			failure.ObjectID = caller[1]
+		}
+		found = append(found, located{match[0], failure})
+	}
+
+	// Report failures in the order they appear in the text
+	for i := 1; i < len(found); i++ {
+		for j := i; j > 0 && found[j].offset < found[j-1].offset; j-- {
+			found[j], found[j-1] = found[j-1], found[j]
+		}
+	}
+
+	seen := make(map[string]bool)
+	var failures []models.AuthorizationFailure
+	for _, item := range found {
+		key := strings.ToLower(strings.Join([]string{
+			item.failure.Code, item.failure.ObjectID,
+			strings.Join(item.failure.Actions, ","), strings.Join(item.failure.Scopes, ","),
+		}, "|"))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		failures = append(failures, item.failure)
+	}
+
+	return failures
+}
+
+// submatches returns the capture groups of a match found with FindAllStringSubmatchIndex
+func submatches(text string, match []int) []string {
+	groups := make([]string, len(match)/2)
+	for i := range groups {
+		if match[2*i] >= 0 {
+			groups[i] = text[match[2*i]:match[2*i+1]]
+		}
+	}
+	return groups
+}
+
+// splitList splits a comma separated list of actions or scopes
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+const (
+	vmScope   = "/subscriptions/0000/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1"
+	nicScope  = "/subscriptions/0000/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic1"
+	subnet    = "/subscriptions/0000/resourceGroups/net/providers/Microsoft.Network/virtualNetworks/vnet/subnets/default"
+	nsg       = "/subscriptions/0000/resourceGroups/net/providers/Microsoft.Network/networkSecurityGroups/nsg"
+	secretRef = "/subscriptions/0000/resourcegroups/rg/providers/microsoft.keyvault/vaults/kv/secrets/password"
+)
+
+// az prints ARM errors as "(Code) message" followed by the same message after "Message:"
+const authorizationFailed = `ERROR: (AuthorizationFailed) The client 'dev@contoso.com' with object id '11111111-1111-1111-1111-111111111111' does not have authorization to perform action 'Microsoft.Compute/virtualMachines/write' over scope '` + vmScope + `' or the scope is invalid. If access was recently granted, please refresh your credentials.
+Code: AuthorizationFailed
+Message: The client 'dev@contoso.com' with object id '11111111-1111-1111-1111-111111111111' does not have authorization to perform action 'Microsoft.Compute/virtualMachines/write' over scope '` + vmScope + `' or the scope is invalid. If access was recently granted, please refresh your credentials.
+`
+
+const linkedAuthorizationFailed = `ERROR: (LinkedAuthorizationFailed) The client 'dev@contoso.com' with object id '11111111-1111-1111-1111-111111111111' has permission to perform action 'Microsoft.Network/networkInterfaces/write' on scope '` + nicScope + `'; however, it does not have permission to perform action(s) 'Microsoft.Network/virtualNetworks/subnets/join/action,Microsoft.Network/networkSecurityGroups/join/action' on the linked scope(s) '` + subnet + `,` + nsg + `' (respectively) or the linked scope(s) are invalid.
+Code: LinkedAuthorizationFailed
+`
+
+// Key Vault reports data plane RBAC failures without a client name, with the caller's
+// object id in the Caller line
+const forbiddenByRbac = `ERROR: (Forbidden) Caller is not authorized to perform action on resource.
+If role assignments, deny assignments or role definitions were changed recently, please observe propagation time.
+Caller: appid=04b07795-8ddb-461a-bbee-02f9e1bf7b46;oid=22222222-2222-2222-2222-222222222222;iss=https://sts.windows.net/tenant/
+Action: 'Microsoft.KeyVault/vaults/secrets/getSecret/action'
+Resource: '` + secretRef + `'
+Assignment: (not found)
+DenyAssignmentId: null
+Vault: kv;location=westeurope
+
+Code: Forbidden
+Message: Caller is not authorized to perform action on resource.
+Inner error: {
+    "code": "ForbiddenByRbac"
+}
+`
+
+func TestParseAuthorizationErrors(t *testing.T) {
+	vmWrite := models.AuthorizationFailure{
+		Code:      CodeAuthorizationFailed,
+		Principal: "dev@contoso.com",
+		ObjectID:  "11111111-1111-1111-1111-111111111111",
+		Actions:   []string{"Microsoft.Compute/virtualMachines/write"},
+		Scopes:    []string{vmScope},
+	}
+	join := models.AuthorizationFailure{
+		Code:          CodeLinkedAuthorizationFailed,
+		Principal:     "dev@contoso.com",
+		ObjectID:      "11111111-1111-1111-1111-111111111111",
+		GrantedAction: "Microsoft.Network/networkInterfaces/write",
+		GrantedScope:  nicScope,
+		Actions:       []string{"Microsoft.Network/virtualNetworks/subnets/join/action", "Microsoft.Network/networkSecurityGroups/join/action"},
+		Scopes:        []string{subnet, nsg},
+	}
+	getSecret := models.AuthorizationFailure{
+		Code:     CodeForbiddenByRbac,
+		ObjectID: "22222222-2222-2222-2222-222222222222",
+		Actions:  []string{"Microsoft.KeyVault/vaults/secrets/getSecret/action"},
+		Scopes:   []string{secretRef},
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []models.AuthorizationFailure
+	}{
+		{name: "authorization failed reported once", text: authorizationFailed, want: []models.AuthorizationFailure{vmWrite}},
+		{name: "linked scopes", text: linkedAuthorizationFailed, want: []models.AuthorizationFailure{join}},
+		{name: "caller object id", text: forbiddenByRbac, want: []models.AuthorizationFailure{getSecret}},
+		{
+			name: "several errors in order",
+			text: forbiddenByRbac + "\n" + linkedAuthorizationFailed + "\n" + authorizationFailed,
+			want: []models.AuthorizationFailure{getSecret, join, vmWrite},
+		},
+		{
+			name: "message wrapped over lines",
+			text: "The client 'dev@contoso.com' with object id\n  '11111111-1111-1111-1111-111111111111' does not have authorization to perform\n  action 'Microsoft.Compute/virtualMachines/write' over scope\n  '" + vmScope + "'",
+			want: []models.AuthorizationFailure{vmWrite},
+		},
+		{
+			name: "no authorization failure",
+			text: "ERROR: (ResourceGroupNotFound) Resource group 'rg' could not be found.\nCode: ResourceGroupNotFound",
+		},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAuthorizationErrors(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAuthorizationErrors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package permissions
+
+import (
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+)
+
+// InspectRole reports which of the given Azure CLI commands (without the "az" prefix) the role
+// fully allows, partially allows or blocks. Commands whose permissions cannot be resolved
+// are listed as unresolved.
+func (r *Resolver) InspectRole(role models.RoleDefinition, commands []string, store *catalog.Store) models.RoleInspection {
+	grants := RolePermissions(role)
+
+	inspection := models.RoleInspection{
+		Role:              role,
+		GrantedOperations: len(expandGrants(grants, store.Operations())),
+		Allowed:           []models.CommandAccess{},
+		Partial:           []models.CommandAccess{},
+		Blocked:           []models.CommandAccess{},
+		Unresolved:        []string{},
+	}
+
+	for _, command := range commands {
+		cmd, err := parser.ParseAzureCommand("az " + command)
+		if err != nil {
+			inspection.Unresolved = append(inspection.Unresolved, "az "+command)
+			continue
+		}
+		required, err := r.Resolve(cmd, store)
+		if err != nil || len(required) == 0 {
+			inspection.Unresolved = append(inspection.Unresolved, "az "+command)
+			continue
+		}
+
+		missing := MissingPermissions(required, grants)
+		access := models.CommandAccess{
+			Command:  "az " + command,
+			Required: PermissionNames(required),
+			Missing:  PermissionNames(missing),
+		}
+
+		switch len(missing) {
+		case 0:
+			access.Access = models.AccessAllowed
+			inspection.Allowed = append(inspection.Allowed, access)
+		case len(required):
+			access.Access = models.AccessBlocked
+			inspection.Blocked = append(inspection.Blocked, access)
+		default:
+			access.Access = models.AccessPartial
+			inspection.Partial = append(inspection.Partial, access)
+		}
+	}
+
+	return inspection
+}
@@ -0,0 +1,86 @@
+package permissions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+)
+
+func TestInspectRole(t *testing.T) {
+	store := catalog.NewStore(catalogtest.Realistic())
+	role := models.RoleDefinition{
+		Name:       "Virtual Machine Operator",
+		Actions:    []string{"Microsoft.Compute/virtualMachines/*", "*/read"},
+		NotActions: []string{"Microsoft.Compute/virtualMachines/delete", "Microsoft.Compute/virtualMachines/restart/action"},
+	}
+	commands := []string{"vm create", "vm show", "vm start", "vm delete", "vm restart", "keyvault create", "keyvault secret show", "unknownservice list"}
+
+	resolver := NewResolver(nil)
+	inspection := resolver.InspectRole(role, commands, store)
+
+	access := func(accesses []models.CommandAccess) []string {
+		commands := []string{}
+		for _, access := range accesses {
+			commands = append(commands, access.Command)
+		}
+		return commands
+	}
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"allowed", access(inspection.Allowed), []string{"az vm create", "az vm show"}},
+		// vm start also needs restart/action, which NotActions exclude, and */read does not
+		// grant the DataAction of keyvault secret show
+		{"partial", access(inspection.Partial), []string{"az vm start", "az keyvault secret show"}},
+		{"blocked", access(inspection.Blocked), []string{"az vm delete", "az vm restart", "az keyvault create"}},
+		{"unresolved", inspection.Unresolved, []string{"az unknownservice list"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Missing permissions are the required ones the role does not grant
+	grants := RolePermissions(role)
+	for _, accesses := range [][]models.CommandAccess{inspection.Allowed, inspection.Partial, inspection.Blocked} {
+		for _, access := range accesses {
+			cmd, err := parser.ParseAzureCommand(access.Command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			required, err := resolver.Resolve(cmd, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			missing := []string{}
+			for _, permission := range required {
+				if !IsGranted(permission.Name, permission.IsDataAction, grants) {
+					missing = append(missing, permission.Name)
+				}
+			}
+			if got := append([]string{}, access.Missing...); !reflect.DeepEqual(got, missing) {
+				t.Errorf("%s missing = %v, want %v", access.Command, access.Missing, missing)
+			}
+		}
+	}
+	if len(inspection.Partial) > 0 && !reflect.DeepEqual(inspection.Partial[0].Missing, []string{"Microsoft.Compute/virtualMachines/restart/action"}) {
+		t.Errorf("az vm start missing = %v", inspection.Partial[0].Missing)
+	}
+
+	granted := 0
+	for _, operation := range store.Operations() {
+		if IsGranted(operation.Name, operation.IsDataAction, grants) {
+			granted++
+		}
+	}
+	if inspection.GrantedOperations != granted {
+		t.Errorf("GrantedOperations = %d, want %d", inspection.GrantedOperations, granted)
+	}
+}
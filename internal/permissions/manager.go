@@ -2,15 +2,24 @@ package permissions
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/registry"
 )
 
-// Manager handles permission mappings and caching
+// Manager handles permission mappings and caching.
+// All methods are safe for concurrent use.
 type Manager struct {
+	mu       sync.RWMutex
 	mappings models.PermissionMapping
+	// index maps lowercase permissions to the commands and rules that require them
+	index map[string][]models.CommandReference
+	// overrides replace the built-in mappings of their commands
+	overrides models.PermissionMapping
 }
 
 // NewManager creates a new permission manager
@@ -30,6 +39,9 @@ func (m *Manager) LoadPermissions() {
 
 // loadDefaultPermissions sets up default permission mappings
 func (m *Manager) loadDefaultPermissions() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.mappings = models.PermissionMapping{
 		Commands: map[string][]string{
 			"group create": {
@@ -47,17 +59,60 @@ func (m *Manager) loadDefaultPermissions() {
 			"storage account create": {
 				"Microsoft.Storage/storageAccounts/write",
 			},
+			"vm create": {
+				"Microsoft.Compute/virtualMachines/write",
+				"Microsoft.Network/networkInterfaces/write",
+				"Microsoft.Network/networkInterfaces/join/action",
+			},
+			"network nic create": {
+				"Microsoft.Network/networkInterfaces/write",
+			},
+			"network private-endpoint create": {
+				"Microsoft.Network/privateEndpoints/write",
+				"Microsoft.Network/virtualNetworks/subnets/join/action",
+			},
+			"webapp vnet-integration add": {
+				"Microsoft.Web/sites/config/write",
+				"Microsoft.Network/virtualNetworks/subnets/join/action",
+			},
+			"aks create": {
+				"Microsoft.ContainerService/managedClusters/write",
+			},
+		},
+		Rules: []models.ConditionalRule{
+			{Command: "vm create", Parameter: "subnet", Permissions: []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}},
+			{Command: "vm create", Parameter: "vnet-name", Permissions: []string{"Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/subnets/join/action"}},
+			{Command: "vm create", Parameter: "public-ip-address", Permissions: []string{"Microsoft.Network/publicIPAddresses/write", "Microsoft.Network/publicIPAddresses/join/action"}},
+			{Command: "vm create", Parameter: "nsg", Permissions: []string{"Microsoft.Network/networkSecurityGroups/join/action"}},
+			{Command: "vm create", Parameter: "assign-identity", Permissions: []string{"Microsoft.ManagedIdentity/userAssignedIdentities/assign/action"}},
+			{Command: "vm create", Parameter: "scope", Permissions: []string{"Microsoft.Authorization/roleAssignments/write"}},
+			{Command: "vm create", Parameter: "attach-os-disk", Permissions: []string{"Microsoft.Compute/disks/read", "Microsoft.Compute/disks/write"}},
+			{Command: "network nic create", Parameter: "subnet", Permissions: []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}},
+			{Command: "network nic create", Parameter: "network-security-group", Permissions: []string{"Microsoft.Network/networkSecurityGroups/join/action"}},
+			{Command: "network nic create", Parameter: "public-ip-address", Permissions: []string{"Microsoft.Network/publicIPAddresses/join/action"}},
+			{Command: "aks create", Parameter: "vnet-subnet-id", Permissions: []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}},
+			{Command: "aks create", Parameter: "attach-acr", Permissions: []string{"Microsoft.Authorization/roleAssignments/write"}},
+			{Command: "aks create", Parameter: "enable-managed-identity", Permissions: []string{"Microsoft.ManagedIdentity/userAssignedIdentities/assign/action"}},
+			{Command: "storage account create", Parameter: "subnet", Permissions: []string{"Microsoft.Network/virtualNetworks/subnets/joinViaServiceEndpoint/action"}},
+			{Command: "storage account create", Parameter: "assign-identity", Permissions: []string{"Microsoft.ManagedIdentity/userAssignedIdentities/assign/action"}},
+			{Command: "webapp create", Parameter: "vnet", Permissions: []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}},
+			{Command: "sql server create", Parameter: "assign-identity", Permissions: []string{"Microsoft.ManagedIdentity/userAssignedIdentities/assign/action"}},
 		},
 		LastUpdated: "built-in",
 		Source:      "default-minimal",
 	}
+	m.applyOverrides()
+	m.rebuildIndex()
 }
 
 // GetPermissions retrieves permissions for a command with fallback logic
 func (m *Manager) GetPermissions(cmd *models.AzureCommand) ([]string, models.ConfidenceLevel) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	// Check for exact match in our database
 	if permissions, exists := m.mappings.Commands[cmd.FullCmd]; exists {
-		return permissions, models.ConfidenceMedium
+		return m.applyRules(cmd, permissions), models.ConfidenceMedium
 	}
 
 	// Try to find partial matches
@@ -123,11 +178,18 @@ func (m *Manager) suggestPermissionsByOperation(cmd *models.AzureCommand) []stri
 
 // UpdateMappings updates the internal mappings with new data
 func (m *Manager) UpdateMappings(newMappings models.PermissionMapping) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.mappings = newMappings
+	m.rebuildIndex()
 }
 
 // GetMappings returns the current permission mappings
 func (m *Manager) GetMappings() models.PermissionMapping {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return m.mappings
 }
 
@@ -148,71 +210,118 @@ func (m *Manager) SetMappingsWithAPIIntegration(commands []string, operations ma
 		Source:      "azure-rest-api-integration",
 	}
 
+	m.mu.Lock()
 	m.mappings = newMappings
+	m.rebuildIndex()
+	m.mu.Unlock()
+
 	return newMappings
 }
 
-// getResourceProviderForService maps service names to Azure resource providers
-func getResourceProviderForService(service string) string {
-	serviceMap := map[string]string{
-		"group":             "Microsoft.Resources",
-		"vm":                "Microsoft.Compute",
-		"storage":           "Microsoft.Storage",
-		"webapp":            "Microsoft.Web",
-		"functionapp":       "Microsoft.Web",
-		"keyvault":          "Microsoft.KeyVault",
-		"network":           "Microsoft.Network",
-		"sql":               "Microsoft.Sql",
-		"aks":               "Microsoft.ContainerService",
-		"cosmosdb":          "Microsoft.DocumentDB",
-		"role":              "Microsoft.Authorization",
-		"ad":                "Microsoft.Graph",
-		"monitor":           "Microsoft.Insights",
-		"backup":            "Microsoft.RecoveryServices",
-		"cdn":               "Microsoft.Cdn",
-		"redis":             "Microsoft.Cache",
-		"servicebus":        "Microsoft.ServiceBus",
-		"eventhub":          "Microsoft.EventHub",
-		"iot":               "Microsoft.Devices",
-		"batch":             "Microsoft.Batch",
-		"hdinsight":         "Microsoft.HDInsight",
-		"search":            "Microsoft.Search",
-		"cognitiveservices": "Microsoft.CognitiveServices",
+// CommandsForPermission returns the mapped commands that require a permission, including the
+// parameter conditions of conditional rules. The permission may contain '*' wildcards.
+func (m *Manager) CommandsForPermission(permission string) []models.CommandReference {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	references := []models.CommandReference{}
+	if !strings.Contains(permission, "*") {
+		return append(references, m.index[strings.ToLower(permission)]...)
 	}
 
-	// Handle compound services
-	for key, provider := range serviceMap {
-		if strings.Contains(service, key) {
-			return provider
+	for key, entries := range m.index {
+		if MatchesPattern(permission, key) {
+			references = append(references, entries...)
+		}
+	}
+	sortReferences(references)
+	return references
+}
+
+// applyRules appends the permissions of the conditional rules whose parameter conditions the command meets.
+// The caller must hold the read lock.
+func (m *Manager) applyRules(cmd *models.AzureCommand, permissions []string) []string {
+	result := append([]string{}, permissions...)
+	seen := make(map[string]bool)
+	for _, permission := range permissions {
+		seen[strings.ToLower(permission)] = true
+	}
+
+	for _, rule := range m.mappings.Rules {
+		if rule.Command != cmd.FullCmd {
+			continue
+		}
+		value, exists := cmd.Parameters[rule.Parameter]
+		if !exists || (rule.Value != "" && !strings.EqualFold(rule.Value, value)) {
+			continue
+		}
+		for _, permission := range rule.Permissions {
+			if !seen[strings.ToLower(permission)] {
+				seen[strings.ToLower(permission)] = true
+				result = append(result, permission)
+			}
 		}
 	}
 
-	return ""
+	return result
 }
 
-// getResourceTypeForService maps service names to Azure resource types
-func getResourceTypeForService(service string) string {
-	serviceMap := map[string]string{
-		"group":              "subscriptions/resourceGroups",
-		"vm":                 "virtualMachines",
-		"storage account":    "storageAccounts",
-		"storage blob":       "storageAccounts/blobServices",
-		"webapp":             "sites",
-		"functionapp":        "sites",
-		"keyvault":           "vaults",
-		"network vnet":       "virtualNetworks",
-		"network nsg":        "networkSecurityGroups",
-		"sql server":         "servers",
-		"sql db":             "servers/databases",
-		"aks":                "managedClusters",
-		"cosmosdb":           "databaseAccounts",
-		"role assignment":    "roleAssignments",
-		"role definition":    "roleDefinitions",
-		"ad user":            "users",
+// rebuildIndex rebuilds the inverted index from permissions to commands.
+// The caller must hold the write lock.
+func (m *Manager) rebuildIndex() {
+	m.index = make(map[string][]models.CommandReference)
+
+	add := func(reference models.CommandReference) {
+		key := strings.ToLower(reference.Permission)
+		m.index[key] = append(m.index[key], reference)
+	}
+
+	for command, permissions := range m.mappings.Commands {
+		for _, permission := range permissions {
+			add(models.CommandReference{Command: "az " + command, Permission: permission, Source: m.mappings.Source})
+		}
+	}
+	for _, rule := range m.mappings.Rules {
+		for _, permission := range rule.Permissions {
+			add(models.CommandReference{Command: "az " + rule.Command, Permission: permission, Condition: ruleCondition(rule), Source: m.mappings.Source})
+		}
+	}
+
+	for key := range m.index {
+		sortReferences(m.index[key])
 	}
+}
 
-	if resourceType, exists := serviceMap[service]; exists {
-		return resourceType
+// ruleCondition describes the parameter condition of a conditional rule (e.g. "--subnet" or "--sku Premium")
+func ruleCondition(rule models.ConditionalRule) string {
+	if rule.Value == "" {
+		return "--" + rule.Parameter
+	}
+	return "--" + rule.Parameter + " " + rule.Value
+}
+
+// sortReferences orders command references by command, then unconditional before conditional
+func sortReferences(references []models.CommandReference) {
+	sort.Slice(references, func(i, j int) bool {
+		if references[i].Command != references[j].Command {
+			return references[i].Command < references[j].Command
+		}
+		if references[i].Condition != references[j].Condition {
+			return references[i].Condition < references[j].Condition
+		}
+		return references[i].Permission < references[j].Permission
+	})
+}
+
+// getResourceProviderForService maps service names to Azure resource providers
+func getResourceProviderForService(service string) string {
+	return registry.Default().Provider(service)
+}
+
+// getResourceTypeForService maps service names to Azure resource types
+func getResourceTypeForService(service string) string {
+	if entry, ok := registry.Default().Exact(service); ok && entry.ResourceType != "" {
+		return entry.ResourceType
 	}
 
 	// Default fallback - try to construct from service name
@@ -0,0 +1,71 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// LoadMappingFile reads command to permission mapping overrides from a JSON file with
+// "commands" and optional conditional "rules", in the format of the built-in mappings
+func LoadMappingFile(path string) (models.PermissionMapping, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return models.PermissionMapping{}, fmt.Errorf("failed to read mappings: %w", err)
+	}
+
+	var mapping models.PermissionMapping
+	if err := json.Unmarshal(content, &mapping); err != nil {
+		return models.PermissionMapping{}, fmt.Errorf("failed to parse mappings %s: %w", path, err)
+	}
+	mapping.Source = path
+	return mapping, nil
+}
+
+// SetOverrides sets mappings that replace the built-in permissions of their commands.
+// Their rules are added to the built-in rules.
+func (m *Manager) SetOverrides(overrides models.PermissionMapping) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.overrides = overrides
+	m.applyOverrides()
+	m.rebuildIndex()
+}
+
+// applyOverrides merges the overrides into the mappings. The caller must hold the write lock.
+func (m *Manager) applyOverrides() {
+	if m.mappings.Commands == nil {
+		m.mappings.Commands = make(map[string][]string)
+	}
+	for command, permissions := range m.overrides.Commands {
+		m.mappings.Commands[command] = permissions
+	}
+	m.mappings.Rules = append(m.mappings.Rules, m.overrides.Rules...)
+}
+
+// SetOverrides sets the permissions of commands that take precedence over the catalog
+// matching. It must be called before the resolver is used.
+func (r *Resolver) SetOverrides(commands map[string][]string) {
+	r.overrides = commands
+}
+
+// overrideDetails describes overridden permissions with their catalog details where available
+func overrideDetails(names []string, store *catalog.Store) []models.PermissionDetail {
+	details := make([]models.PermissionDetail, 0, len(names))
+	for _, name := range names {
+		namespace := strings.SplitN(name, "/", 2)[0]
+		if operation, ok := store.Lookup(name); ok {
+			details = append(details, newPermissionDetail(namespace, operation.ResourceType, operation.ProviderOperation))
+			continue
+		}
+
+		risk := ClassifyRisk(name, false)
+		details = append(details, models.PermissionDetail{Name: name, Provider: namespace, Risk: &risk})
+	}
+	return details
+}
@@ -0,0 +1,608 @@
+package permissions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/registry"
+)
+
+// DebugFunc receives verbose diagnostic output while resolving permissions
+type DebugFunc func(format string, args ...interface{})
+
+// DiscoverFunc matches a service that is not in the service registry to a resource provider
+type DiscoverFunc func(service string) (registry.Service, bool)
+
+// Resolver maps parsed Azure CLI commands to operations in the provider operations catalog.
+// A Resolver holds no per-command state and is safe for concurrent use.
+type Resolver struct {
+	debug DebugFunc
+	// overrides maps commands (e.g. "vm start") to permissions replacing the catalog matches
+	overrides map[string][]string
+	// discover matches unknown services to resource providers, if set
+	discover DiscoverFunc
+
+	// discovered caches the results of discover by service, including services it did not match
+	discoveredMu sync.Mutex
+	discovered   map[string]discoveredService
+}
+
+// discoveredService is a cached result of the discovery function
+type discoveredService struct {
+	service registry.Service
+	found   bool
+}
+
+// NewResolver creates a new resolver; debug may be nil to disable verbose output
+func NewResolver(debug DebugFunc) *Resolver {
+	return &Resolver{debug: debug}
+}
+
+// SetDiscovery sets the function that matches services missing from the service registry to
+// resource providers. Each service is discovered once per resolver; the registry is left
+// unchanged. It must be called before the resolver is used.
+func (r *Resolver) SetDiscovery(discover DiscoverFunc) {
+	r.discover = discover
+}
+
+// debugf writes verbose output when debugging is enabled
+func (r *Resolver) debugf(format string, args ...interface{}) {
+	if r.debug != nil {
+		r.debug(format, args...)
+	}
+}
+
+// Analyze resolves the permissions for a command and wraps them in an analysis result
+func (r *Resolver) Analyze(cmd *models.AzureCommand, store *catalog.Store) models.AnalysisResult {
+	result := models.AnalysisResult{
+		Command:     "az " + cmd.FullCmd,
+		Service:     cmd.Service,
+		Operation:   cmd.Operation,
+		Parameters:  cmd.Parameters,
+		Permissions: []models.PermissionDetail{},
+		Confidence:  models.ConfidenceLow,
+		RiskLevel:   models.RiskNone,
+	}
+
+	details, err := r.Resolve(cmd, store)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if len(details) == 0 {
+		result.Error = fmt.Sprintf("no permissions found for command: %s", cmd.FullCmd)
+		return result
+	}
+
+	result.Permissions = details
+	result.Confidence = r.Confidence(cmd)
+	result.Scope = ComputeScope(cmd, SubscriptionPlaceholder, details)
+	if scopes := ComputeScopes(cmd, SubscriptionPlaceholder, details); len(scopes) > 1 {
+		result.Scopes = scopes
+	}
+	AssessRisk(&result)
+	return result
+}
+
+// Resolve finds the operations in the catalog that are required by the command. Operations
+// are looked up through the store's namespace, segment and verb indexes.
+func (r *Resolver) Resolve(cmd *models.AzureCommand, store *catalog.Store) ([]models.PermissionDetail, error) {
+	if names, exists := r.overrides[cmd.FullCmd]; exists {
+		r.debugf("📌 Using mapping override for '%s'\n", cmd.FullCmd)
+		return overrideDetails(names, store), nil
+	}
+
+	// Resource IDs name the provider and resource type directly
+	if targets := ResourceTargets(cmd); len(targets) > 0 {
+		if permissions, ok := r.resolveTargets(cmd, targets, store); ok {
+			return permissions, nil
+		}
+		r.debugf("🔁 Inferring the resource type from '%s' instead\n", cmd.Service)
+	}
+
+	// Map service to resource provider
+	provider := r.provider(cmd.Service)
+	if provider == "" {
+		return nil, fmt.Errorf("unknown service: %s", cmd.Service)
+	}
+
+	r.debugf("🔗 Mapped service '%s' to provider '%s'\n", cmd.Service, provider)
+
+	providerOps, exists := catalog.LookupNamespace(store.Providers(), provider)
+	if !exists {
+		return nil, fmt.Errorf("provider not found: %s", provider)
+	}
+
+	r.debugf("📋 Found provider '%s' with %d resource types\n", provider, len(providerOps.ResourceTypes))
+
+	permissionsSet := make(map[string]models.PermissionDetail) // Use map to avoid duplicates
+
+	// First check provider-level operations
+	for _, operation := range providerOps.Operations {
+		if matchesOperation(cmd.Operation, operation.Name) {
+			permissionsSet[operation.Name] = newPermissionDetail(provider, "", operation)
+			r.debugf("✅ Matched provider operation: %s\n", operation.Name)
+		}
+	}
+
+	// Then check the operations of the resource types that belong to the command
+	for _, operation := range r.matchingOperations(cmd, provider, store) {
+		if matchesOperation(cmd.Operation, operation.Name) {
+			permissionsSet[operation.Name] = newPermissionDetail(provider, operation.ResourceType, operation.ProviderOperation)
+			r.debugf("✅ Matched operation: %s\n", operation.Name)
+		}
+	}
+
+	// Convert map to slice
+	var permissions []models.PermissionDetail
+	for _, detail := range permissionsSet {
+		permissions = append(permissions, detail)
+	}
+
+	// If no exact matches, provide intelligent suggestions
+	if len(permissions) == 0 {
+		r.debugf("⚠️  No exact matches found, using intelligent suggestions...\n")
+		permissions = r.suggestOperationsFromLiveData(cmd, provider, providerOps, store)
+	}
+
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
+
+	r.debugf("🎯 Found %d permissions\n", len(permissions))
+	return permissions, nil
+}
+
+// matchingOperations returns the operations of the provider's resource types that belong
+// to the command. When the registry names the command's resource types, the candidates come
+// from the segment index; otherwise every operation of the namespace is a candidate.
+func (r *Resolver) matchingOperations(cmd *models.AzureCommand, provider string, store *catalog.Store) []models.OperationInfo {
+	var candidates []models.OperationInfo
+	if segments, ok := r.resourceTypeSegments(cmd); ok {
+		for _, segment := range segments {
+			candidates = append(candidates, store.Query(catalog.StoreQuery{Namespace: provider, Segment: segment})...)
+		}
+	} else {
+		candidates = store.Query(catalog.StoreQuery{Namespace: provider})
+	}
+
+	// Resource types are checked once, however many operations they have
+	matched := make(map[string]bool)
+	var operations []models.OperationInfo
+	for _, operation := range candidates {
+		if operation.ResourceType == "" {
+			continue
+		}
+		key := strings.ToLower(operation.ResourceType)
+		match, checked := matched[key]
+		if !checked {
+			match = r.matchesResourceType(cmd, operation.ResourceType)
+			matched[key] = match
+			if match {
+				r.debugf("✅ Matched resource type: %s\n", operation.ResourceType)
+			} else if isDataPlaneOperation(cmd) {
+				// Show what we're rejecting for debugging
+				r.debugf("❌ Rejected resource type: %s\n", operation.ResourceType)
+			}
+		}
+		if match {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+// resourceTypeSegments returns the last path segment of each resource type matchesResourceType
+// accepts for the command, and false when resource types are matched by name instead
+func (r *Resolver) resourceTypeSegments(cmd *models.AzureCommand) ([]string, bool) {
+	var resourceTypes []string
+	entry, exists := registry.Default().Exact(cmd.Service)
+	discovered, isDiscovered := r.discoveredService(cmd.Service)
+	switch {
+	case isDiscovered:
+		resourceTypes = []string{discovered.ResourceType}
+	case isDataPlaneOperation(cmd):
+		return nil, false
+	default:
+		var restricted bool
+		if resourceTypes, restricted = registry.Default().ResourceTypes(cmd.Service, cmd.Operation); !restricted && exists {
+			resourceTypes = []string{entry.ResourceType}
+		}
+	}
+
+	var segments []string
+	seen := make(map[string]bool)
+	for _, resourceType := range resourceTypes {
+		if resourceType == "" {
+			continue
+		}
+		segment := strings.ToLower(resourceType[strings.LastIndex(resourceType, "/")+1:])
+		if !seen[segment] {
+			seen[segment] = true
+			segments = append(segments, segment)
+		}
+	}
+	return segments, true
+}
+
+// Namespaces returns the provider namespaces Resolve consults for a command, so they can be
+// fetched without the rest of the catalog
+func (r *Resolver) Namespaces(cmd *models.AzureCommand) []string {
+	if names, exists := r.overrides[cmd.FullCmd]; exists {
+		var namespaces []string
+		seen := make(map[string]bool)
+		for _, name := range names {
+			namespace := strings.SplitN(name, "/", 2)[0]
+			if !seen[namespace] {
+				seen[namespace] = true
+				namespaces = append(namespaces, namespace)
+			}
+		}
+		return namespaces
+	}
+
+	// Resolve falls back to the service's provider when a target is not in the catalog
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, target := range ResourceTargets(cmd) {
+		if !seen[strings.ToLower(target.Provider)] {
+			seen[strings.ToLower(target.Provider)] = true
+			namespaces = append(namespaces, target.Provider)
+		}
+	}
+	if provider := r.provider(cmd.Service); provider != "" && !seen[strings.ToLower(provider)] {
+		namespaces = append(namespaces, provider)
+	}
+	return namespaces
+}
+
+// PermissionNames returns the names of the given permission details
+func PermissionNames(details []models.PermissionDetail) []string {
+	names := make([]string, 0, len(details))
+	for _, detail := range details {
+		names = append(names, detail.Name)
+	}
+	return names
+}
+
+// newPermissionDetail builds a permission detail from a provider operation
+func newPermissionDetail(provider, resourceType string, operation models.ProviderOperation) models.PermissionDetail {
+	risk := ClassifyRisk(operation.Name, operation.IsDataAction)
+	return models.PermissionDetail{
+		Name:         operation.Name,
+		IsDataAction: operation.IsDataAction,
+		Provider:     provider,
+		ResourceType: resourceType,
+		DisplayName:  operation.DisplayName,
+		Description:  operation.Description,
+		Risk:         &risk,
+	}
+}
+
+// mapServiceToProvider maps an Azure CLI service to its resource provider namespace
+func mapServiceToProvider(service string) string {
+	return registry.Default().Provider(service)
+}
+
+// provider maps a service to its resource provider namespace, discovering services that are
+// not in the registry
+func (r *Resolver) provider(service string) string {
+	if provider := mapServiceToProvider(service); provider != "" {
+		return provider
+	}
+	discovered, _ := r.discoveredService(service)
+	return discovered.Provider
+}
+
+// discoveredService matches a service that is not in the registry with the discovery
+// function. Results are cached by the resolver, so each service is discovered once.
+func (r *Resolver) discoveredService(service string) (registry.Service, bool) {
+	if r.discover == nil || mapServiceToProvider(service) != "" {
+		return registry.Service{}, false
+	}
+
+	key := strings.Join(strings.Fields(strings.ToLower(service)), " ")
+	r.discoveredMu.Lock()
+	cached, ok := r.discovered[key]
+	r.discoveredMu.Unlock()
+	if ok {
+		return cached.service, cached.found
+	}
+
+	// Discovery may wait for the provider list, so it runs without holding the lock
+	discovered, found := r.discover(service)
+	if found {
+		r.debugf("🔎 Discovered service '%s' as %s/%s\n", service, discovered.Provider, discovered.ResourceType)
+	}
+
+	r.discoveredMu.Lock()
+	defer r.discoveredMu.Unlock()
+	if r.discovered == nil {
+		r.discovered = make(map[string]discoveredService)
+	}
+	r.discovered[key] = discoveredService{service: discovered, found: found}
+	return discovered, found
+}
+
+// Confidence returns how reliable the resolved permissions of a command are: high when the
+// service is in the registry, the command is overridden or names its resources by ID,
+// medium when the service was discovered
+func (r *Resolver) Confidence(cmd *models.AzureCommand) models.ConfidenceLevel {
+	if _, exists := r.overrides[cmd.FullCmd]; exists {
+		return models.ConfidenceHigh
+	}
+	if len(ResourceTargets(cmd)) > 0 {
+		return models.ConfidenceHigh
+	}
+	if _, discovered := r.discoveredService(cmd.Service); discovered {
+		return models.ConfidenceMedium
+	}
+	return models.ConfidenceHigh
+}
+
+// matchesResourceType checks whether a resource type from the API belongs to the command
+func (r *Resolver) matchesResourceType(cmd *models.AzureCommand, resourceType string) bool {
+	service := strings.ToLower(cmd.Service)
+	resType := strings.ToLower(resourceType)
+	operation := strings.ToLower(cmd.Operation)
+
+	// Discovered services were matched to a single resource type by name
+	if discovered, ok := r.discoveredService(service); ok {
+		return discovered.ResourceType != "" && strings.EqualFold(discovered.ResourceType, resourceType)
+	}
+
+	// Dynamic data plane detection based on service patterns
+	if isDataPlaneOperation(cmd) {
+		return matchesDataPlaneResourceType(service, operation, resType)
+	}
+
+	// Control plane operations - use the resource types the registry lists for the operation,
+	// or the resource type of the service. For "storage account" commands this matches
+	// exactly "storageAccounts", not sub-resources.
+	resourceTypes, restricted := registry.Default().ResourceTypes(service, operation)
+	if !restricted {
+		entry, ok := registry.Default().Exact(service)
+		if !ok || entry.ResourceType == "" {
+			return false
+		}
+		resourceTypes = []string{entry.ResourceType}
+	}
+
+	normalizedResType := strings.ReplaceAll(resType, "/", "")
+	for _, resourceTypePattern := range resourceTypes {
+		normalizedPattern := strings.ReplaceAll(strings.ToLower(resourceTypePattern), "/", "")
+		if normalizedResType == normalizedPattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isDataPlaneOperation reports whether a command works on data rather than resources.
+// Multi-part service names (like "keyvault secret" or "storage blob") are strong indicators
+// of data plane operations, except for the groups the registry marks as control plane, such
+// as "storage account".
+func isDataPlaneOperation(cmd *models.AzureCommand) bool {
+	service := strings.ToLower(cmd.Service)
+	return len(strings.Fields(service)) >= 2 && !registry.Default().ControlPlane(service)
+}
+
+// matchesDataPlaneResourceType dynamically matches data plane resource types
+// by analyzing the actual Azure API resource type patterns
+func matchesDataPlaneResourceType(service, operation, resourceType string) bool {
+	serviceParts := strings.Fields(service)
+	if len(serviceParts) < 2 {
+		return false
+	}
+
+	baseService := serviceParts[0]
+	subResource := serviceParts[1]
+
+	// Dynamic matching based on resource type structure from Azure API
+	resourceTypeLower := strings.ToLower(resourceType)
+
+	// Check if the resource type contains the base service name, or one of the names the
+	// registry lists for its resource types
+	typeNames := registry.Default().TypeNames(baseService)
+	if len(typeNames) == 0 {
+		typeNames = []string{baseService}
+	}
+	serviceMatched := false
+	for _, name := range typeNames {
+		if strings.Contains(resourceTypeLower, strings.ToLower(name)) {
+			serviceMatched = true
+			break
+		}
+	}
+
+	if !serviceMatched {
+		return false
+	}
+
+	// Check if the resource type contains the sub-resource name
+	if !strings.Contains(resourceTypeLower, subResource) {
+		return false
+	}
+
+	// Count hierarchy levels in the original resource type
+	hierarchyLevels := strings.Count(resourceType, "/")
+
+	// Data plane operations typically have deeper hierarchy (1+ levels)
+	if hierarchyLevels < 1 {
+		return false
+	}
+
+	// For truly dynamic matching, prioritize the most specific resource types
+	// by preferring deeper hierarchy levels that directly contain the sub-resource
+	resourceTypeParts := strings.Split(resourceTypeLower, "/")
+
+	// Check if the sub-resource name appears in the resource type path
+	// Search from the end to find the most specific match
+	subResourceFound := false
+	subResourcePosition := -1
+	for i := len(resourceTypeParts) - 1; i >= 0; i-- {
+		part := resourceTypeParts[i]
+		if strings.Contains(part, subResource) {
+			subResourceFound = true
+			subResourcePosition = i
+			// Continue searching backwards for an even more specific match
+			// but if we find a direct match (part == subResource+"s" or part == subResource), prefer it
+			if part == subResource || part == subResource+"s" {
+				break
+			}
+		}
+	}
+
+	if !subResourceFound {
+		return false
+	}
+
+	// Prefer more specific resource types:
+	// The sub-resource should appear towards the end of the path for specificity
+	// For example: "storageAccounts/blobServices/containers/blobs" is more specific than "storageAccounts/blobServices"
+	totalParts := len(resourceTypeParts)
+
+	// Only match if the sub-resource appears in the last 2 parts of the path
+	// This ensures we get the most specific permissions
+	if subResourcePosition < totalParts-2 {
+		return false
+	}
+
+	// Additional heuristic: avoid monitoring/insights resources unless they're specifically requested
+	if strings.Contains(resourceTypeLower, "insights") || strings.Contains(resourceTypeLower, "monitoring") {
+		// Only include if the operation is specifically about insights/monitoring
+		if !strings.Contains(strings.ToLower(operation), "monitor") &&
+			!strings.Contains(strings.ToLower(operation), "metric") &&
+			!strings.Contains(strings.ToLower(operation), "diagnostic") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// operationVerbs maps az operations to the words of the API operations they may use,
+// including data plane operations
+var operationVerbs = map[string][]string{
+	"create":   {"write", "create"},
+	"update":   {"write", "update"},
+	"set":      {"write", "set", "setsecret", "setkey", "setcertificate"},
+	"delete":   {"delete", "remove", "deletesecret", "deletekey", "deletecertificate"},
+	"remove":   {"delete", "remove"},
+	"list":     {"read", "list", "getsecret", "getkey", "getcertificate"},
+	"show":     {"read", "get", "getsecret", "getkey", "getcertificate"},
+	"get":      {"read", "get", "getsecret", "getkey", "getcertificate"},
+	"start":    {"start"},
+	"stop":     {"poweroff", "stop"},
+	"restart":  {"restart"},
+	"upload":   {"write", "put"},
+	"download": {"read", "get"},
+}
+
+// suggestionVerbs maps az operations to the verbs of the operations suggested when nothing
+// matched exactly
+var suggestionVerbs = map[string][]string{
+	"create":  {"write"},
+	"update":  {"write"},
+	"set":     {"write"},
+	"delete":  {"delete"},
+	"remove":  {"delete"},
+	"list":    {"read"},
+	"show":    {"read"},
+	"get":     {"read"},
+	"start":   {"start"},
+	"stop":    {"poweroff", "stop"},
+	"restart": {"restart"},
+}
+
+// matchesOperation checks whether an API operation corresponds to the command operation
+func matchesOperation(cmdOp, apiOp string) bool {
+	cmdOp = strings.ToLower(cmdOp)
+	apiOp = strings.ToLower(apiOp)
+
+	// Direct match first
+	if strings.Contains(apiOp, cmdOp) {
+		return true
+	}
+
+	if matches, exists := operationVerbs[cmdOp]; exists {
+		for _, match := range matches {
+			if strings.Contains(apiOp, match) {
+				return true
+			}
+		}
+	}
+
+	// Additional data plane operation patterns
+	// Many data plane operations end with "/action"
+	if strings.HasSuffix(apiOp, "/action") {
+		actionName := strings.TrimSuffix(apiOp, "/action")
+		if strings.Contains(actionName, cmdOp) {
+			return true
+		}
+
+		// Check if the action name contains operation patterns
+		for _, match := range operationVerbs[cmdOp] {
+			if strings.Contains(actionName, match) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// suggestOperationsFromLiveData picks likely operations when no exact match was found
+func (r *Resolver) suggestOperationsFromLiveData(cmd *models.AzureCommand, provider string, providerOps models.ProviderOperationsResponse, store *catalog.Store) []models.PermissionDetail {
+	// Find the most likely resource type
+	var bestResourceType *models.ProviderResourceType
+	for i, rt := range providerOps.ResourceTypes {
+		if r.matchesResourceType(cmd, rt.Name) {
+			bestResourceType = &providerOps.ResourceTypes[i]
+			break
+		}
+	}
+
+	if bestResourceType == nil && len(providerOps.ResourceTypes) > 0 {
+		bestResourceType = &providerOps.ResourceTypes[0]
+	}
+
+	if bestResourceType == nil {
+		return nil
+	}
+	return suggestResourceTypeOperations(cmd.Operation, provider, bestResourceType.Name, store)
+}
+
+// suggestResourceTypeOperations picks the operations of a resource type whose verb likely
+// matches the az operation, falling back to its read operation. Operations are looked up
+// through the verb index.
+func suggestResourceTypeOperations(operation, provider, resourceType string, store *catalog.Store) []models.PermissionDetail {
+	var suggestions []models.PermissionDetail
+	for _, verb := range suggestionVerbs[strings.ToLower(operation)] {
+		suggestions = append(suggestions, operationsWithVerb(provider, resourceType, verb, store)...)
+	}
+
+	// If no suggestions yet, add read permission as fallback
+	if len(suggestions) == 0 {
+		if reads := operationsWithVerb(provider, resourceType, "read", store); len(reads) > 0 {
+			suggestions = reads[:1]
+		}
+	}
+
+	return suggestions
+}
+
+// operationsWithVerb returns the operations of a resource type with the given verb
+func operationsWithVerb(provider, resourceType, verb string, store *catalog.Store) []models.PermissionDetail {
+	var details []models.PermissionDetail
+	for _, operation := range store.Query(catalog.StoreQuery{Namespace: provider, Verb: verb}) {
+		if strings.EqualFold(operation.ResourceType, resourceType) {
+			details = append(details, newPermissionDetail(provider, operation.ResourceType, operation.ProviderOperation))
+		}
+	}
+	return details
+}
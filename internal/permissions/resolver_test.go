@@ -0,0 +1,196 @@
+package permissions
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+	"github.com/mathwro/azperm/internal/registry"
+)
+
+func TestResolve(t *testing.T) {
+	store := catalog.NewStore(catalogtest.Realistic())
+	resolver := NewResolver(nil)
+	resolver.SetOverrides(map[string][]string{
+		"vm restart": {"Microsoft.Compute/virtualMachines/restart/action", "Microsoft.Custom/things/read"},
+	})
+
+	tests := []struct {
+		command string
+		want    []string
+		wantErr string
+	}{
+		{command: "az vm create --name vm1 -g rg", want: []string{"Microsoft.Compute/virtualMachines/write"}},
+		{command: "az vm delete --name vm1 -g rg", want: []string{"Microsoft.Compute/virtualMachines/delete"}},
+		{command: "az webapp restart --name app -g rg", want: []string{"Microsoft.Web/sites/restart/action"}},
+		{command: "az keyvault create --name kv -g rg", want: []string{"Microsoft.KeyVault/vaults/write"}},
+		{command: "az identity create --name id -g rg", want: []string{"Microsoft.ManagedIdentity/userAssignedIdentities/write"}},
+		{
+			command: "az resource show --ids /subscriptions/0000/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1",
+			want:    []string{"Microsoft.Compute/virtualMachines/read"},
+		},
+		{
+			command: "az vm restart --name vm1 -g rg",
+			want:    []string{"Microsoft.Compute/virtualMachines/restart/action", "Microsoft.Custom/things/read"},
+		},
+		{command: "az unknownservice list", wantErr: "unknown service: unknownservice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			cmd, err := parser.ParseAzureCommand(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			details, err := resolver.Resolve(cmd, store)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := PermissionNames(details); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMissingProvider(t *testing.T) {
+	providers := catalogtest.Realistic()
+	delete(providers, "Microsoft.Compute")
+
+	cmd, err := parser.ParseAzureCommand("az vm show --name vm1 -g rg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewResolver(nil).Resolve(cmd, catalog.NewStore(providers)); err == nil || !strings.Contains(err.Error(), "provider not found") {
+		t.Errorf("Resolve() error = %v, want a provider not found error", err)
+	}
+}
+
+func BenchmarkResolve(b *testing.B) {
+	store := catalog.NewStore(catalogtest.Realistic())
+	resolver := NewResolver(nil)
+
+	var commands []*models.AzureCommand
+	for _, line := range []string{
+		"az vm start --name vm1 -g rg",
+		"az storage blob upload --account-name st --container-name c --name f --file f",
+		"az keyvault secret show --vault-name kv --name secret",
+		"az network vnet subnet create -g rg --vnet-name vnet --name subnet",
+		"az webapp config appsettings list --name app -g rg",
+	} {
+		cmd, err := parser.ParseAzureCommand(line)
+		if err != nil {
+			b.Fatal(err)
+		}
+		commands = append(commands, cmd)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, cmd := range commands {
+			if _, err := resolver.Resolve(cmd, store); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestIsDataPlaneOperation(t *testing.T) {
+	tests := []struct {
+		service string
+		want    bool
+	}{
+		{"vm", false},
+		{"storage account", false},
+		{"Key Vault", false},
+		{"network vnet", false},
+		{"storage blob", true},
+		{"keyvault secret", true},
+		{"network vnet subnet", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			if got := isDataPlaneOperation(&models.AzureCommand{Service: tt.service}); got != tt.want {
+				t.Errorf("isDataPlaneOperation(%q) = %v, want %v", tt.service, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveDiscoveredService(t *testing.T) {
+	providers := catalogtest.Realistic()
+	providers["Microsoft.Databricks"] = models.ProviderOperationsResponse{
+		Namespace: "Microsoft.Databricks",
+		ResourceTypes: []models.ProviderResourceType{
+			{Name: "workspaces", Operations: []models.ProviderOperation{{Name: "Microsoft.Databricks/workspaces/read"}}},
+			{Name: "accessConnectors", Operations: []models.ProviderOperation{{Name: "Microsoft.Databricks/accessConnectors/read"}}},
+		},
+	}
+	store := catalog.NewStore(providers)
+
+	var discoveries atomic.Int32
+	resolver := NewResolver(nil)
+	resolver.SetDiscovery(func(service string) (registry.Service, bool) {
+		discoveries.Add(1)
+		if service != "databricks access-connector" {
+			return registry.Service{}, false
+		}
+		return registry.Service{Group: service, Provider: "Microsoft.Databricks", ResourceType: "accessConnectors", Source: registry.SourceDiscovered}, true
+	})
+
+	cmd, err := parser.ParseAzureCommand("az databricks access-connector show --name connector -g rg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := resolver.Analyze(cmd, store)
+			if got := PermissionNames(result.Permissions); !reflect.DeepEqual(got, []string{"Microsoft.Databricks/accessConnectors/read"}) {
+				t.Errorf("Analyze() permissions = %v", got)
+			}
+			if result.Confidence != models.ConfidenceMedium {
+				t.Errorf("Analyze() confidence = %s, want %s", result.Confidence, models.ConfidenceMedium)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Later lookups, including of services that were not found, use the cache
+	unknown, err := parser.ParseAzureCommand("az unknownservice list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := resolver.Resolve(unknown, store); err == nil {
+			t.Error("Resolve() resolved an unknown service")
+		}
+	}
+	before := discoveries.Load()
+	resolver.Analyze(cmd, store)
+	if _, err := resolver.Resolve(unknown, store); err == nil {
+		t.Error("Resolve() resolved an unknown service")
+	}
+	if discoveries.Load() != before {
+		t.Errorf("discovery ran again for a cached service")
+	}
+	if _, ok := registry.Default().Exact("databricks access-connector"); ok {
+		t.Error("discovery added the service to the default registry")
+	}
+}
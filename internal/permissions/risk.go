@@ -0,0 +1,232 @@
+package permissions
+
+import (
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// Risk categories
+const (
+	RiskPrivilegeEscalation = "privilege-escalation"
+	RiskSecretExposure      = "secret-exposure"
+	RiskCodeExecution       = "code-execution"
+	RiskSecurityControl     = "security-control"
+	RiskBroadAccess         = "broad-access"
+	RiskDataAccess          = "data-access"
+	RiskDestructive         = "destructive"
+	RiskModify              = "modify"
+	RiskRead                = "read"
+)
+
+// riskRule classifies the permissions matching an RBAC wildcard pattern
+type riskRule struct {
+	pattern   string
+	level     models.RiskLevel
+	category  string
+	rationale string
+}
+
+// riskRules is the maintained rule set of known risky operations. Rules are checked in order
+// and the first match wins, so specific patterns come before generic ones.
+var riskRules = []riskRule{
+	// Privilege escalation
+	{"Microsoft.Authorization/roleAssignments/write", models.RiskCritical, RiskPrivilegeEscalation,
+		"Can assign any role, including Owner, to any principal at the scope"},
+	{"Microsoft.Authorization/roleDefinitions/write", models.RiskCritical, RiskPrivilegeEscalation,
+		"Can add actions to custom roles that are already assigned"},
+	{"Microsoft.Authorization/elevateAccess/action", models.RiskCritical, RiskPrivilegeEscalation,
+		"Grants User Access Administrator at the root scope"},
+	{"Microsoft.ManagedIdentity/userAssignedIdentities/assign/action", models.RiskHigh, RiskPrivilegeEscalation,
+		"Attaches a managed identity to a resource, so code running there can act with the identity's roles"},
+	{"Microsoft.ManagedIdentity/userAssignedIdentities/federatedIdentityCredentials/write", models.RiskHigh, RiskPrivilegeEscalation,
+		"Lets an external workload sign in as the managed identity"},
+	{"Microsoft.KeyVault/vaults/accessPolicies/write", models.RiskHigh, RiskPrivilegeEscalation,
+		"Can grant any principal access to all secrets, keys and certificates of the vault"},
+
+	// Code execution
+	{"*/runCommand/action", models.RiskHigh, RiskCodeExecution,
+		"Runs arbitrary scripts with administrative rights on the target, exposing its managed identity"},
+	{"Microsoft.Compute/virtualMachines/runCommands/write", models.RiskHigh, RiskCodeExecution,
+		"Runs arbitrary scripts with administrative rights on the VM, exposing its managed identity"},
+	{"Microsoft.Compute/virtualMachines/extensions/write", models.RiskHigh, RiskCodeExecution,
+		"Installs extensions such as CustomScript that run arbitrary code on the VM"},
+	{"Microsoft.Compute/virtualMachineScaleSets/extensions/write", models.RiskHigh, RiskCodeExecution,
+		"Installs extensions such as CustomScript that run arbitrary code on every instance"},
+	{"Microsoft.Automation/automationAccounts/jobs/write", models.RiskHigh, RiskCodeExecution,
+		"Starts runbooks that run with the Automation account's identity"},
+	{"Microsoft.Resources/deploymentScripts/write", models.RiskHigh, RiskCodeExecution,
+		"Runs arbitrary scripts with the identity attached to the deployment script"},
+
+	// Secret exposure
+	{"Microsoft.Web/sites/config/list/action", models.RiskHigh, RiskSecretExposure,
+		"Returns app settings and connection strings, which often contain secrets"},
+	{"Microsoft.Web/sites/slots/config/list/action", models.RiskHigh, RiskSecretExposure,
+		"Returns app settings and connection strings, which often contain secrets"},
+	{"Microsoft.Web/sites/publishxml/action", models.RiskHigh, RiskSecretExposure,
+		"Returns the publishing credentials of the app"},
+	{"*/listKeys/action", models.RiskHigh, RiskSecretExposure,
+		"Returns access keys that bypass RBAC and grant full data plane access"},
+	{"*/regenerateKey/action", models.RiskHigh, RiskSecretExposure,
+		"Returns newly generated access keys and breaks clients using the old ones"},
+	{"*/listConnectionStrings/action", models.RiskHigh, RiskSecretExposure,
+		"Returns connection strings containing credentials"},
+	{"*/listClusterAdminCredential/action", models.RiskCritical, RiskSecretExposure,
+		"Returns cluster-admin credentials for the Kubernetes cluster"},
+	{"*/listCredential*/action", models.RiskHigh, RiskSecretExposure,
+		"Returns credentials for the resource"},
+	{"*/listAdminCredentials/action", models.RiskHigh, RiskSecretExposure,
+		"Returns administrator credentials for the resource"},
+	{"Microsoft.KeyVault/vaults/secrets/getSecret/action", models.RiskHigh, RiskSecretExposure,
+		"Reads secret values from the vault"},
+	{"Microsoft.KeyVault/vaults/keys/decrypt/action", models.RiskHigh, RiskSecretExposure,
+		"Decrypts data protected by the key"},
+	{"Microsoft.KeyVault/vaults/keys/unwrap/action", models.RiskHigh, RiskSecretExposure,
+		"Unwraps keys protecting other data"},
+
+	// Security controls
+	{"Microsoft.Authorization/policyAssignments/delete", models.RiskHigh, RiskSecurityControl,
+		"Removes governance policies from the scope"},
+	{"Microsoft.Authorization/policyExemptions/write", models.RiskHigh, RiskSecurityControl,
+		"Exempts resources from governance policies"},
+	{"Microsoft.Authorization/locks/delete", models.RiskHigh, RiskSecurityControl,
+		"Removes locks that protect resources from deletion or change"},
+	{"Microsoft.Authorization/roleAssignments/delete", models.RiskMedium, RiskSecurityControl,
+		"Removes access of other principals"},
+	{"Microsoft.Insights/diagnosticSettings/delete", models.RiskMedium, RiskSecurityControl,
+		"Stops the export of audit and diagnostic logs"},
+	{"Microsoft.Network/networkSecurityGroups/securityRules/write", models.RiskMedium, RiskSecurityControl,
+		"Can open network access to resources"},
+	{"Microsoft.Storage/storageAccounts/write", models.RiskMedium, RiskSecurityControl,
+		"Can enable public access and shared key authorization on the account"},
+}
+
+// riskWeights are the scores of the risk levels
+var riskWeights = map[models.RiskLevel]int{
+	models.RiskNone:     0,
+	models.RiskLow:      10,
+	models.RiskMedium:   40,
+	models.RiskHigh:     70,
+	models.RiskCritical: 90,
+}
+
+// ClassifyRisk classifies a permission with the maintained rule set, or heuristically from
+// its verb when no rule matches
+func ClassifyRisk(name string, isDataAction bool) models.Risk {
+	if name == "*" {
+		return models.Risk{Level: models.RiskCritical, Category: RiskBroadAccess,
+			Rationale: "Grants every operation, including role assignments"}
+	}
+
+	for _, rule := range riskRules {
+		if MatchesPattern(rule.pattern, name) {
+			return models.Risk{Level: rule.level, Category: rule.category, Rationale: rule.rationale}
+		}
+	}
+
+	risk := classifyVerb(name, isDataAction)
+	risk.Heuristic = true
+	return risk
+}
+
+// classifyVerb classifies an unknown operation from its verb (read, write, delete or action)
+func classifyVerb(name string, isDataAction bool) models.Risk {
+	if strings.Contains(name, "*") {
+		return models.Risk{Level: models.RiskHigh, Category: RiskBroadAccess,
+			Rationale: "Wildcard grants every matching operation, including ones added later"}
+	}
+
+	segments := strings.Split(strings.ToLower(name), "/")
+	verb := segments[len(segments)-1]
+	action := ""
+	if len(segments) > 1 {
+		action = segments[len(segments)-2]
+	}
+
+	if isDataAction {
+		if verb == "delete" {
+			return models.Risk{Level: models.RiskHigh, Category: RiskDestructive, Rationale: "Deletes data"}
+		}
+		return models.Risk{Level: models.RiskMedium, Category: RiskDataAccess, Rationale: "Accesses data in the resource"}
+	}
+
+	switch verb {
+	case "read":
+		return models.Risk{Level: models.RiskLow, Category: RiskRead, Rationale: "Reads resource configuration"}
+	case "write":
+		return models.Risk{Level: models.RiskMedium, Category: RiskModify, Rationale: "Creates or updates resources"}
+	case "delete":
+		return models.Risk{Level: models.RiskMedium, Category: RiskDestructive, Rationale: "Deletes resources"}
+	case "action":
+		if strings.HasPrefix(action, "list") || strings.HasPrefix(action, "get") {
+			for _, secret := range []string{"key", "secret", "credential", "password", "token", "connectionstring"} {
+				if strings.Contains(action, secret) {
+					return models.Risk{Level: models.RiskHigh, Category: RiskSecretExposure, Rationale: "Returns secrets or credentials"}
+				}
+			}
+			return models.Risk{Level: models.RiskLow, Category: RiskRead, Rationale: "Lists resource information"}
+		}
+		return models.Risk{Level: models.RiskMedium, Category: RiskModify, Rationale: "Performs an operation on the resource"}
+	default:
+		return models.Risk{Level: models.RiskMedium, Category: RiskModify, Rationale: "Unrecognized operation"}
+	}
+}
+
+// RiskOf returns the risk of a resolved permission, classifying it if it has not been yet
+func RiskOf(permission models.PermissionDetail) models.Risk {
+	if permission.Risk != nil {
+		return *permission.Risk
+	}
+	return ClassifyRisk(permission.Name, permission.IsDataAction)
+}
+
+// IsHighRisk reports whether a permission is classified as high or critical risk
+func IsHighRisk(permission models.PermissionDetail) bool {
+	level := RiskOf(permission).Level
+	return level == models.RiskHigh || level == models.RiskCritical
+}
+
+// RiskScore rates a set of permissions from 0 to 100: the weight of the riskiest permission
+// plus 3 for every other high or critical permission. The level is that of the riskiest permission.
+func RiskScore(details []models.PermissionDetail) (int, models.RiskLevel) {
+	level := models.RiskNone
+	highRisk := 0
+	seen := make(map[string]bool)
+	for _, permission := range details {
+		if seen[permission.Name] {
+			continue
+		}
+		seen[permission.Name] = true
+
+		risk := RiskOf(permission)
+		if riskWeights[risk.Level] > riskWeights[level] {
+			level = risk.Level
+		}
+		if IsHighRisk(permission) {
+			highRisk++
+		}
+	}
+
+	score := riskWeights[level]
+	if highRisk > 1 {
+		score += 3 * (highRisk - 1)
+	}
+	if score > 100 {
+		score = 100
+	}
+	return score, level
+}
+
+// AssessRisk sets the risk score and level of an analysis result
+func AssessRisk(result *models.AnalysisResult) {
+	result.RiskScore, result.RiskLevel = RiskScore(result.Permissions)
+}
+
+// AssessScanRisk sets the overall risk score and level of a script from all its commands
+func AssessScanRisk(result *models.ScanResult) {
+	var all []models.PermissionDetail
+	for _, command := range result.Commands {
+		all = append(all, command.Permissions...)
+	}
+	result.RiskScore, result.RiskLevel = RiskScore(all)
+}
@@ -0,0 +1,89 @@
+package permissions
+
+import (
+	"testing"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+func TestClassifyRisk(t *testing.T) {
+	tests := []struct {
+		name          string
+		isDataAction  bool
+		wantLevel     models.RiskLevel
+		wantCategory  string
+		wantHeuristic bool
+	}{
+		{"*", false, models.RiskCritical, RiskBroadAccess, false},
+		{"Microsoft.Authorization/roleAssignments/write", false, models.RiskCritical, RiskPrivilegeEscalation, false},
+		{"microsoft.authorization/ROLEASSIGNMENTS/write", false, models.RiskCritical, RiskPrivilegeEscalation, false},
+		{"Microsoft.Compute/virtualMachines/runCommand/action", false, models.RiskHigh, RiskCodeExecution, false},
+		{"Microsoft.Storage/storageAccounts/listKeys/action", false, models.RiskHigh, RiskSecretExposure, false},
+		{"Microsoft.ContainerService/managedClusters/listClusterAdminCredential/action", false, models.RiskCritical, RiskSecretExposure, false},
+		{"Microsoft.Authorization/roleAssignments/delete", false, models.RiskMedium, RiskSecurityControl, false},
+		{"Microsoft.Compute/virtualMachines/read", false, models.RiskLow, RiskRead, true},
+		{"Microsoft.Compute/virtualMachines/write", false, models.RiskMedium, RiskModify, true},
+		{"Microsoft.Compute/virtualMachines/delete", false, models.RiskMedium, RiskDestructive, true},
+		{"Microsoft.Web/sites/listsecrets/action", false, models.RiskHigh, RiskSecretExposure, true},
+		{"Microsoft.Network/virtualNetworks/listUsage/action", false, models.RiskLow, RiskRead, true},
+		{"Microsoft.Compute/virtualMachines/start/action", false, models.RiskMedium, RiskModify, true},
+		{"Microsoft.Compute/*/read", false, models.RiskHigh, RiskBroadAccess, true},
+		{"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read", true, models.RiskMedium, RiskDataAccess, true},
+		{"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/delete", true, models.RiskHigh, RiskDestructive, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk := ClassifyRisk(tt.name, tt.isDataAction)
+			if risk.Level != tt.wantLevel || risk.Category != tt.wantCategory || risk.Heuristic != tt.wantHeuristic {
+				t.Errorf("ClassifyRisk() = %s/%s (heuristic %v), want %s/%s (heuristic %v)",
+					risk.Level, risk.Category, risk.Heuristic, tt.wantLevel, tt.wantCategory, tt.wantHeuristic)
+			}
+			if risk.Rationale == "" {
+				t.Error("ClassifyRisk() returned no rationale")
+			}
+		})
+	}
+}
+
+func TestRiskScore(t *testing.T) {
+	detail := func(name string) models.PermissionDetail {
+		return models.PermissionDetail{Name: name}
+	}
+
+	tests := []struct {
+		name      string
+		details   []models.PermissionDetail
+		wantScore int
+		wantLevel models.RiskLevel
+	}{
+		{"no permissions", nil, 0, models.RiskNone},
+		{"read only", []models.PermissionDetail{detail("Microsoft.Compute/virtualMachines/read")}, 10, models.RiskLow},
+		{"riskiest wins", []models.PermissionDetail{
+			detail("Microsoft.Compute/virtualMachines/read"),
+			detail("Microsoft.Compute/virtualMachines/write"),
+		}, 40, models.RiskMedium},
+		{"extra high risk permissions add up", []models.PermissionDetail{
+			detail("Microsoft.Authorization/roleAssignments/write"),
+			detail("Microsoft.Storage/storageAccounts/listKeys/action"),
+			detail("Microsoft.Web/sites/config/list/action"),
+		}, 96, models.RiskCritical},
+		{"duplicates count once", []models.PermissionDetail{
+			detail("Microsoft.Storage/storageAccounts/listKeys/action"),
+			detail("Microsoft.Storage/storageAccounts/listKeys/action"),
+		}, 70, models.RiskHigh},
+		{"explicit risk is kept", []models.PermissionDetail{{
+			Name: "Microsoft.Compute/virtualMachines/read",
+			Risk: &models.Risk{Level: models.RiskHigh},
+		}}, 70, models.RiskHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, level := RiskScore(tt.details)
+			if score != tt.wantScore || level != tt.wantLevel {
+				t.Errorf("RiskScore() = %d, %s, want %d, %s", score, level, tt.wantScore, tt.wantLevel)
+			}
+		})
+	}
+}
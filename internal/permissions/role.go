@@ -0,0 +1,157 @@
+package permissions
+
+import (
+	"sort"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// DefaultAssignableScope is used when no assignable scope is provided for a generated role
+const DefaultAssignableScope = "/subscriptions/" + SubscriptionPlaceholder
+
+// BuildCustomRole creates a least-privilege custom role definition covering the given analysis results
+func BuildCustomRole(name, description string, scopes []string, results []models.AnalysisResult) models.RoleDefinition {
+	actions, dataActions := SplitActions(results)
+	return NewCustomRole(name, description, scopes, actions, dataActions)
+}
+
+// NewCustomRole creates a custom role definition, filling in defaults for empty fields
+func NewCustomRole(name, description string, scopes, actions, dataActions []string) models.RoleDefinition {
+	if name == "" {
+		name = "azperm Custom Role"
+	}
+	if description == "" {
+		description = "Generated by azperm from Azure CLI commands"
+	}
+	if len(scopes) == 0 {
+		scopes = []string{DefaultAssignableScope}
+	}
+	if actions == nil {
+		actions = []string{}
+	}
+	if dataActions == nil {
+		dataActions = []string{}
+	}
+
+	return models.RoleDefinition{
+		Name:             name,
+		IsCustom:         true,
+		Description:      description,
+		Actions:          actions,
+		NotActions:       []string{},
+		DataActions:      dataActions,
+		NotDataActions:   []string{},
+		AssignableScopes: scopes,
+	}
+}
+
+// CompareWithRoles reports which patterns of the assigned roles were never used by the
+// observed permissions and which observed permissions the assigned roles do not grant
+func CompareWithRoles(observed []models.PermissionDetail, roles []models.RoleDefinition) models.RoleComparison {
+	comparison := models.RoleComparison{
+		AssignedRoles:    []string{},
+		UnusedPatterns:   []string{},
+		UncoveredActions: []string{},
+	}
+
+	var grants []models.Permission
+	unused := make(map[string]bool)
+	for _, role := range roles {
+		comparison.AssignedRoles = append(comparison.AssignedRoles, role.Name)
+		grants = append(grants, RolePermissions(role)...)
+
+		for _, pattern := range role.Actions {
+			if !matchesObserved(pattern, false, observed) {
+				unused[pattern] = true
+			}
+		}
+		for _, pattern := range role.DataActions {
+			if !matchesObserved(pattern, true, observed) {
+				unused[pattern] = true
+			}
+		}
+	}
+	comparison.UnusedPatterns = sortedKeys(unused)
+
+	for _, permission := range MissingPermissions(observed, grants) {
+		comparison.UncoveredActions = append(comparison.UncoveredActions, permission.Name)
+	}
+
+	return comparison
+}
+
+// matchesObserved reports whether a pattern matches any observed permission of the same kind
+func matchesObserved(pattern string, isDataAction bool, observed []models.PermissionDetail) bool {
+	for _, permission := range observed {
+		if permission.IsDataAction == isDataAction && MatchesPattern(pattern, permission.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitActions collects the unique control plane actions and data actions of the analysis results
+func SplitActions(results []models.AnalysisResult) ([]string, []string) {
+	actionSet := make(map[string]bool)
+	dataActionSet := make(map[string]bool)
+
+	for _, result := range results {
+		for _, permission := range result.Permissions {
+			if permission.IsDataAction {
+				dataActionSet[permission.Name] = true
+			} else {
+				actionSet[permission.Name] = true
+			}
+		}
+	}
+
+	return sortedKeys(actionSet), sortedKeys(dataActionSet)
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RemovedReferences reports the patterns of the roles that matched operations which have been
+// removed from the catalog. A pattern is orphaned when it matches none of the current operations.
+func RemovedReferences(roles []models.RoleDefinition, removed []string, current []models.OperationInfo) []models.RoleReference {
+	var references []models.RoleReference
+
+	for _, role := range roles {
+		patterns := append(append([]string{}, role.Actions...), role.DataActions...)
+		for _, pattern := range patterns {
+			var operations []string
+			for _, operation := range removed {
+				if MatchesPattern(pattern, operation) {
+					operations = append(operations, operation)
+				}
+			}
+			if len(operations) == 0 {
+				continue
+			}
+
+			orphaned := true
+			for _, operation := range current {
+				if MatchesPattern(pattern, operation.Name) {
+					orphaned = false
+					break
+				}
+			}
+
+			references = append(references, models.RoleReference{
+				Role:       role.Name,
+				Pattern:    pattern,
+				Operations: operations,
+				Orphaned:   orphaned,
+			})
+		}
+	}
+
+	return references
+}
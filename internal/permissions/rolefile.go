@@ -0,0 +1,117 @@
+package permissions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// roleFileEntry accepts the role definition formats produced by 'az role definition list',
+// the ARM REST API and the custom role files accepted by 'az role definition create'
+type roleFileEntry struct {
+	ID               string              `json:"id"`
+	Name             string              `json:"name"`
+	RoleName         string              `json:"roleName"`
+	Description      string              `json:"description"`
+	IsCustom         bool                `json:"isCustom"`
+	RoleType         string              `json:"roleType"`
+	Actions          []string            `json:"actions"`
+	NotActions       []string            `json:"notActions"`
+	DataActions      []string            `json:"dataActions"`
+	NotDataActions   []string            `json:"notDataActions"`
+	AssignableScopes []string            `json:"assignableScopes"`
+	Permissions      []models.Permission `json:"permissions"`
+	Properties       *struct {
+		RoleName         string              `json:"roleName"`
+		Description      string              `json:"description"`
+		Type             string              `json:"type"`
+		Permissions      []models.Permission `json:"permissions"`
+		AssignableScopes []string            `json:"assignableScopes"`
+	} `json:"properties"`
+}
+
+// LoadRoleDefinitions reads one or more role definitions from a JSON file
+func LoadRoleDefinitions(path string) ([]models.RoleDefinition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role definition file: %w", err)
+	}
+
+	roles, err := ParseRoleDefinitions(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse role definition file %s: %w", path, err)
+	}
+	return roles, nil
+}
+
+// ParseRoleDefinitions parses a single role definition, a list of role definitions
+// or an ARM list response ({"value": [...]})
+func ParseRoleDefinitions(content []byte) ([]models.RoleDefinition, error) {
+	content = bytes.TrimSpace(content)
+
+	var entries []roleFileEntry
+	switch {
+	case bytes.HasPrefix(content, []byte("[")):
+		if err := json.Unmarshal(content, &entries); err != nil {
+			return nil, err
+		}
+	default:
+		var list struct {
+			Value []roleFileEntry `json:"value"`
+		}
+		if err := json.Unmarshal(content, &list); err == nil && len(list.Value) > 0 {
+			entries = list.Value
+			break
+		}
+
+		var entry roleFileEntry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			return nil, err
+		}
+		entries = []roleFileEntry{entry}
+	}
+
+	roles := make([]models.RoleDefinition, 0, len(entries))
+	for _, entry := range entries {
+		roles = append(roles, entry.roleDefinition())
+	}
+	return roles, nil
+}
+
+// roleDefinition converts a parsed entry to the common role definition model
+func (e roleFileEntry) roleDefinition() models.RoleDefinition {
+	role := models.RoleDefinition{
+		ID:               e.ID,
+		Name:             e.Name,
+		IsCustom:         e.IsCustom || e.RoleType == "CustomRole",
+		Description:      e.Description,
+		Actions:          e.Actions,
+		NotActions:       e.NotActions,
+		DataActions:      e.DataActions,
+		NotDataActions:   e.NotDataActions,
+		AssignableScopes: e.AssignableScopes,
+	}
+
+	permissions := e.Permissions
+	if e.Properties != nil {
+		role.Name = e.Properties.RoleName
+		role.Description = e.Properties.Description
+		role.IsCustom = e.Properties.Type == "CustomRole"
+		role.AssignableScopes = e.Properties.AssignableScopes
+		permissions = e.Properties.Permissions
+	}
+
+	// 'az role definition list' uses "name" for the role GUID and "roleName" for the display name
+	if e.RoleName != "" {
+		role.Name = e.RoleName
+		if role.ID == "" {
+			role.ID = e.Name
+		}
+	}
+
+	role.SetPermissions(permissions)
+	return role
+}
@@ -0,0 +1,120 @@
+package permissions
+
+import (
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// SubscriptionPlaceholder stands in for the subscription ID in scopes computed without one
+const SubscriptionPlaceholder = "<subscription-id>"
+
+// ComputeScope determines the ARM scope a command operates on from its parameters.
+// It returns the most specific scope that exists before the command runs, so commands
+// creating a resource are scoped to the resource group. An empty string is returned
+// when no subscription is known.
+func ComputeScope(cmd *models.AzureCommand, subscriptionID string, details []models.PermissionDetail) string {
+	if scope := cmd.Parameters["scope"]; scope != "" {
+		return scope
+	}
+	if ids := strings.Fields(cmd.Parameters["ids"]); len(ids) > 0 {
+		return ids[0]
+	}
+
+	if subscription := cmd.Parameters["subscription"]; subscription != "" {
+		subscriptionID = subscription
+	}
+	if subscriptionID == "" {
+		return ""
+	}
+
+	subscriptionScope := "/subscriptions/" + subscriptionID
+	operation := strings.ToLower(cmd.Operation)
+	creates := operation == "create" || operation == "list"
+
+	// Resource group commands target the group itself
+	if strings.ToLower(cmd.Service) == "group" {
+		if name := cmd.Parameters["name"]; name != "" && !creates {
+			return subscriptionScope + "/resourceGroups/" + name
+		}
+		return subscriptionScope
+	}
+
+	resourceGroup := cmd.Parameters["resource-group"]
+	if resourceGroup == "" {
+		return subscriptionScope
+	}
+	resourceGroupScope := subscriptionScope + "/resourceGroups/" + resourceGroup
+
+	name := cmd.Parameters["name"]
+	if name == "" || creates {
+		return resourceGroupScope
+	}
+
+	// Only top-level resource types can be addressed from the name alone
+	for _, detail := range details {
+		if detail.IsDataAction || detail.ResourceType == "" || strings.Contains(detail.ResourceType, "/") {
+			continue
+		}
+		return resourceGroupScope + "/providers/" + detail.Provider + "/" + detail.ResourceType + "/" + name
+	}
+
+	return resourceGroupScope
+}
+
+// ComputeScopes determines every ARM scope a command operates on: one per resource ID in
+// --ids, otherwise the single scope of ComputeScope. It returns nil when no scope is known.
+func ComputeScopes(cmd *models.AzureCommand, subscriptionID string, details []models.PermissionDetail) []string {
+	if cmd.Parameters["scope"] == "" {
+		if ids := strings.Fields(cmd.Parameters["ids"]); len(ids) > 0 {
+			var scopes []string
+			seen := make(map[string]bool)
+			for _, id := range ids {
+				id = strings.TrimSuffix(id, "/")
+				if !seen[strings.ToLower(id)] {
+					seen[strings.ToLower(id)] = true
+					scopes = append(scopes, id)
+				}
+			}
+			return scopes
+		}
+	}
+
+	if scope := ComputeScope(cmd, subscriptionID, details); scope != "" {
+		return []string{scope}
+	}
+	return nil
+}
+
+// ParentScope returns the enclosing scope: resource → resource group → subscription.
+// The root scope "/" is returned for subscriptions and unknown scopes.
+func ParentScope(scope string) string {
+	lower := strings.ToLower(scope)
+
+	if index := strings.LastIndex(lower, "/providers/"); index > 0 {
+		return scope[:index]
+	}
+	if index := strings.Index(lower, "/resourcegroups/"); index > 0 {
+		return scope[:index]
+	}
+	return "/"
+}
+
+// SubscriptionScope returns the subscription part of a scope, or "/" if it has none
+func SubscriptionScope(scope string) string {
+	parts := strings.Split(strings.Trim(scope, "/"), "/")
+	if len(parts) >= 2 && strings.EqualFold(parts[0], "subscriptions") {
+		return "/subscriptions/" + parts[1]
+	}
+	return "/"
+}
+
+// ResourceGroupScope returns the resource group part of a scope, or the subscription scope
+// if the scope is not inside a resource group
+func ResourceGroupScope(scope string) string {
+	parts := strings.Split(strings.Trim(scope, "/"), "/")
+	if len(parts) >= 4 && strings.EqualFold(parts[2], "resourceGroups") {
+		return "/" + strings.Join(parts[:4], "/")
+	}
+	return SubscriptionScope(scope)
+}
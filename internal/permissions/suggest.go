@@ -0,0 +1,59 @@
+package permissions
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// SuggestRoles returns the roles that grant all required permissions, ordered from the
+// narrowest to the broadest role. A limit of zero or less returns all matching roles.
+func SuggestRoles(required []models.PermissionDetail, roles []models.RoleDefinition, limit int) []models.RoleSuggestion {
+	var suggestions []models.RoleSuggestion
+
+	for _, role := range roles {
+		grants := RolePermissions(role)
+		if len(MissingPermissions(required, grants)) > 0 {
+			continue
+		}
+
+		suggestions = append(suggestions, models.RoleSuggestion{
+			Name:           role.Name,
+			ID:             role.ID,
+			Description:    role.Description,
+			PrivilegeScore: privilegeScore(role),
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].PrivilegeScore != suggestions[j].PrivilegeScore {
+			return suggestions[i].PrivilegeScore < suggestions[j].PrivilegeScore
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// privilegeScore estimates how broad a role is: every pattern counts, and wildcards
+// count much more the earlier they appear in the pattern
+func privilegeScore(role models.RoleDefinition) int {
+	score := 0
+	for _, pattern := range append(append([]string{}, role.Actions...), role.DataActions...) {
+		score++
+		if index := strings.Index(pattern, "*"); index >= 0 {
+			// "*" alone matches everything; "Microsoft.Compute/*" is broader than ".../virtualMachines/*"
+			weight := 10000 / (strings.Count(pattern[:index], "/") + 1)
+			// Read-only wildcards such as "*/read" are far less dangerous than write wildcards
+			if strings.HasSuffix(strings.ToLower(pattern), "/read") {
+				weight /= 10
+			}
+			score += weight
+		}
+	}
+	return score
+}
@@ -0,0 +1,182 @@
+package permissions
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// genericServices are the command groups that operate on any resource type named with
+// --resource-type (and --namespace and --parent)
+var genericServices = map[string]bool{
+	"resource": true,
+}
+
+// resourceGroupType is the catalog resource type of resource groups
+const resourceGroupType = "subscriptions/resourceGroups"
+
+// ResourceTargets returns the resources a command names explicitly: one per resource ID in
+// --ids, or the type given with --resource-type for generic commands such as "az resource".
+// Resource group IDs map to Microsoft.Resources; other scopes and invalid IDs are skipped.
+func ResourceTargets(cmd *models.AzureCommand) []models.ResourceID {
+	var targets []models.ResourceID
+	for _, value := range strings.Fields(cmd.Parameters["ids"]) {
+		id, err := models.ParseResourceID(value)
+		if err != nil {
+			continue
+		}
+		if id.IsResourceGroup() {
+			id.Provider, id.ResourceType, id.Name = "Microsoft.Resources", resourceGroupType, id.ResourceGroup
+		}
+		if id.Provider != "" && id.ResourceType != "" {
+			targets = append(targets, id)
+		}
+	}
+	if len(targets) > 0 {
+		return targets
+	}
+
+	if !genericServices[strings.ToLower(cmd.Service)] {
+		return nil
+	}
+	if target, ok := genericResourceType(cmd.Parameters); ok {
+		return []models.ResourceID{target}
+	}
+	return nil
+}
+
+// genericResourceType reads the resource type of an "az resource" command, given either as
+// --resource-type Microsoft.Web/sites or as --namespace Microsoft.Web --resource-type sites,
+// with the parent resource types in --parent (e.g. sites/app for slots)
+func genericResourceType(parameters map[string]string) (models.ResourceID, bool) {
+	resourceType := strings.Trim(parameters["resource-type"], "/")
+	if resourceType == "" {
+		return models.ResourceID{}, false
+	}
+
+	namespace := parameters["namespace"]
+	if namespace == "" {
+		var found bool
+		if namespace, resourceType, found = strings.Cut(resourceType, "/"); !found || resourceType == "" {
+			return models.ResourceID{}, false
+		}
+	}
+
+	var types []string
+	if parent := strings.Trim(parameters["parent"], "/"); parent != "" {
+		for i, segment := range strings.Split(parent, "/") {
+			if i%2 == 0 {
+				types = append(types, segment)
+			}
+		}
+	}
+	types = append(types, resourceType)
+
+	return models.ResourceID{
+		Provider:     namespace,
+		ResourceType: strings.Join(types, "/"),
+		Name:         parameters["name"],
+	}, true
+}
+
+// resolveTargets finds the operations of the command on each resource it names. The
+// namespace and resource type come from the target, narrowed to the child type the command's
+// subgroups name, e.g. sites/config for "webapp config appsettings list" on a site. It
+// returns false when the namespace or resource type of a target is not in the catalog, so
+// that the caller can infer them from the command group instead.
+func (r *Resolver) resolveTargets(cmd *models.AzureCommand, targets []models.ResourceID, store *catalog.Store) ([]models.PermissionDetail, bool) {
+	subgroups, verb := commandPath(cmd)
+	permissionsSet := make(map[string]models.PermissionDetail)
+	for _, target := range targets {
+		providerOps, ok := catalog.LookupNamespace(store.Providers(), target.Provider)
+		if !ok {
+			r.debugf("⚠️  Provider '%s' of the resource ID is not in the catalog\n", target.Provider)
+			return nil, false
+		}
+		provider := providerOps.Namespace
+		if index := strings.LastIndex(provider, "/"); index >= 0 {
+			provider = provider[index+1:]
+		}
+		if provider == "" {
+			provider = target.Provider
+		}
+
+		resourceType := targetResourceType(providerOps, target.ResourceType, subgroups)
+		if resourceType == nil {
+			r.debugf("⚠️  Resource type '%s' of the resource ID is not in the catalog\n", target.FullType())
+			return nil, false
+		}
+		r.debugf("🎯 Using resource type '%s/%s' from the command's resource ID\n", provider, resourceType.Name)
+
+		matched := false
+		for _, operation := range resourceType.Operations {
+			if matchesOperation(verb, operation.Name) {
+				permissionsSet[operation.Name] = newPermissionDetail(provider, resourceType.Name, operation)
+				matched = true
+			}
+		}
+		if !matched {
+			for _, detail := range suggestResourceTypeOperations(verb, provider, resourceType.Name, store) {
+				permissionsSet[detail.Name] = detail
+			}
+		}
+	}
+
+	permissions := make([]models.PermissionDetail, 0, len(permissionsSet))
+	for _, detail := range permissionsSet {
+		permissions = append(permissions, detail)
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
+	return permissions, true
+}
+
+// commandPath splits the words of a command into its subgroups, the words between the base
+// service and the last word, and its verb, the last word. For
+// "webapp config appsettings list" the subgroups are config and appsettings and the verb
+// is list.
+func commandPath(cmd *models.AzureCommand) ([]string, string) {
+	words := append(strings.Fields(cmd.Service), cmd.Operation)
+	words = append(words, cmd.Subcommand...)
+	return words[1 : len(words)-1], words[len(words)-1]
+}
+
+// targetResourceType returns the resource type of a target in the namespace, descending to
+// the child type each subgroup names: "slot" selects sites/slots below sites. Subgroups that
+// name no child type, such as "appsettings", are skipped. It returns nil when the target's
+// own type is not in the namespace.
+func targetResourceType(providerOps models.ProviderOperationsResponse, targetType string, subgroups []string) *models.ProviderResourceType {
+	var current *models.ProviderResourceType
+	for i, rt := range providerOps.ResourceTypes {
+		if strings.EqualFold(rt.Name, targetType) {
+			current = &providerOps.ResourceTypes[i]
+			break
+		}
+	}
+	if current == nil {
+		return nil
+	}
+
+	for _, subgroup := range subgroups {
+		prefix := strings.ToLower(current.Name) + "/"
+		for i, rt := range providerOps.ResourceTypes {
+			child, found := strings.CutPrefix(strings.ToLower(rt.Name), prefix)
+			if found && !strings.Contains(child, "/") && namesResourceType(subgroup, child) {
+				current = &providerOps.ResourceTypes[i]
+				break
+			}
+		}
+	}
+	return current
+}
+
+// namesResourceType reports whether a command group word names a resource type segment,
+// e.g. "slot" for slots or "access-policy" for accessPolicies
+func namesResourceType(word, segment string) bool {
+	word = strings.ToLower(strings.ReplaceAll(word, "-", ""))
+	segment = strings.ToLower(segment)
+	return segment == word || segment == word+"s" || (strings.HasSuffix(word, "y") && segment == strings.TrimSuffix(word, "y")+"ies")
+}
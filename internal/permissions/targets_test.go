@@ -0,0 +1,109 @@
+package permissions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+)
+
+func TestResolveTargets(t *testing.T) {
+	const (
+		group = "/subscriptions/0000/resourceGroups/rg"
+		site  = group + "/providers/Microsoft.Web/sites/app"
+	)
+
+	providers := catalogtest.Realistic()
+	web := providers["Microsoft.Web"]
+	web.ResourceTypes = append(web.ResourceTypes,
+		models.ProviderResourceType{Name: "sites/config", Operations: []models.ProviderOperation{
+			{Name: "Microsoft.Web/sites/config/read"},
+			{Name: "Microsoft.Web/sites/config/write"},
+			{Name: "Microsoft.Web/sites/config/list/action"},
+		}},
+		models.ProviderResourceType{Name: "sites/slots", Operations: []models.ProviderOperation{
+			{Name: "Microsoft.Web/sites/slots/read"},
+			{Name: "Microsoft.Web/sites/slots/write"},
+		}},
+	)
+	providers["Microsoft.Web"] = web
+	store := catalog.NewStore(providers)
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{
+			command: "az webapp restart --ids " + site,
+			want:    []string{"Microsoft.Web/sites/restart/action"},
+		},
+		{
+			command: "az webapp config appsettings list --ids " + site,
+			want:    []string{"Microsoft.Web/sites/config/list/action", "Microsoft.Web/sites/config/read"},
+		},
+		{
+			command: "az webapp config appsettings set --ids " + site + " --settings KEY=value",
+			want:    []string{"Microsoft.Web/sites/config/write"},
+		},
+		{
+			command: "az webapp deployment slot create --ids " + site,
+			want:    []string{"Microsoft.Web/sites/slots/write"},
+		},
+		{
+			command: "az webapp show --ids " + site + "/slots/staging",
+			want:    []string{"Microsoft.Web/sites/slots/read"},
+		},
+		{
+			command: "az group show --ids " + group,
+			want:    []string{"Microsoft.Resources/subscriptions/resourceGroups/read"},
+		},
+		{
+			command: "az resource delete --resource-type Microsoft.Web/sites --name app -g rg",
+			want:    []string{"Microsoft.Web/sites/delete"},
+		},
+		{
+			// Types missing from the catalog fall back to the command group
+			command: "az webapp show --ids " + site + "/hybridConnectionNamespaces/ns",
+			want:    []string{"Microsoft.Web/operations/read", "Microsoft.Web/sites/read"},
+		},
+		{
+			command: "az vm show --ids " + group + "/providers/Microsoft.Unknown/things/thing",
+			want: []string{
+				"Microsoft.Compute/operations/read",
+				"Microsoft.Compute/virtualMachines/instanceView/read",
+				"Microsoft.Compute/virtualMachines/read",
+			},
+		},
+	}
+
+	resolver := NewResolver(nil)
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			cmd, err := parser.ParseAzureCommand(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			details, err := resolver.Resolve(cmd, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := PermissionNames(details); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespacesWithTargets(t *testing.T) {
+	cmd, err := parser.ParseAzureCommand("az vm show --ids /subscriptions/0000/resourceGroups/rg/providers/Microsoft.Unknown/things/thing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Microsoft.Unknown", "Microsoft.Compute"}
+	if got := NewResolver(nil).Namespaces(cmd); !reflect.DeepEqual(got, want) {
+		t.Errorf("Namespaces() = %v, want %v", got, want)
+	}
+}
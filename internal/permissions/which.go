@@ -0,0 +1,38 @@
+package permissions
+
+import (
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/parser"
+)
+
+// CatalogReferenceSource labels references found by resolving commands against the provider catalog
+const CatalogReferenceSource = "provider-catalog"
+
+// CommandsForPermission resolves the given Azure CLI commands (without the "az" prefix)
+// against the catalog and returns a reference for every required operation that matches
+// the permission, which may contain '*' wildcards. Commands that cannot be resolved are
+// skipped.
+func (r *Resolver) CommandsForPermission(permission string, commands []string, store *catalog.Store) []models.CommandReference {
+	references := []models.CommandReference{}
+	for _, command := range commands {
+		cmd, err := parser.ParseAzureCommand("az " + command)
+		if err != nil {
+			continue
+		}
+		required, err := r.Resolve(cmd, store)
+		if err != nil {
+			continue
+		}
+		for _, detail := range required {
+			if MatchesPattern(permission, detail.Name) {
+				references = append(references, models.CommandReference{
+					Command:    "az " + command,
+					Permission: detail.Name,
+					Source:     CatalogReferenceSource,
+				})
+			}
+		}
+	}
+	return references
+}
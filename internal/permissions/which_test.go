@@ -0,0 +1,103 @@
+package permissions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/catalog"
+	"github.com/mathwro/azperm/internal/catalog/catalogtest"
+	"github.com/mathwro/azperm/internal/models"
+)
+
+func TestResolverCommandsForPermission(t *testing.T) {
+	store := catalog.NewStore(catalogtest.Realistic())
+	commands := []string{"vm start", "vm show", "vm restart", "webapp show", "keyvault create", "unknownservice list"}
+
+	tests := []struct {
+		permission string
+		want       []models.CommandReference
+	}{
+		{
+			permission: "Microsoft.Compute/virtualMachines/start/action",
+			want: []models.CommandReference{
+				{Command: "az vm start", Permission: "Microsoft.Compute/virtualMachines/start/action", Source: CatalogReferenceSource},
+			},
+		},
+		{
+			permission: "microsoft.compute/VIRTUALMACHINES/start/action",
+			want: []models.CommandReference{
+				{Command: "az vm start", Permission: "Microsoft.Compute/virtualMachines/start/action", Source: CatalogReferenceSource},
+			},
+		},
+		{
+			permission: "*/read",
+			want: []models.CommandReference{
+				{Command: "az vm show", Permission: "Microsoft.Compute/operations/read", Source: CatalogReferenceSource},
+				{Command: "az vm show", Permission: "Microsoft.Compute/virtualMachines/instanceView/read", Source: CatalogReferenceSource},
+				{Command: "az vm show", Permission: "Microsoft.Compute/virtualMachines/read", Source: CatalogReferenceSource},
+				{Command: "az webapp show", Permission: "Microsoft.Web/operations/read", Source: CatalogReferenceSource},
+				{Command: "az webapp show", Permission: "Microsoft.Web/sites/read", Source: CatalogReferenceSource},
+			},
+		},
+		{
+			// vm start also matches restart/action by name
+			permission: "Microsoft.Compute/*/action",
+			want: []models.CommandReference{
+				{Command: "az vm start", Permission: "Microsoft.Compute/virtualMachines/restart/action", Source: CatalogReferenceSource},
+				{Command: "az vm start", Permission: "Microsoft.Compute/virtualMachines/start/action", Source: CatalogReferenceSource},
+				{Command: "az vm restart", Permission: "Microsoft.Compute/virtualMachines/restart/action", Source: CatalogReferenceSource},
+			},
+		},
+		{permission: "Microsoft.Storage/*", want: []models.CommandReference{}},
+	}
+
+	resolver := NewResolver(nil)
+	for _, tt := range tests {
+		t.Run(tt.permission, func(t *testing.T) {
+			if got := resolver.CommandsForPermission(tt.permission, commands, store); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommandsForPermission(%q) = %+v, want %+v", tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagerCommandsForPermission(t *testing.T) {
+	manager := NewManager()
+	manager.LoadPermissions()
+
+	tests := []struct {
+		permission string
+		want       []models.CommandReference
+	}{
+		{
+			permission: "Microsoft.Compute/virtualMachines/start/action",
+			want: []models.CommandReference{
+				{Command: "az vm start", Permission: "Microsoft.Compute/virtualMachines/start/action", Source: "default-minimal"},
+			},
+		},
+		{
+			permission: "Microsoft.Network/*/read",
+			want: []models.CommandReference{
+				{Command: "az vm create", Permission: "Microsoft.Network/virtualNetworks/read", Condition: "--vnet-name", Source: "default-minimal"},
+			},
+		},
+		{
+			permission: "Microsoft.ManagedIdentity/*",
+			want: []models.CommandReference{
+				{Command: "az aks create", Permission: "Microsoft.ManagedIdentity/userAssignedIdentities/assign/action", Condition: "--enable-managed-identity", Source: "default-minimal"},
+				{Command: "az sql server create", Permission: "Microsoft.ManagedIdentity/userAssignedIdentities/assign/action", Condition: "--assign-identity", Source: "default-minimal"},
+				{Command: "az storage account create", Permission: "Microsoft.ManagedIdentity/userAssignedIdentities/assign/action", Condition: "--assign-identity", Source: "default-minimal"},
+				{Command: "az vm create", Permission: "Microsoft.ManagedIdentity/userAssignedIdentities/assign/action", Condition: "--assign-identity", Source: "default-minimal"},
+			},
+		},
+		{permission: "Microsoft.Unknown/things/read", want: []models.CommandReference{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.permission, func(t *testing.T) {
+			if got := manager.CommandsForPermission(tt.permission); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CommandsForPermission(%q) = %+v, want %+v", tt.permission, got, tt.want)
+			}
+		})
+	}
+}
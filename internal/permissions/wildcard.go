@@ -0,0 +1,108 @@
+package permissions
+
+import (
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// MatchesPattern reports whether an operation matches an RBAC action pattern using Azure's
+// matching semantics: comparison is case-insensitive and '*' matches any sequence of
+// characters, including '/' so a single wildcard can span several path segments.
+func MatchesPattern(pattern, operation string) bool {
+	pattern = strings.ToLower(pattern)
+	operation = strings.ToLower(operation)
+
+	// Iterative glob matching with backtracking to the last wildcard
+	p, o := 0, 0
+	starP, starO := -1, 0
+	for o < len(operation) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starO = p, o
+			p++
+		case p < len(pattern) && pattern[p] == operation[o]:
+			p++
+			o++
+		case starP >= 0:
+			p = starP + 1
+			starO++
+			o = starO
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// MatchesAnyPattern reports whether the operation matches at least one of the patterns
+func MatchesAnyPattern(patterns []string, operation string) bool {
+	for _, pattern := range patterns {
+		if MatchesPattern(pattern, operation) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGranted reports whether an operation is allowed by any of the permission sets.
+// NotActions only subtract from the Actions of the permission set they belong to.
+func IsGranted(operation string, isDataAction bool, grants []models.Permission) bool {
+	for _, grant := range grants {
+		allowed, excluded := grant.Actions, grant.NotActions
+		if isDataAction {
+			allowed, excluded = grant.DataActions, grant.NotDataActions
+		}
+
+		if MatchesAnyPattern(allowed, operation) && !MatchesAnyPattern(excluded, operation) {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingPermissions returns the required permissions that none of the permission sets grant
+func MissingPermissions(required []models.PermissionDetail, grants []models.Permission) []models.PermissionDetail {
+	var missing []models.PermissionDetail
+	for _, permission := range required {
+		if !IsGranted(permission.Name, permission.IsDataAction, grants) {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
+
+// RolePermissions returns the permission sets granted by a role definition
+func RolePermissions(role models.RoleDefinition) []models.Permission {
+	if len(role.Permissions) > 0 {
+		return role.Permissions
+	}
+	return []models.Permission{{
+		Actions:        role.Actions,
+		NotActions:     role.NotActions,
+		DataActions:    role.DataActions,
+		NotDataActions: role.NotDataActions,
+	}}
+}
+
+// ExpandPermission returns the catalog operations granted by a permission set: control plane
+// operations matched by Actions but not NotActions, and data plane operations matched by
+// DataActions but not NotDataActions
+func ExpandPermission(permission models.Permission, operations []models.OperationInfo) []models.OperationInfo {
+	return expandGrants([]models.Permission{permission}, operations)
+}
+
+// expandGrants returns the catalog operations granted by any of the permission sets
+func expandGrants(grants []models.Permission, operations []models.OperationInfo) []models.OperationInfo {
+	expanded := []models.OperationInfo{}
+	for _, operation := range operations {
+		if IsGranted(operation.Name, operation.IsDataAction, grants) {
+			expanded = append(expanded, operation)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,133 @@
+package permissions
+
+import (
+	"testing"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		operation string
+		want      bool
+	}{
+		{"*", "Microsoft.Compute/virtualMachines/read", true},
+		{"Microsoft.Compute/virtualMachines/read", "Microsoft.Compute/virtualMachines/read", true},
+		{"microsoft.compute/VIRTUALMACHINES/read", "Microsoft.Compute/virtualMachines/read", true},
+		{"Microsoft.Compute/*", "Microsoft.Compute/virtualMachines/start/action", true},
+		{"Microsoft.Compute/*/read", "Microsoft.Compute/virtualMachines/read", true},
+		{"Microsoft.Compute/*/read", "Microsoft.Compute/virtualMachines/extensions/read", true},
+		{"Microsoft.Compute/*/read", "Microsoft.Compute/virtualMachines/delete", false},
+		{"*/read", "Microsoft.Storage/storageAccounts/read", true},
+		{"Microsoft.Storage/*/listKeys/*", "Microsoft.Storage/storageAccounts/listKeys/action", true},
+		{"Microsoft.Storage/*/listKeys/*", "Microsoft.Storage/storageAccounts/regenerateKey/action", false},
+		{"Microsoft.*/read", "Microsoft.Web/sites/read", true},
+		{"Microsoft.Compute/**", "Microsoft.Compute/disks/read", true},
+		{"Microsoft.Compute", "Microsoft.Compute/disks/read", false},
+		{"Microsoft.Compute/disks/read", "Microsoft.Compute/disks/read/extra", false},
+		{"", "Microsoft.Compute/disks/read", false},
+		{"*", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesPattern(tt.pattern, tt.operation); got != tt.want {
+			t.Errorf("MatchesPattern(%q, %q) = %v, want %v", tt.pattern, tt.operation, got, tt.want)
+		}
+	}
+}
+
+func TestIsGranted(t *testing.T) {
+	grants := []models.Permission{
+		{
+			Actions:     []string{"Microsoft.Compute/*"},
+			NotActions:  []string{"Microsoft.Compute/*/delete"},
+			DataActions: []string{"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read"},
+		},
+		{
+			Actions: []string{"Microsoft.Compute/disks/delete"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		operation    string
+		isDataAction bool
+		want         bool
+	}{
+		{"action", "Microsoft.Compute/virtualMachines/read", false, true},
+		{"excluded by NotActions", "Microsoft.Compute/virtualMachines/delete", false, false},
+		{"NotActions only apply to their own set", "Microsoft.Compute/disks/delete", false, true},
+		{"data action", "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read", true, true},
+		{"data action is not granted by Actions", "Microsoft.Compute/virtualMachines/read", true, false},
+		{"action is not granted by DataActions", "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGranted(tt.operation, tt.isDataAction, grants); got != tt.want {
+				t.Errorf("IsGranted(%q, %v) = %v, want %v", tt.operation, tt.isDataAction, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	single := models.RoleDefinition{Name: "single"}
+	single.SetPermissions([]models.Permission{
+		{Actions: []string{"Microsoft.Compute/*"}, NotActions: []string{"Microsoft.Compute/*/delete"}},
+	})
+	if len(single.Permissions) != 0 || len(single.NotActions) != 1 {
+		t.Errorf("single entry: Permissions = %v, NotActions = %v, want the flat fields", single.Permissions, single.NotActions)
+	}
+	if IsGranted("Microsoft.Compute/disks/delete", false, RolePermissions(single)) {
+		t.Error("single entry: Microsoft.Compute/disks/delete granted, want excluded by NotActions")
+	}
+
+	// The NotActions of one entry must not subtract the Actions of another
+	multiple := models.RoleDefinition{Name: "multiple"}
+	multiple.SetPermissions([]models.Permission{
+		{Actions: []string{"Microsoft.Compute/*"}, NotActions: []string{"Microsoft.Compute/*/delete"}},
+		{Actions: []string{"Microsoft.Compute/disks/delete"}},
+	})
+	if len(multiple.NotActions) != 0 {
+		t.Errorf("multiple entries: NotActions = %v, want none", multiple.NotActions)
+	}
+	grants := RolePermissions(multiple)
+	if len(grants) != 2 {
+		t.Fatalf("multiple entries: %d permission sets, want 2", len(grants))
+	}
+	if !IsGranted("Microsoft.Compute/disks/delete", false, grants) {
+		t.Error("multiple entries: Microsoft.Compute/disks/delete not granted")
+	}
+	if IsGranted("Microsoft.Compute/virtualMachines/delete", false, grants) {
+		t.Error("multiple entries: Microsoft.Compute/virtualMachines/delete granted, want excluded by NotActions")
+	}
+}
+
+func TestExpandPermission(t *testing.T) {
+	operations := []models.OperationInfo{
+		{ProviderOperation: models.ProviderOperation{Name: "Microsoft.Compute/disks/read"}},
+		{ProviderOperation: models.ProviderOperation{Name: "Microsoft.Compute/disks/delete"}},
+		{ProviderOperation: models.ProviderOperation{Name: "Microsoft.Compute/virtualMachines/read"}},
+		{ProviderOperation: models.ProviderOperation{Name: "Microsoft.Compute/virtualMachines/read/data", IsDataAction: true}},
+		{ProviderOperation: models.ProviderOperation{Name: "Microsoft.Web/sites/read"}},
+	}
+
+	permission := models.Permission{
+		Actions:    []string{"Microsoft.Compute/*/read", "Microsoft.Compute/disks/*"},
+		NotActions: []string{"Microsoft.Compute/disks/delete"},
+	}
+	got := ExpandPermission(permission, operations)
+
+	want := []string{"Microsoft.Compute/disks/read", "Microsoft.Compute/virtualMachines/read"}
+	if len(got) != len(want) {
+		t.Fatalf("ExpandPermission() returned %d operations, want %d: %+v", len(got), len(want), got)
+	}
+	for i, operation := range got {
+		if operation.Name != want[i] {
+			t.Errorf("ExpandPermission()[%d] = %s, want %s", i, operation.Name, want[i])
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mathwro/azperm/internal/models"
+	"github.com/mathwro/azperm/internal/permissions"
+)
+
+// DefaultFile is the policy file picked up from the working directory
+const DefaultFile = ".azperm-policy.yaml"
+
+// Rule severities, from most to least severe
+const (
+	SeverityDeny = "deny"
+	SeverityWarn = "warn"
+	SeverityInfo = "info"
+)
+
+// RequireAuthModeLogin requires data plane commands to authenticate with --auth-mode login,
+// so access is governed by RBAC DataActions rather than account keys
+const RequireAuthModeLogin = "auth-mode-login"
+
+// severities lists the valid severities from most to least severe
+var severities = []string{SeverityDeny, SeverityWarn, SeverityInfo}
+
+// defaultExitCodes are used for severities the policy file does not configure
+var defaultExitCodes = map[string]int{
+	SeverityDeny: 3,
+	SeverityWarn: 0,
+	SeverityInfo: 0,
+}
+
+// Rule flags commands whose required permissions match one of the patterns, or commands
+// that do not meet a requirement. Patterns use Azure RBAC wildcard semantics.
+type Rule struct {
+	ID          string   `yaml:"id"`
+	Description string   `yaml:"description"`
+	Severity    string   `yaml:"severity"`
+	Permissions []string `yaml:"permissions"`
+	// Commands limits the rule to commands matching these patterns, e.g. "storage blob *"
+	Commands []string `yaml:"commands"`
+	Require  string   `yaml:"require"`
+}
+
+// Policy is a set of rules and the exit codes used when they are violated
+type Policy struct {
+	Rules     []Rule         `yaml:"rules"`
+	ExitCodes map[string]int `yaml:"exit-codes"`
+}
+
+// Load reads and validates a policy file
+func Load(path string) (*Policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	policy, err := Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Parse parses and validates policy YAML
+func Parse(content []byte) (*Policy, error) {
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, rule := range policy.Rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule %d has no id", i+1)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("duplicate rule id: %s", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if !validSeverity(rule.Severity) {
+			return nil, fmt.Errorf("rule %s: invalid severity %q (expected %s)", rule.ID, rule.Severity, strings.Join(severities, ", "))
+		}
+		if len(rule.Permissions) == 0 && rule.Require == "" {
+			return nil, fmt.Errorf("rule %s: expected permissions or require", rule.ID)
+		}
+		if rule.Require != "" && rule.Require != RequireAuthModeLogin {
+			return nil, fmt.Errorf("rule %s: unknown requirement %q (expected %s)", rule.ID, rule.Require, RequireAuthModeLogin)
+		}
+	}
+
+	for severity := range policy.ExitCodes {
+		if !validSeverity(severity) {
+			return nil, fmt.Errorf("invalid severity in exit-codes: %q", severity)
+		}
+	}
+
+	return &policy, nil
+}
+
+// validSeverity reports whether a severity is known
+func validSeverity(severity string) bool {
+	for _, valid := range severities {
+		if severity == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate checks analysis results against the policy rules and returns the violations
+// in the order of the results
+func (p *Policy) Evaluate(results []models.AnalysisResult) []models.PolicyViolation {
+	violations := []models.PolicyViolation{}
+
+	for _, result := range results {
+		command := strings.TrimPrefix(result.Command, "az ")
+		for _, rule := range p.Rules {
+			if len(rule.Commands) > 0 && !permissions.MatchesAnyPattern(rule.Commands, command) {
+				continue
+			}
+
+			violation := models.PolicyViolation{
+				RuleID:   rule.ID,
+				Severity: rule.Severity,
+				Command:  result.Command,
+				File:     result.File,
+				Line:     result.Line,
+				Column:   result.Column,
+			}
+
+			for _, permission := range result.Permissions {
+				if permissions.MatchesAnyPattern(rule.Permissions, permission.Name) {
+					violation.Permission = permission.Name
+					violation.Message = fmt.Sprintf("%s requires %s", result.Command, permission.Name)
+					violations = append(violations, withDescription(violation, rule))
+				}
+			}
+
+			if rule.Require == RequireAuthModeLogin && isDataPlaneCommand(result) &&
+				!strings.EqualFold(result.Parameters["auth-mode"], "login") {
+				violation.Message = fmt.Sprintf("%s must use --auth-mode login", result.Command)
+				violations = append(violations, withDescription(violation, rule))
+			}
+		}
+	}
+
+	return violations
+}
+
+// withDescription appends the rule description to the message of a violation
+func withDescription(violation models.PolicyViolation, rule Rule) models.PolicyViolation {
+	if rule.Description != "" {
+		violation.Message += ": " + rule.Description
+	}
+	return violation
+}
+
+// authModeServices are the command groups that accept --auth-mode, choosing between account
+// keys and RBAC DataActions for data access
+var authModeServices = []string{
+	"storage blob", "storage container", "storage queue", "storage table", "storage fs",
+	"storage file", "storage share", "storage message", "storage entity", "storage directory",
+}
+
+// isDataPlaneCommand reports whether a command accesses data through a data plane API that
+// also accepts account keys, i.e. whether it takes --auth-mode
+func isDataPlaneCommand(result models.AnalysisResult) bool {
+	service := strings.ToLower(result.Service)
+	for _, group := range authModeServices {
+		if service == group || strings.HasPrefix(service, group+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns the exit code for the most severe violation, or 0 if no
+// violated severity has a non-zero exit code
+func (p *Policy) ExitCode(violations []models.PolicyViolation) int {
+	for _, severity := range severities {
+		code, configured := p.ExitCodes[severity]
+		if !configured {
+			code = defaultExitCodes[severity]
+		}
+		if code == 0 {
+			continue
+		}
+		for _, violation := range violations {
+			if violation.Severity == severity {
+				return code
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,168 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `rules:
+  - id: no-role-assignments
+    severity: deny
+    permissions: ["Microsoft.Authorization/roleAssignments/write"]
+  - id: storage-login
+    severity: warn
+    require: auth-mode-login
+exit-codes:
+  warn: 2
+`,
+		},
+		{"missing id", "rules:\n  - severity: deny\n    permissions: ['*']\n", "has no id"},
+		{"duplicate id", "rules:\n  - {id: a, severity: deny, permissions: ['*']}\n  - {id: a, severity: warn, permissions: ['*']}\n", "duplicate rule id"},
+		{"invalid severity", "rules:\n  - {id: a, severity: fatal, permissions: ['*']}\n", "invalid severity"},
+		{"no permissions or requirement", "rules:\n  - {id: a, severity: deny}\n", "expected permissions or require"},
+		{"unknown requirement", "rules:\n  - {id: a, severity: deny, require: mfa}\n", "unknown requirement"},
+		{"invalid exit code severity", "exit-codes:\n  error: 1\n", "invalid severity in exit-codes"},
+		{"unknown field", "rules:\n  - {id: a, severity: deny, permission: ['*']}\n", "failed to parse policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	policy, err := Parse([]byte(`rules:
+  - id: no-role-assignments
+    description: grant roles through PIM
+    severity: deny
+    permissions: ["Microsoft.Authorization/roleAssignments/*"]
+  - id: no-vm-delete
+    severity: warn
+    permissions: ["Microsoft.Compute/*/delete"]
+    commands: ["vm *"]
+  - id: storage-login
+    severity: info
+    require: auth-mode-login
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []models.AnalysisResult{
+		{
+			Command: "az role assignment create --role Owner",
+			Service: "role assignment", Line: 4, Column: 1,
+			Permissions: []models.PermissionDetail{{Name: "Microsoft.Authorization/roleAssignments/write"}},
+		},
+		{
+			Command:     "az vm delete --name vm1",
+			Service:     "vm",
+			Permissions: []models.PermissionDetail{{Name: "Microsoft.Compute/virtualMachines/delete"}},
+		},
+		{
+			Command:     "az disk delete --name disk1",
+			Service:     "disk",
+			Permissions: []models.PermissionDetail{{Name: "Microsoft.Compute/disks/delete"}},
+		},
+		{
+			Command:     "az storage blob upload --account-name st --auth-mode key",
+			Service:     "storage blob",
+			Parameters:  map[string]string{"auth-mode": "key"},
+			Permissions: []models.PermissionDetail{{Name: "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/write", IsDataAction: true}},
+		},
+		{
+			Command:     "az storage blob download --account-name st --auth-mode login",
+			Service:     "storage blob",
+			Parameters:  map[string]string{"auth-mode": "login"},
+			Permissions: []models.PermissionDetail{{Name: "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read", IsDataAction: true}},
+		},
+		{
+			// Key Vault has no --auth-mode, so the requirement does not apply
+			Command:     "az keyvault secret show --vault-name kv --name secret",
+			Service:     "keyvault secret",
+			Permissions: []models.PermissionDetail{{Name: "Microsoft.KeyVault/vaults/secrets/getSecret/action", IsDataAction: true}},
+		},
+	}
+
+	violations := policy.Evaluate(results)
+
+	want := []models.PolicyViolation{
+		{
+			RuleID: "no-role-assignments", Severity: SeverityDeny,
+			Message:    "az role assignment create --role Owner requires Microsoft.Authorization/roleAssignments/write: grant roles through PIM",
+			Command:    "az role assignment create --role Owner",
+			Permission: "Microsoft.Authorization/roleAssignments/write",
+			Line:       4, Column: 1,
+		},
+		{
+			RuleID: "no-vm-delete", Severity: SeverityWarn,
+			Message:    "az vm delete --name vm1 requires Microsoft.Compute/virtualMachines/delete",
+			Command:    "az vm delete --name vm1",
+			Permission: "Microsoft.Compute/virtualMachines/delete",
+		},
+		{
+			RuleID: "storage-login", Severity: SeverityInfo,
+			Message: "az storage blob upload --account-name st --auth-mode key must use --auth-mode login",
+			Command: "az storage blob upload --account-name st --auth-mode key",
+		},
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("Evaluate() returned %d violations, want %d: %+v", len(violations), len(want), violations)
+	}
+	for i := range want {
+		if violations[i] != want[i] {
+			t.Errorf("violation %d =\n%+v\nwant\n%+v", i, violations[i], want[i])
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	deny := models.PolicyViolation{Severity: SeverityDeny}
+	warn := models.PolicyViolation{Severity: SeverityWarn}
+	info := models.PolicyViolation{Severity: SeverityInfo}
+
+	tests := []struct {
+		name       string
+		exitCodes  map[string]int
+		violations []models.PolicyViolation
+		want       int
+	}{
+		{"no violations", nil, nil, 0},
+		{"default deny", nil, []models.PolicyViolation{warn, deny}, 3},
+		{"default warn", nil, []models.PolicyViolation{warn, info}, 0},
+		{"configured warn", map[string]int{SeverityWarn: 2}, []models.PolicyViolation{info, warn}, 2},
+		{"most severe wins", map[string]int{SeverityWarn: 2, SeverityDeny: 5}, []models.PolicyViolation{warn, deny}, 5},
+		{"deny disabled falls back to warn", map[string]int{SeverityDeny: 0, SeverityWarn: 2}, []models.PolicyViolation{deny, warn}, 2},
+		{"configured info", map[string]int{SeverityInfo: 1}, []models.PolicyViolation{info}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &Policy{ExitCodes: tt.exitCodes}
+			if got := policy.ExitCode(tt.violations); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package registry
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// SourceDiscovered labels services matched to a resource provider by name similarity
+const SourceDiscovered = "discovered"
+
+// Similarity scores of two names
+const (
+	similarityNone = iota
+	similarityContains
+	similarityPrefix
+	similarityEqual
+)
+
+// minSimilarityLength is the shortest name matched as a prefix or substring of another
+const minSimilarityLength = 4
+
+// discoveryCandidate is a provider namespace and resource type scored against a command group
+type discoveryCandidate struct {
+	namespace    string
+	resourceType string
+	namespaceHit int
+	typeHit      int
+}
+
+// score ranks candidates; a match on the namespace outweighs any resource type match
+func (c discoveryCandidate) score() int {
+	return c.namespaceHit*4 + c.typeHit
+}
+
+// Discover matches a command group that is not in the registry to a resource provider and
+// resource type by name similarity, e.g. "databricks workspace" to
+// Microsoft.Databricks/workspaces. The first word of the group is compared with the
+// namespace and every word with the last segment of each resource type. It reports false
+// when no namespace or resource type matches closely enough.
+func Discover(group string, providers []models.ResourceProvider) (Service, bool) {
+	words := strings.Fields(normalize(group))
+	if len(words) == 0 {
+		return Service{}, false
+	}
+
+	var best discoveryCandidate
+	for _, provider := range providers {
+		namespace := provider.Namespace
+		if index := strings.Index(namespace, "."); index >= 0 {
+			namespace = namespace[index+1:]
+		}
+		candidate := discoveryCandidate{
+			namespace:    provider.Namespace,
+			namespaceHit: similarity(words[0], namespace),
+		}
+
+		namedByGroup := false
+		for _, resourceType := range resourceTypeNames(provider) {
+			segments := strings.Split(resourceType, "/")
+			last := segments[len(segments)-1]
+			hit := similarityNone
+			for _, word := range words {
+				hit = max(hit, similarity(word, last))
+			}
+			if hit > candidate.typeHit || (hit == candidate.typeHit && hit > similarityNone && betterResourceType(resourceType, candidate.resourceType)) {
+				candidate.resourceType, candidate.typeHit = resourceType, hit
+			}
+			if len(segments) == 1 && similarity(words[0], last) == similarityEqual {
+				namedByGroup = true
+			}
+		}
+
+		// Without a namespace match, only a top-level resource type named like the group
+		// itself (e.g. "redis" for Microsoft.Cache/redis) identifies the provider
+		if candidate.namespaceHit < similarityPrefix && !namedByGroup {
+			continue
+		}
+		if candidate.score() > best.score() || (candidate.score() == best.score() && len(candidate.namespace) < len(best.namespace)) {
+			best = candidate
+		}
+	}
+
+	if best.namespace == "" {
+		return Service{}, false
+	}
+	return Service{
+		Group:        strings.Join(words, " "),
+		Provider:     best.namespace,
+		ResourceType: best.resourceType,
+		Source:       SourceDiscovered,
+	}, true
+}
+
+// resourceTypeNames returns the resource type paths of a provider, sorted
+func resourceTypeNames(provider models.ResourceProvider) []string {
+	names := make([]string, 0, len(provider.ResourceTypes))
+	for _, resourceType := range provider.ResourceTypes {
+		name := resourceType.ResourceType
+		if name == "" {
+			name = resourceType.Name
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// betterResourceType prefers top-level resource types over nested ones, then shorter paths
+func betterResourceType(candidate, current string) bool {
+	if current == "" {
+		return true
+	}
+	candidateDepth, currentDepth := strings.Count(candidate, "/"), strings.Count(current, "/")
+	if candidateDepth != currentDepth {
+		return candidateDepth < currentDepth
+	}
+	return len(candidate) < len(current)
+}
+
+// similarity compares a command group word with a provider or resource type name, ignoring
+// case, punctuation and plural forms
+func similarity(word, name string) int {
+	a, b := compactName(word), compactName(name)
+	if a == "" || b == "" {
+		return similarityNone
+	}
+	if singular(a) == singular(b) {
+		return similarityEqual
+	}
+
+	shorter := min(len(a), len(b))
+	switch {
+	case shorter < minSimilarityLength:
+		return similarityNone
+	case strings.HasPrefix(a, b) || strings.HasPrefix(b, a):
+		return similarityPrefix
+	case strings.Contains(a, b) || strings.Contains(b, a):
+		return similarityContains
+	default:
+		return similarityNone
+	}
+}
+
+// compactName lowercases a name and drops everything but letters and digits
+func compactName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// singular strips the common English plural endings of a compacted name
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 4:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 3:
+		return strings.TrimSuffix(name, "s")
+	default:
+		return name
+	}
+}
@@ -0,0 +1,222 @@
+// Package registry maps Azure CLI command groups to the resource provider namespaces and
+// resource types they manage
+package registry
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of registry entries
+const (
+	SourceBuiltIn = "built-in"
+	SourceUser    = "user"
+)
+
+//go:embed services.yaml
+var builtInServices []byte
+
+// Service describes an az command group
+type Service struct {
+	Group        string              `json:"group" yaml:"group"`
+	Aliases      []string            `json:"aliases,omitempty" yaml:"aliases"`
+	Provider     string              `json:"provider" yaml:"provider"`
+	ResourceType string              `json:"resourceType,omitempty" yaml:"resourceType"`
+	Operations   map[string][]string `json:"operations,omitempty" yaml:"operations"`
+	// ControlPlane marks a group of more than one word as managing resources, e.g. "storage
+	// account"; other such groups are matched as data plane commands
+	ControlPlane bool `json:"controlPlane,omitempty" yaml:"controlPlane"`
+	// TypeNames are the words the resource types of the group use when they differ from the
+	// group name, e.g. "vault" for keyvault. They match data plane commands of subgroups.
+	TypeNames []string `json:"typeNames,omitempty" yaml:"typeNames"`
+	Source    string   `json:"source" yaml:"-"`
+}
+
+// Registry resolves az command groups to services
+type Registry struct {
+	services []Service
+	byName   map[string]int
+}
+
+var (
+	defaultMu       sync.Mutex
+	defaultRegistry *Registry
+)
+
+// Default returns the built-in registry, including the user services added with Extend
+func Default() *Registry {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultRegistry == nil {
+		services, err := Parse(builtInServices, SourceBuiltIn)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in service registry: %v", err))
+		}
+		defaultRegistry = New(services)
+	}
+	return defaultRegistry
+}
+
+// Extend adds services to the default registry. A service with the group of a built-in
+// service replaces it. Registries are immutable, so lookups on a registry returned by
+// Default before the call are unaffected.
+func Extend(services []Service) {
+	Default()
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultRegistry = New(defaultRegistry.services, services)
+}
+
+// DefaultExtensionsPath returns the file user-defined services are loaded from
+func DefaultExtensionsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "azperm", "services.yaml"), nil
+}
+
+// LoadFile reads user-defined services from a YAML file with a top-level "services" list
+func LoadFile(path string) ([]Service, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service registry: %w", err)
+	}
+
+	services, err := Parse(content, SourceUser)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return services, nil
+}
+
+// Parse decodes and validates a services file, labelling the services with source
+func Parse(content []byte, source string) ([]Service, error) {
+	var file struct {
+		Services []Service `yaml:"services"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse service registry: %w", err)
+	}
+
+	for i := range file.Services {
+		service := &file.Services[i]
+		service.Group = normalize(service.Group)
+		if service.Group == "" {
+			return nil, fmt.Errorf("service %d: group is required", i+1)
+		}
+		if service.Provider == "" {
+			return nil, fmt.Errorf("service %s: provider is required", service.Group)
+		}
+		operations := make(map[string][]string, len(service.Operations))
+		for operation, resourceTypes := range service.Operations {
+			operations[strings.ToLower(operation)] = resourceTypes
+		}
+		if service.Operations != nil {
+			service.Operations = operations
+		}
+		service.Source = source
+	}
+	return file.Services, nil
+}
+
+// New builds a registry from lists of services. Services in later lists replace services
+// of the same group in earlier ones.
+func New(lists ...[]Service) *Registry {
+	registry := &Registry{byName: make(map[string]int)}
+
+	// Index groups first so an alias never shadows a group of the same name
+	for _, services := range lists {
+		for _, service := range services {
+			if index, ok := registry.byName[service.Group]; ok {
+				registry.services[index] = service
+			} else {
+				registry.byName[service.Group] = len(registry.services)
+				registry.services = append(registry.services, service)
+			}
+		}
+	}
+	for i, service := range registry.services {
+		for _, alias := range service.Aliases {
+			if _, ok := registry.byName[normalize(alias)]; !ok {
+				registry.byName[normalize(alias)] = i
+			}
+		}
+	}
+	return registry
+}
+
+// Services returns the services sorted by group
+func (r *Registry) Services() []Service {
+	services := append([]Service{}, r.services...)
+	sort.Slice(services, func(i, j int) bool { return services[i].Group < services[j].Group })
+	return services
+}
+
+// Exact finds the service of a command group or alias, ignoring case
+func (r *Registry) Exact(group string) (Service, bool) {
+	index, ok := r.byName[normalize(group)]
+	if !ok {
+		return Service{}, false
+	}
+	return r.services[index], true
+}
+
+// Lookup finds the service of a command group, falling back to the closest parent group,
+// e.g. "network vnet subnet" resolves to "network vnet"
+func (r *Registry) Lookup(group string) (Service, bool) {
+	words := strings.Fields(normalize(group))
+	for n := len(words); n > 0; n-- {
+		if service, ok := r.Exact(strings.Join(words[:n], " ")); ok {
+			return service, true
+		}
+	}
+	return Service{}, false
+}
+
+// Provider returns the resource provider namespace of a command group, or ""
+func (r *Registry) Provider(group string) string {
+	service, _ := r.Lookup(group)
+	return service.Provider
+}
+
+// ResourceTypes returns the resource types an operation of a command group may use and
+// whether the group restricts them. Only exact groups are considered.
+func (r *Registry) ResourceTypes(group, operation string) ([]string, bool) {
+	service, ok := r.Exact(group)
+	if !ok || service.Operations == nil {
+		return nil, false
+	}
+	return service.Operations[strings.ToLower(operation)], true
+}
+
+// ControlPlane reports whether a command group or alias is marked as managing resources.
+// Only exact groups are considered.
+func (r *Registry) ControlPlane(group string) bool {
+	service, ok := r.Exact(group)
+	return ok && service.ControlPlane
+}
+
+// TypeNames returns the words the resource types of a command group use, or nil when they
+// use the group name. Only exact groups are considered.
+func (r *Registry) TypeNames(group string) []string {
+	service, _ := r.Exact(group)
+	return service.TypeNames
+}
+
+// normalize lowercases a command group and collapses its whitespace
+func normalize(group string) string {
+	return strings.Join(strings.Fields(strings.ToLower(group)), " ")
+}
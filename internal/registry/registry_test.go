@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", "services:\n  - {group: ' Storage  Blob ', provider: Microsoft.Storage, controlPlane: false, typeNames: [blob]}\n", ""},
+		{"missing group", "services:\n  - {provider: Microsoft.Storage}\n", "group is required"},
+		{"missing provider", "services:\n  - {group: storage}\n", "provider is required"},
+		{"unknown field", "services:\n  - {group: storage, provider: Microsoft.Storage, dataPlane: true}\n", "failed to parse service registry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, err := Parse([]byte(tt.content), SourceUser)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if services[0].Group != "storage blob" || services[0].Source != SourceUser {
+					t.Errorf("Parse() = %+v, want a normalized user service", services[0])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultLookups(t *testing.T) {
+	registry := Default()
+
+	tests := []struct {
+		group            string
+		wantProvider     string
+		wantControlPlane bool
+		wantTypeNames    []string
+	}{
+		{"vm", "Microsoft.Compute", false, nil},
+		{"network vnet subnet", "Microsoft.Network", false, nil},
+		{"network vnet", "Microsoft.Network", true, nil},
+		{"Storage Account", "Microsoft.Storage", true, nil},
+		{"storage blob", "Microsoft.Storage", false, nil},
+		{"storage", "Microsoft.Storage", false, []string{"storageaccount", "storageaccounts"}},
+		{"key vault", "Microsoft.KeyVault", true, []string{"vault", "vaults"}},
+		{"app service", "Microsoft.Web", true, nil},
+		{"cosmosdb", "Microsoft.DocumentDB", false, []string{"documentdb", "cosmos"}},
+		{"unknown", "", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			if provider := registry.Provider(tt.group); provider != tt.wantProvider {
+				t.Errorf("Provider() = %q, want %q", provider, tt.wantProvider)
+			}
+			if controlPlane := registry.ControlPlane(tt.group); controlPlane != tt.wantControlPlane {
+				t.Errorf("ControlPlane() = %v, want %v", controlPlane, tt.wantControlPlane)
+			}
+			if typeNames := registry.TypeNames(tt.group); !reflect.DeepEqual(typeNames, tt.wantTypeNames) {
+				t.Errorf("TypeNames() = %v, want %v", typeNames, tt.wantTypeNames)
+			}
+		})
+	}
+}
+
+func TestNewReplacesGroups(t *testing.T) {
+	builtIn := []Service{{Group: "storage account", Provider: "Microsoft.Storage", ControlPlane: true, Source: SourceBuiltIn}}
+	user := []Service{{Group: "storage account", Provider: "Contoso.Storage", Source: SourceUser}}
+
+	registry := New(builtIn, user)
+	if service, ok := registry.Exact("storage account"); !ok || service.Provider != "Contoso.Storage" || service.ControlPlane {
+		t.Errorf("Exact() = %+v, want the user service", service)
+	}
+}
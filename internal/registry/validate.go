@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mathwro/azperm/internal/models"
+)
+
+// Issue is a registry entry that does not exist in a provider operations catalog
+type Issue struct {
+	Group   string `json:"group"`
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+// Validate checks that the provider namespace and resource types of every service exist in
+// the catalog. Namespaces and resource types are compared ignoring case.
+func (r *Registry) Validate(providers map[string]models.ProviderOperationsResponse) []Issue {
+	namespaces := make(map[string]models.ProviderOperationsResponse, len(providers))
+	for namespace, provider := range providers {
+		namespaces[strings.ToLower(namespace)] = provider
+	}
+
+	var issues []Issue
+	for _, service := range r.Services() {
+		provider, ok := namespaces[strings.ToLower(service.Provider)]
+		if !ok {
+			issues = append(issues, Issue{
+				Group:   service.Group,
+				Source:  service.Source,
+				Message: fmt.Sprintf("provider %s not found", service.Provider),
+			})
+			continue
+		}
+
+		resourceTypes := make(map[string]bool, len(provider.ResourceTypes))
+		for _, resourceType := range provider.ResourceTypes {
+			resourceTypes[strings.ToLower(resourceType.Name)] = true
+		}
+		for _, resourceType := range referencedResourceTypes(service) {
+			if !resourceTypes[strings.ToLower(resourceType)] {
+				issues = append(issues, Issue{
+					Group:   service.Group,
+					Source:  service.Source,
+					Message: fmt.Sprintf("resource type %s/%s not found", service.Provider, resourceType),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// referencedResourceTypes returns the distinct resource types a service refers to, sorted
+func referencedResourceTypes(service Service) []string {
+	seen := make(map[string]bool)
+	var resourceTypes []string
+	add := func(resourceType string) {
+		if resourceType != "" && !seen[strings.ToLower(resourceType)] {
+			seen[strings.ToLower(resourceType)] = true
+			resourceTypes = append(resourceTypes, resourceType)
+		}
+	}
+
+	add(service.ResourceType)
+	for _, types := range service.Operations {
+		for _, resourceType := range types {
+			add(resourceType)
+		}
+	}
+	sort.Strings(resourceTypes)
+	return resourceTypes
+}
@@ -0,0 +1,346 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxTemplateDepth limits template nesting, matching the Azure Pipelines limit
+const maxTemplateDepth = 20
+
+// ServiceConnection groups the Azure CLI invocations that run under an Azure Resource
+// Manager service connection. Invocations of plain script steps have an empty Name.
+type ServiceConnection struct {
+	Name        string       `json:"name"`
+	Invocations []Invocation `json:"invocations"`
+}
+
+// Pipeline is an Azure Pipelines definition with its Azure CLI invocations grouped by service connection
+type Pipeline struct {
+	Name        string              `json:"name,omitempty"`
+	Connections []ServiceConnection `json:"connections"`
+	Files       []string            `json:"files"`
+	Warnings    []string            `json:"warnings,omitempty"`
+}
+
+// parameterRegex matches ${{ parameters.name }} template expressions
+var parameterRegex = regexp.MustCompile(`\$\{\{\s*parameters\.([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// scriptPathPrefixes are the predefined variables a scriptPath is commonly rooted at
+var scriptPathPrefixes = []string{
+	"$(System.DefaultWorkingDirectory)",
+	"$(Build.SourcesDirectory)",
+	"$(Build.Repository.LocalPath)",
+}
+
+// pipelineScanner walks a pipeline definition and the local templates it includes
+type pipelineScanner struct {
+	root        string
+	connections map[string]*ServiceConnection
+	order       []string
+	pipeline    Pipeline
+}
+
+// pipelineContext is the file being scanned and the template parameters in effect
+type pipelineContext struct {
+	file       string
+	lines      []string
+	parameters map[string]string
+	depth      int
+}
+
+// ScanAzurePipeline finds the Azure CLI invocations in an Azure Pipelines definition: in the
+// inline scripts and script files of AzureCLI tasks, interpreted for their scriptType, and in
+// script, bash, pwsh and powershell steps. Local template includes (template: and
+// extends:) are followed; paths starting with "/" are resolved from the repository root.
+// Templates in other repositories and missing files are reported as warnings.
+func ScanAzurePipeline(path string) (Pipeline, error) {
+	scanner := &pipelineScanner{
+		root:        repositoryRoot(path),
+		connections: make(map[string]*ServiceConnection),
+		pipeline: Pipeline{
+			Connections: []ServiceConnection{},
+			Files:       []string{},
+		},
+	}
+
+	root, err := scanner.scanFile(path, nil, 0)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	scanner.pipeline.Name = scalarValue(root, "name")
+
+	for _, name := range scanner.order {
+		scanner.pipeline.Connections = append(scanner.pipeline.Connections, *scanner.connections[name])
+	}
+	return scanner.pipeline, nil
+}
+
+// repositoryRoot returns the closest directory above path containing .git, or the directory
+// of path. The root is relative to the working directory when possible so reported file
+// names stay short.
+func repositoryRoot(path string) string {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return filepath.Dir(path)
+	}
+
+	root := dir
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			root = current
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	if workingDir, err := os.Getwd(); err == nil {
+		if relative, err := filepath.Rel(workingDir, root); err == nil {
+			return relative
+		}
+	}
+	return root
+}
+
+// scanFile scans a pipeline or template file with the given parameter values and returns its root node
+func (s *pipelineScanner) scanFile(path string, parameters map[string]string, depth int) (*yaml.Node, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+	root, lines, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.pipeline.Files = append(s.pipeline.Files, path)
+
+	ctx := pipelineContext{
+		file:       path,
+		lines:      lines,
+		parameters: templateDefaults(mappingValue(root, "parameters")),
+		depth:      depth,
+	}
+	for name, value := range parameters {
+		ctx.parameters[name] = value
+	}
+
+	s.scanNode(root, ctx)
+	return root, nil
+}
+
+// templateDefaults returns the default parameter values declared by a template, in either the
+// list form (- name: x, default: y) or the legacy mapping form (x: y)
+func templateDefaults(node *yaml.Node) map[string]string {
+	defaults := make(map[string]string)
+	if node == nil {
+		return defaults
+	}
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, parameter := range node.Content {
+			if name := scalarValue(parameter, "name"); name != "" {
+				defaults[name] = scalarValue(parameter, "default")
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i+1].Kind == yaml.ScalarNode {
+				defaults[node.Content[i].Value] = node.Content[i+1].Value
+			}
+		}
+	}
+	return defaults
+}
+
+// expand substitutes ${{ parameters.name }} expressions with the parameter values in effect
+func (ctx pipelineContext) expand(value string) string {
+	return parameterRegex.ReplaceAllStringFunc(value, func(expression string) string {
+		name := parameterRegex.FindStringSubmatch(expression)[1]
+		if parameterValue, exists := ctx.parameters[name]; exists {
+			return parameterValue
+		}
+		return expression
+	})
+}
+
+// scanNode scans a pipeline, stage, job, template or deployment strategy mapping
+func (s *pipelineScanner) scanNode(node *yaml.Node, ctx pipelineContext) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		switch node.Content[i].Value {
+		case "stages", "jobs":
+			for _, item := range sequenceItems(value) {
+				if template := mappingValue(item, "template"); template != nil {
+					s.include(template, mappingValue(item, "parameters"), ctx)
+				} else {
+					s.scanNode(item, ctx)
+				}
+			}
+		case "steps":
+			for _, item := range sequenceItems(value) {
+				if template := mappingValue(item, "template"); template != nil {
+					s.include(template, mappingValue(item, "parameters"), ctx)
+				} else {
+					s.scanStep(item, ctx)
+				}
+			}
+		case "extends":
+			s.include(mappingValue(value, "template"), mappingValue(value, "parameters"), ctx)
+		case "strategy", "runOnce", "rolling", "canary", "preDeploy", "deploy", "routeTraffic", "postRouteTraffic", "on", "failure", "success":
+			// Deployment jobs nest their steps inside the strategy lifecycle hooks
+			s.scanNode(value, ctx)
+		}
+	}
+}
+
+// sequenceItems returns the items of a sequence, flattening ${{ if }} and ${{ each }} blocks
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	var items []*yaml.Node
+	for _, item := range node.Content {
+		if item.Kind == yaml.MappingNode && len(item.Content) == 2 && strings.HasPrefix(item.Content[0].Value, "${{") {
+			items = append(items, sequenceItems(item.Content[1])...)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// include scans a local template with the parameters passed to it
+func (s *pipelineScanner) include(template, parameters *yaml.Node, ctx pipelineContext) {
+	if template == nil || template.Kind != yaml.ScalarNode {
+		return
+	}
+
+	reference := ctx.expand(template.Value)
+	if strings.Contains(reference, "@") {
+		s.warnf("%s:%d: skipped template %s from another repository", ctx.file, template.Line, reference)
+		return
+	}
+	if ctx.depth >= maxTemplateDepth {
+		s.warnf("%s:%d: skipped template %s: templates nested too deeply", ctx.file, template.Line, reference)
+		return
+	}
+
+	values := make(map[string]string)
+	if parameters != nil && parameters.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(parameters.Content); i += 2 {
+			if parameters.Content[i+1].Kind == yaml.ScalarNode {
+				values[parameters.Content[i].Value] = ctx.expand(parameters.Content[i+1].Value)
+			}
+		}
+	}
+
+	if _, err := s.scanFile(s.resolve(reference, ctx.file), values, ctx.depth+1); err != nil {
+		s.warnf("%s:%d: %v", ctx.file, template.Line, err)
+	}
+}
+
+// resolve returns the path of a template or script: absolute paths are relative to the
+// repository root and other paths are relative to the including file
+func (s *pipelineScanner) resolve(reference, from string) string {
+	if strings.HasPrefix(reference, "/") {
+		return filepath.Join(s.root, filepath.FromSlash(reference))
+	}
+	return filepath.Join(filepath.Dir(from), filepath.FromSlash(reference))
+}
+
+// scanStep scans a single step for Azure CLI invocations
+func (s *pipelineScanner) scanStep(step *yaml.Node, ctx pipelineContext) {
+	if task := strings.ToLower(scalarValue(step, "task")); strings.HasPrefix(task, "azurecli@") {
+		s.scanAzureCLITask(mappingValue(step, "inputs"), ctx)
+		return
+	}
+
+	shells := map[string]string{"script": "", "bash": "bash", "pwsh": "pwsh", "powershell": "pwsh"}
+	for key, shell := range shells {
+		if script := mappingValue(step, key); script != nil {
+			s.add("", ctx.file, scanScalarScript(script, ctx.lines, shell))
+		}
+	}
+}
+
+// scanAzureCLITask scans the inline script or script file of an AzureCLI task
+func (s *pipelineScanner) scanAzureCLITask(inputs *yaml.Node, ctx pipelineContext) {
+	connection := ctx.expand(scalarValue(inputs, "azureSubscription"))
+	if connection == "" {
+		connection = ctx.expand(scalarValue(inputs, "connectedServiceNameARM"))
+	}
+
+	shell := ""
+	switch strings.ToLower(scalarValue(inputs, "scriptType")) {
+	case "bash":
+		shell = "bash"
+	case "ps", "pscore":
+		shell = "pwsh"
+	case "batch":
+		shell = "cmd"
+	}
+
+	scriptPath := ctx.expand(scalarValue(inputs, "scriptPath"))
+	if inline := mappingValue(inputs, "inlineScript"); inline != nil && (scriptPath == "" || !strings.EqualFold(scalarValue(inputs, "scriptLocation"), "scriptPath")) {
+		s.add(connection, ctx.file, scanScalarScript(inline, ctx.lines, shell))
+		return
+	}
+	if scriptPath == "" {
+		return
+	}
+
+	for _, prefix := range scriptPathPrefixes {
+		if strings.HasPrefix(scriptPath, prefix) {
+			scriptPath = "/" + strings.TrimLeft(strings.TrimPrefix(scriptPath, prefix), `/\`)
+		}
+	}
+	path := filepath.Join(s.root, filepath.FromSlash(strings.TrimPrefix(scriptPath, "/")))
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		s.warnf("%s: could not read scriptPath %s: %v", ctx.file, scriptPath, err)
+		return
+	}
+	if shell == "" && (strings.HasSuffix(strings.ToLower(path), ".ps1")) {
+		shell = "pwsh"
+	}
+	s.add(connection, path, ScanShellScript(string(content), shell))
+}
+
+// add records invocations found in a file under a service connection
+func (s *pipelineScanner) add(connection, file string, invocations []Invocation) {
+	if len(invocations) == 0 {
+		return
+	}
+
+	group, exists := s.connections[connection]
+	if !exists {
+		group = &ServiceConnection{Name: connection, Invocations: []Invocation{}}
+		s.connections[connection] = group
+		s.order = append(s.order, connection)
+	}
+	for _, invocation := range invocations {
+		invocation.File = file
+		group.Invocations = append(group.Invocations, invocation)
+	}
+}
+
+// warnf records a problem that did not stop the scan
+func (s *pipelineScanner) warnf(format string, args ...interface{}) {
+	s.pipeline.Warnings = append(s.pipeline.Warnings, fmt.Sprintf(format, args...))
+}
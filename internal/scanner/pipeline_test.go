@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files with the given contents below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanAzurePipeline(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".git/HEAD": "ref: refs/heads/main\n",
+		"azure-pipelines.yml": `name: release
+stages:
+  - stage: deploy
+    jobs:
+      - job: infra
+        steps:
+          - task: AzureCLI@2
+            inputs:
+              azureSubscription: prod-connection
+              scriptType: bash
+              scriptLocation: inlineScript
+              inlineScript: |
+                az group create --name rg --location westeurope
+          - script: az account show --output table
+          - template: templates/deploy.yml
+            parameters:
+              connection: test-connection
+          - template: steps.yml@shared
+`,
+		"templates/deploy.yml": `parameters:
+  - name: connection
+    default: default-connection
+steps:
+  - task: AzureCLI@2
+    inputs:
+      azureSubscription: ${{ parameters.connection }}
+      scriptType: pscore
+      scriptLocation: scriptPath
+      scriptPath: $(System.DefaultWorkingDirectory)/scripts/deploy.ps1
+`,
+		"scripts/deploy.ps1": "# deploy\n$app = az webapp show --name app | ConvertFrom-Json\n",
+	})
+
+	path := filepath.Join(dir, "azure-pipelines.yml")
+	pipeline, err := ScanAzurePipeline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pipeline.Name != "release" {
+		t.Errorf("Name = %q, want release", pipeline.Name)
+	}
+	if len(pipeline.Files) != 2 {
+		t.Errorf("Files = %v, want the pipeline and one template", pipeline.Files)
+	}
+	if len(pipeline.Warnings) != 1 || !strings.Contains(pipeline.Warnings[0], "steps.yml@shared") {
+		t.Errorf("Warnings = %v, want one warning for the template in another repository", pipeline.Warnings)
+	}
+
+	got := make(map[string][]Invocation)
+	for _, connection := range pipeline.Connections {
+		for _, invocation := range connection.Invocations {
+			invocation.File = filepath.Base(invocation.File)
+			got[connection.Name] = append(got[connection.Name], invocation)
+		}
+	}
+	want := map[string][]Invocation{
+		"prod-connection": {{File: "azure-pipelines.yml", Line: 13, Column: 17, Command: "az group create --name rg --location westeurope"}},
+		"":                {{File: "azure-pipelines.yml", Line: 14, Column: 21, Command: "az account show --output table"}},
+		"test-connection": {{File: "deploy.ps1", Line: 2, Column: 8, Command: "az webapp show --name app"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invocations by connection =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestScanAzurePipelineTemplateDepth(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".git/HEAD":           "ref: refs/heads/main\n",
+		"azure-pipelines.yml": "steps:\n  - template: loop.yml\n",
+		"loop.yml":            "steps:\n  - template: loop.yml\n",
+	})
+
+	pipeline, err := ScanAzurePipeline(filepath.Join(dir, "azure-pipelines.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipeline.Warnings) != 1 || !strings.Contains(pipeline.Warnings[0], "nested too deeply") {
+		t.Errorf("Warnings = %v, want one warning for the nesting limit", pipeline.Warnings)
+	}
+}
@@ -0,0 +1,275 @@
+package scanner
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Invocation represents an Azure CLI command found in a script, located at the
+// 1-based line and column (in Unicode code points) where "az" starts. File is set when
+// the invocation was found in a file other than the one being scanned.
+type Invocation struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Command string `json:"command"`
+}
+
+// commandPrefixes are shell keywords and commands whose next word is still a command, as in
+// "if az group exists ..." or "sudo az ..."
+var commandPrefixes = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "while": true, "until": true, "do": true,
+	"!": true, "{": true, "time": true, "sudo": true, "exec": true, "command": true, "nohup": true, "env": true,
+}
+
+// assignmentRegex matches a bash variable assignment prefix such as FOO=bar
+var assignmentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\+?=`)
+
+// powershellVariableRegex matches a PowerShell variable assigned with "$name = az ..."
+var powershellVariableRegex = regexp.MustCompile(`^\$[A-Za-z_][A-Za-z0-9_:]*$`)
+
+// ScanScript finds all Azure CLI invocations in bash or PowerShell script text
+func ScanScript(text string) []Invocation {
+	return ScanShellScript(text, "")
+}
+
+// ScanShellScript finds all Azure CLI invocations in a script written for the given shell
+// (e.g. "bash", "pwsh", "cmd" or a custom "bash -e {0}" template). Line continuations are
+// interpreted for that shell; an empty shell accepts both bash and PowerShell continuations.
+// Scripts for non-shell languages such as python yield no invocations.
+func ScanShellScript(text, shell string) []Invocation {
+	continuations, ok := shellContinuations(shell)
+	if !ok {
+		return nil
+	}
+
+	var invocations []Invocation
+	for _, line := range logicalLines(text, continuations) {
+		for _, segment := range splitCommands(line.text) {
+			command, offset := extractInvocation(segment.text)
+			if command == "" {
+				continue
+			}
+			position := line.positions[segment.offset+offset]
+			invocations = append(invocations, Invocation{Line: position.line, Column: position.column, Command: command})
+		}
+	}
+
+	return invocations
+}
+
+// position is a 1-based line and column in the original script text
+type position struct {
+	line   int
+	column int
+}
+
+// logicalLine is a script line with continuations joined. positions holds the original
+// position of every rune of text, so invocations can be located in the source.
+type logicalLine struct {
+	text      string
+	positions []position
+}
+
+// shellContinuations returns the line continuation characters of a shell, and false
+// when the shell is not a command shell
+func shellContinuations(shell string) (string, bool) {
+	fields := strings.Fields(strings.ToLower(shell))
+	if len(fields) == 0 {
+		return "\\`", true
+	}
+
+	switch strings.TrimSuffix(path.Base(strings.ReplaceAll(fields[0], `\`, "/")), ".exe") {
+	case "bash", "sh", "zsh", "dash", "ksh":
+		return "\\", true
+	case "pwsh", "powershell":
+		return "`", true
+	case "cmd":
+		return "^", true
+	default:
+		return "", false
+	}
+}
+
+// logicalLines joins continuation lines (e.g. trailing '\' in bash, trailing '`' in PowerShell)
+// and drops comment lines
+func logicalLines(text, continuations string) []logicalLine {
+	var lines []logicalLine
+	var current []rune
+	var positions []position
+
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmedLeft := strings.TrimLeftFunc(raw, unicode.IsSpace)
+		line := []rune(strings.TrimRightFunc(trimmedLeft, unicode.IsSpace))
+		indent := utf8.RuneCountInString(raw) - utf8.RuneCountInString(trimmedLeft)
+
+		if len(current) == 0 && (len(line) == 0 || line[0] == '#') {
+			continue
+		}
+
+		continued := len(line) > 0 && strings.ContainsRune(continuations, line[len(line)-1])
+		if continued {
+			line = []rune(strings.TrimRightFunc(string(line[:len(line)-1]), unicode.IsSpace))
+		}
+
+		for j, r := range line {
+			current = append(current, r)
+			positions = append(positions, position{line: i + 1, column: indent + j + 1})
+		}
+
+		if continued {
+			current = append(current, ' ')
+			positions = append(positions, position{line: i + 1, column: indent + len(line) + 1})
+			continue
+		}
+
+		lines = append(lines, logicalLine{text: string(current), positions: positions})
+		current, positions = nil, nil
+	}
+
+	if len(current) > 0 {
+		lines = append(lines, logicalLine{text: string(current), positions: positions})
+	}
+
+	return lines
+}
+
+// segment is a single command of a logical line, starting at rune offset in the line
+type segment struct {
+	text   string
+	offset int
+}
+
+// splitCommands splits a logical line on shell command separators outside of quotes
+func splitCommands(line string) []segment {
+	var segments []segment
+	var quote rune
+	start := 0
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch r {
+		case '\'', '"':
+			quote = r
+		case ';', '|', '&':
+			// '&&', '||' and '|' all end the current command
+			segments = append(segments, segment{text: string(runes[start:i]), offset: start})
+			if i+1 < len(runes) && runes[i+1] == r {
+				i++
+			}
+			start = i + 1
+		case '#':
+			// Inline comment
+			if i == start || runes[i-1] == ' ' {
+				return append(segments, segment{text: string(runes[start:i]), offset: start})
+			}
+		}
+	}
+
+	return append(segments, segment{text: string(runes[start:]), offset: start})
+}
+
+// extractInvocation returns the Azure CLI command contained in a single command segment
+// and the rune offset in the segment where it starts
+func extractInvocation(segment string) (string, int) {
+	runes := []rune(segment)
+	start := commandStart(runes)
+	if start < 0 {
+		return "", 0
+	}
+
+	command := strings.TrimSpace(string(runes[start:]))
+
+	// Strip the closing parenthesis of a surrounding command substitution
+	if start > 0 && strings.ContainsRune(string(runes[:start]), '(') {
+		command = strings.TrimSpace(strings.TrimSuffix(strings.TrimRight(command, "\"' "), ")"))
+	}
+
+	if len(strings.Fields(command)) < 3 {
+		return "", 0
+	}
+
+	return command, start
+}
+
+// commandStart returns the rune offset of the first "az" in command position: the first word
+// of the command, a word after assignments and keywords such as "if" or "sudo", or the first
+// word of a subshell or command substitution. Words in quotes are arguments, so "az" in
+// echo text is ignored. It returns -1 when no "az" is run.
+func commandStart(runes []rune) int {
+	var quote rune
+	atCommand := true
+	wordStart := -1
+
+	for i, r := range runes {
+		if quote == 0 && unicode.IsSpace(r) {
+			if wordStart >= 0 {
+				atCommand = atCommand && keepsCommandPosition(string(runes[wordStart:i]))
+				wordStart = -1
+			}
+			continue
+		}
+
+		if wordStart < 0 {
+			wordStart = i
+			if atCommand && quote == 0 && isAz(runes, i) {
+				return i
+			}
+		}
+
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if quote == '"' && r == '(' && runes[i-1] == '$' {
+				// Command substitution in double quotes
+				if start := startsWithAz(runes, i+1); start >= 0 {
+					return start
+				}
+			}
+		case r == '\'', r == '"':
+			quote = r
+		case r == '(':
+			if start := startsWithAz(runes, i+1); start >= 0 {
+				return start
+			}
+		}
+	}
+
+	return -1
+}
+
+// keepsCommandPosition reports whether the word after a word in command position is still a
+// command: after keywords, variable assignments and the "=" of a PowerShell assignment
+func keepsCommandPosition(word string) bool {
+	return commandPrefixes[strings.ToLower(word)] || word == "=" ||
+		assignmentRegex.MatchString(word) || powershellVariableRegex.MatchString(word)
+}
+
+// startsWithAz returns the offset of "az" if it is the first word at or after offset, or -1
+func startsWithAz(runes []rune, offset int) int {
+	for offset < len(runes) && unicode.IsSpace(runes[offset]) {
+		offset++
+	}
+	if isAz(runes, offset) {
+		return offset
+	}
+	return -1
+}
+
+// isAz reports whether the word at offset is "az" followed by whitespace
+func isAz(runes []rune, offset int) bool {
+	return offset+2 < len(runes) && runes[offset] == 'a' && runes[offset+1] == 'z' && unicode.IsSpace(runes[offset+2])
+}
@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanShellScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		shell  string
+		want   []Invocation
+	}{
+		{
+			name:   "single command",
+			script: "az vm delete --name vm1 --resource-group rg",
+			want:   []Invocation{{Line: 1, Column: 1, Command: "az vm delete --name vm1 --resource-group rg"}},
+		},
+		{
+			name:   "indented after comment and blank line",
+			script: "# setup\n\n    az group create --name rg --location westeurope",
+			want:   []Invocation{{Line: 3, Column: 5, Command: "az group create --name rg --location westeurope"}},
+		},
+		{
+			name:   "bash continuation",
+			script: "az storage account create \\\n  --name st \\\n  --resource-group rg",
+			shell:  "bash",
+			want:   []Invocation{{Line: 1, Column: 1, Command: "az storage account create --name st --resource-group rg"}},
+		},
+		{
+			name:   "powershell continuation",
+			script: "az webapp restart `\n  --name app",
+			shell:  "pwsh",
+			want:   []Invocation{{Line: 1, Column: 1, Command: "az webapp restart --name app"}},
+		},
+		{
+			name:   "command separators",
+			script: "az group list && echo done; az vm list -o table | jq .",
+			want: []Invocation{
+				{Line: 1, Column: 1, Command: "az group list"},
+				{Line: 1, Column: 29, Command: "az vm list -o table"},
+			},
+		},
+		{
+			name:   "command substitution",
+			script: `ID=$(az vm show --name vm1 --query id -o tsv)`,
+			want:   []Invocation{{Line: 1, Column: 6, Command: "az vm show --name vm1 --query id -o tsv"}},
+		},
+		{
+			name:   "command substitution in double quotes",
+			script: `echo "$(az account show --query name)"`,
+			want:   []Invocation{{Line: 1, Column: 9, Command: "az account show --query name"}},
+		},
+		{
+			name:   "keyword and assignment prefixes",
+			script: "if az group exists --name rg; then\n  AZURE_CORE_OUTPUT=json sudo az group delete --name rg --yes\nfi",
+			want: []Invocation{
+				{Line: 1, Column: 4, Command: "az group exists --name rg"},
+				{Line: 2, Column: 31, Command: "az group delete --name rg --yes"},
+			},
+		},
+		{
+			name:   "powershell assignment",
+			script: "$vm = az vm show --name vm1 | ConvertFrom-Json",
+			shell:  "pwsh",
+			want:   []Invocation{{Line: 1, Column: 7, Command: "az vm show --name vm1"}},
+		},
+		{
+			name:   "az in quoted echo text",
+			script: `echo "now run az vm delete --name vm1"`,
+		},
+		{
+			name:   "az as an argument",
+			script: "echo az vm delete --name vm1\nWrite-Host 'az group delete --name rg'",
+		},
+		{
+			name:   "az inside a word",
+			script: "baz vm list --all\nmyaz group list",
+		},
+		{
+			name:   "inline comment",
+			script: "az vm list --all # az vm delete --name vm1",
+			want:   []Invocation{{Line: 1, Column: 1, Command: "az vm list --all"}},
+		},
+		{
+			name:   "too short to be a command",
+			script: "az login",
+		},
+		{
+			name:   "non-shell language",
+			script: "az vm list --all",
+			shell:  "python",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScanShellScript(tt.script, tt.shell)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScanShellScript() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
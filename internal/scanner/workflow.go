@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScanGitHubWorkflow finds the Azure CLI invocations in a GitHub Actions workflow: in the
+// run: scripts of its steps, interpreted for the step's shell, and in the inlineScript input
+// of azure/cli action steps. The identity of a job is the client-id passed to azure/login.
+func ScanGitHubWorkflow(content []byte) (Workflow, error) {
+	root, lines, err := parseYAMLDocument(content)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	workflow := Workflow{
+		Name: scalarValue(root, "name"),
+		Jobs: []Job{},
+	}
+	workflowShell := scalarValue(root, "defaults", "run", "shell")
+
+	jobs := mappingValue(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return workflow, nil
+	}
+
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		jobNode := jobs.Content[i+1]
+		job := Job{
+			ID:          jobs.Content[i].Value,
+			Name:        scalarValue(jobNode, "name"),
+			Invocations: []Invocation{},
+		}
+
+		shell := scalarValue(jobNode, "defaults", "run", "shell")
+		if shell == "" {
+			shell = workflowShell
+		}
+		if shell == "" {
+			shell = defaultGitHubShell(mappingValue(jobNode, "runs-on"))
+		}
+
+		steps := mappingValue(jobNode, "steps")
+		if steps != nil && steps.Kind == yaml.SequenceNode {
+			for _, step := range steps.Content {
+				uses := strings.ToLower(scalarValue(step, "uses"))
+
+				switch {
+				case strings.HasPrefix(uses, "azure/login@"):
+					if clientID := scalarValue(step, "with", "client-id"); clientID != "" {
+						job.Identity = clientID
+					}
+				case strings.HasPrefix(uses, "azure/cli@"):
+					// azure/cli runs its inline script with bash in the Azure CLI container
+					inlineScript := mappingValue(mappingValue(step, "with"), "inlineScript")
+					job.Invocations = append(job.Invocations, scanScalarScript(inlineScript, lines, "bash")...)
+				}
+
+				if run := mappingValue(step, "run"); run != nil {
+					stepShell := scalarValue(step, "shell")
+					if stepShell == "" {
+						stepShell = shell
+					}
+					job.Invocations = append(job.Invocations, scanScalarScript(run, lines, stepShell)...)
+				}
+			}
+		}
+
+		workflow.Jobs = append(workflow.Jobs, job)
+	}
+
+	return workflow, nil
+}
+
+// defaultGitHubShell returns the shell GitHub Actions uses for run: steps on a runner:
+// pwsh on Windows runners and bash elsewhere
+func defaultGitHubShell(runsOn *yaml.Node) string {
+	if runsOn == nil {
+		return "bash"
+	}
+
+	labels := []*yaml.Node{runsOn}
+	if runsOn.Kind == yaml.SequenceNode {
+		labels = runsOn.Content
+	}
+	for _, label := range labels {
+		if label.Kind == yaml.ScalarNode && strings.Contains(strings.ToLower(label.Value), "windows") {
+			return "pwsh"
+		}
+	}
+	return "bash"
+}
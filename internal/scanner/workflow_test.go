@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanGitHubWorkflow(t *testing.T) {
+	content := `name: deploy
+on: push
+jobs:
+  infra:
+    name: Infrastructure
+    runs-on: ubuntu-latest
+    steps:
+      - uses: azure/login@v2
+        with:
+          client-id: 00000000-0000-0000-0000-000000000001
+      - run: |
+          echo "deploying with az"
+          az group create --name rg --location westeurope
+      - uses: azure/cli@v2
+        with:
+          inlineScript: az vm list --resource-group rg
+  windows:
+    runs-on: windows-latest
+    steps:
+      - run: |
+          az webapp restart --name app ` + "`" + `
+            --resource-group rg
+      - shell: python
+        run: print("az vm delete --name vm1")
+`
+	workflow, err := ScanGitHubWorkflow([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Workflow{
+		Name: "deploy",
+		Jobs: []Job{
+			{
+				ID:       "infra",
+				Name:     "Infrastructure",
+				Identity: "00000000-0000-0000-0000-000000000001",
+				Invocations: []Invocation{
+					{Line: 13, Column: 11, Command: "az group create --name rg --location westeurope"},
+					{Line: 16, Column: 25, Command: "az vm list --resource-group rg"},
+				},
+			},
+			{
+				ID: "windows",
+				Invocations: []Invocation{
+					{Line: 21, Column: 11, Command: "az webapp restart --name app --resource-group rg"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(workflow, want) {
+		t.Errorf("ScanGitHubWorkflow() =\n%+v\nwant\n%+v", workflow, want)
+	}
+}
+
+func TestScanGitHubWorkflowInvalid(t *testing.T) {
+	for _, content := range []string{"- just\n- a list\n", "jobs: [\n"} {
+		if _, err := ScanGitHubWorkflow([]byte(content)); err == nil {
+			t.Errorf("ScanGitHubWorkflow(%q) succeeded, want an error", content)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Job is a CI job together with the Azure CLI invocations found in its scripts.
+// Invocation positions refer to the CI definition file.
+type Job struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name,omitempty"`
+	Identity    string       `json:"identity,omitempty"`
+	Invocations []Invocation `json:"invocations"`
+}
+
+// Workflow is a CI definition, such as a GitHub Actions workflow, and its jobs
+type Workflow struct {
+	Name string `json:"name,omitempty"`
+	Jobs []Job  `json:"jobs"`
+}
+
+// parseYAMLDocument parses a YAML document and returns its root mapping and the raw lines
+func parseYAMLDocument(content []byte) (*yaml.Node, []string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("expected a YAML mapping at the top level")
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	return document.Content[0], lines, nil
+}
+
+// mappingValue returns the value of a key in a mapping node, or nil if the key does not exist
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the value of a scalar at a key path, or "" if it does not exist
+func scalarValue(node *yaml.Node, path ...string) string {
+	for _, key := range path {
+		node = mappingValue(node, key)
+	}
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// scanScalarScript finds the Azure CLI invocations in a script stored in a YAML scalar and
+// maps their positions from the script to the YAML file. Positions are exact for block
+// scalars and single-line scalars, and approximate for multi-line flow scalars.
+func scanScalarScript(node *yaml.Node, lines []string, shell string) []Invocation {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	invocations := ScanShellScript(node.Value, shell)
+	if node.Style == yaml.LiteralStyle || node.Style == yaml.FoldedStyle {
+		mapBlockPositions(invocations, node, lines)
+		return invocations
+	}
+
+	column := node.Column - 1
+	if node.Style == yaml.DoubleQuotedStyle || node.Style == yaml.SingleQuotedStyle {
+		column++
+	}
+	for i := range invocations {
+		if invocations[i].Line == 1 {
+			invocations[i].Column += column
+		}
+		invocations[i].Line += node.Line - 1
+	}
+	return invocations
+}
+
+// mapBlockPositions maps invocation positions from the value of a block scalar to the YAML
+// file. The value is aligned with the block's source lines, so folded line breaks that became
+// spaces and collapsed blank lines are accounted for. Invocations that cannot be aligned are
+// reported at the start of the block.
+func mapBlockPositions(invocations []Invocation, node *yaml.Node, lines []string) {
+	// Block content starts on the line after the indicator, indented by the first content line
+	indent := blockIndent(lines, node.Line)
+	source := blockSource(lines, node.Line, indent)
+	positions := alignBlockValue([]rune(node.Value), source)
+
+	var lineStarts []int
+	offset := 0
+	for _, line := range strings.Split(node.Value, "\n") {
+		lineStarts = append(lineStarts, offset)
+		offset += utf8.RuneCountInString(line) + 1
+	}
+
+	for i := range invocations {
+		offset := -1
+		if line := invocations[i].Line; line >= 1 && line <= len(lineStarts) {
+			offset = lineStarts[line-1] + invocations[i].Column - 1
+		}
+		if offset >= 0 && offset < len(positions) && positions[offset].line > 0 {
+			invocations[i].Line, invocations[i].Column = positions[offset].line, positions[offset].column
+		} else {
+			invocations[i].Line, invocations[i].Column = node.Line+1, indent+1
+		}
+	}
+}
+
+// sourceRune is a rune of a block scalar's source and its position in the YAML file
+type sourceRune struct {
+	r rune
+	position
+}
+
+// blockSource returns the runes of a block scalar's content lines without their indentation,
+// with a newline after every line
+func blockSource(lines []string, indicatorLine, indent int) []sourceRune {
+	var source []sourceRune
+	for i := indicatorLine; i < len(lines); i++ {
+		line := []rune(strings.TrimRight(lines[i], "\r"))
+		if strings.TrimSpace(string(line)) == "" {
+			source = append(source, sourceRune{'\n', position{line: i + 1, column: 1}})
+			continue
+		}
+		if len(line)-len([]rune(strings.TrimLeftFunc(string(line), unicode.IsSpace))) < indent {
+			break
+		}
+		for j, r := range line[indent:] {
+			source = append(source, sourceRune{r, position{line: i + 1, column: indent + j + 1}})
+		}
+		source = append(source, sourceRune{'\n', position{line: i + 1, column: len(line) + 1}})
+	}
+	return source
+}
+
+// alignBlockValue returns the source position of every rune of a block scalar's value. A
+// space in the value may stand for a folded line break, and line breaks of the source may be
+// missing from the value. Runes after the first mismatch have no position.
+func alignBlockValue(value []rune, source []sourceRune) []position {
+	positions := make([]position, len(value))
+	s := 0
+	for v, r := range value {
+		for s < len(source) && source[s].r != r && source[s].r == '\n' && r != ' ' {
+			s++
+		}
+		if s >= len(source) || (source[s].r != r && !(r == ' ' && source[s].r == '\n')) {
+			break
+		}
+		positions[v] = source[s].position
+		s++
+	}
+	return positions
+}
+
+// blockIndent returns the indentation of the first non-blank line after a block scalar indicator
+func blockIndent(lines []string, indicatorLine int) int {
+	for i := indicatorLine; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			return utf8.RuneCountInString(lines[i]) - utf8.RuneCountInString(strings.TrimLeftFunc(lines[i], unicode.IsSpace))
+		}
+	}
+	return 0
+}
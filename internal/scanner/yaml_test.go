@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanScalarScriptPositions(t *testing.T) {
+	content := `literal: |
+  echo start
+
+  az group create --name rg \
+    --location westeurope
+folded: >
+  az vm start
+  --name vm1
+
+  az vm list
+  --all
+plain: az webapp restart --name app
+quoted: "echo go; az webapp stop --name app"
+`
+	root, lines, err := parseYAMLDocument([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		want []Invocation
+	}{
+		{"literal", []Invocation{{Line: 4, Column: 3, Command: "az group create --name rg --location westeurope"}}},
+		{"folded", []Invocation{
+			{Line: 7, Column: 3, Command: "az vm start --name vm1"},
+			{Line: 10, Column: 3, Command: "az vm list --all"},
+		}},
+		{"plain", []Invocation{{Line: 12, Column: 8, Command: "az webapp restart --name app"}}},
+		{"quoted", []Invocation{{Line: 13, Column: 19, Command: "az webapp stop --name app"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := scanScalarScript(mappingValue(root, tt.key), lines, "bash")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanScalarScript() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
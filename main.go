@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/mathwro/azperm/cmd"
+	"github.com/mathwro/azperm/internal/config"
+	"github.com/mathwro/azperm/internal/policy"
 )
 
 func main() {
@@ -19,6 +22,18 @@ func main() {
 		debugShort   = flag.Bool("d", false, "Enable debug mode with verbose output (short)")
 		lastCommand  = flag.Bool("last", false, "Analyze the last Azure CLI command from shell history")
 		lastShort    = flag.Bool("l", false, "Analyze the last Azure CLI command from shell history (short)")
+		_            = flag.String("output", "text", "Output format: text, json, markdown, csv, html or sarif")
+		_            = flag.String("o", "", "Output format: text, json, markdown, csv, html or sarif (short)")
+		_            = flag.String("catalog", "", "Load the provider operations catalog from a snapshot file")
+		_            = flag.Bool("offline", false, "Use the cached provider operations catalog instead of the live API")
+		_            = flag.String("cloud", "", "Azure cloud profile: AzureCloud, AzureUSGovernment, AzureChinaCloud or a user-defined profile")
+		_            = flag.String("policy", "", "Check results against a permission policy file (default: "+policy.DefaultFile+" if present)")
+		_            = flag.String("profile", "", "Use a named profile from the config files")
+		_            = flag.String("proxy", "", "Proxy URL for Azure API requests (default: HTTPS_PROXY)")
+		_            = flag.String("ca-file", "", "PEM bundle of additional trusted CAs (default: REQUESTS_CA_BUNDLE)")
+		_            = flag.String("client-cert", "", "PEM client certificate for mutual TLS")
+		_            = flag.String("client-key", "", "PEM key of the client certificate")
+		_            = flag.String("providers", "", "Resource providers file (az provider list output) for matching unknown services")
 	)
 	
 	flag.Parse()
@@ -31,6 +46,29 @@ func main() {
 		cli.SetDebugMode(true)
 	}
 
+	// Resolve settings from config files, environment variables and explicitly set flags
+	flags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "output", "o":
+			if f.Name == "o" || flags[config.KeyOutput] == "" {
+				flags[config.KeyOutput] = f.Value.String()
+			}
+		case config.KeyCloud, config.KeyCatalog, config.KeyOffline, config.KeyPolicy, "profile",
+			config.KeyProxy, config.KeyCAFile, config.KeyClientCert, config.KeyClientKey, config.KeyProviders:
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	cfg, err := config.Load(flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cli.ApplyConfig(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Handle version flag
 	if *showVersion || *versionShort {
 		fmt.Printf("Azure CLI Permissions Analyzer (azperm) v%s\n", cli.Version())
@@ -45,19 +83,29 @@ func main() {
 
 	// Handle last command flag
 	if *lastCommand || *lastShort {
-		if err := cli.RunWithLastCommand(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		os.Exit(0)
+		exit(cli.RunWithLastCommand())
 	}
 
 	// Get remaining command line arguments (the Azure CLI command)
 	args := flag.Args()
 
 	// Run the main CLI logic (always uses live Azure API)
-	if err := cli.RunWithArgs(args); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	exit(cli.RunWithArgs(args))
+}
+
+// exit reports an error and exits with the status code it carries, or 1
+func exit(err error) {
+	if err == nil {
+		os.Exit(0)
+	}
+
+	var exitErr *cmd.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", exitErr.Err)
+		}
+		os.Exit(exitErr.Code)
 	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
 }